@@ -22,9 +22,60 @@ type Config struct {
 			Enable           bool     `mapstructure:"ENABLE"`
 			MaxAgeSeconds    int      `mapstructure:"MAX_AGE_SECONDS"`
 		}
-		Name     string `mapstructure:"NAME"`
-		Revision string `mapstructure:"REVISION"`
-		URL      string `mapstructure:"URL"`
+		Name         string `mapstructure:"NAME"`
+		Revision     string `mapstructure:"REVISION"`
+		URL          string `mapstructure:"URL"`
+		SupportEmail string `mapstructure:"SUPPORT_EMAIL"`
+	}
+
+	Auth struct {
+		Issuer      string `mapstructure:"ISSUER"`
+		AccessToken struct {
+			Secret string        `mapstructure:"SECRET"`
+			TTL    time.Duration `mapstructure:"TTL"`
+		} `mapstructure:"ACCESS_TOKEN"`
+		RefreshToken struct {
+			TTL time.Duration `mapstructure:"TTL"`
+		} `mapstructure:"REFRESH_TOKEN"`
+		PasswordHash struct {
+			Algorithm string `mapstructure:"ALGORITHM"`
+			Argon2id  struct {
+				Memory      uint32 `mapstructure:"MEMORY"`
+				Iterations  uint32 `mapstructure:"ITERATIONS"`
+				Parallelism uint8  `mapstructure:"PARALLELISM"`
+				SaltLength  uint32 `mapstructure:"SALT_LENGTH"`
+				KeyLength   uint32 `mapstructure:"KEY_LENGTH"`
+			}
+			Bcrypt struct {
+				Cost int `mapstructure:"COST"`
+			}
+		} `mapstructure:"PASSWORD_HASH"`
+		PasswordReset struct {
+			TTL time.Duration `mapstructure:"TTL"`
+			URL string        `mapstructure:"URL"`
+		} `mapstructure:"PASSWORD_RESET"`
+		EmailVerification struct {
+			Secret         string        `mapstructure:"SECRET"`
+			TTL            time.Duration `mapstructure:"TTL"`
+			URL            string        `mapstructure:"URL"`
+			ResendInterval time.Duration `mapstructure:"RESEND_INTERVAL"`
+		} `mapstructure:"EMAIL_VERIFICATION"`
+		MFA struct {
+			EncryptionKey        string        `mapstructure:"ENCRYPTION_KEY"`
+			ChallengeTTL         time.Duration `mapstructure:"CHALLENGE_TTL"`
+			MaxChallengeAttempts int           `mapstructure:"MAX_CHALLENGE_ATTEMPTS"`
+		}
+		WebAuthn struct {
+			RPID          string        `mapstructure:"RP_ID"`
+			RPDisplayName string        `mapstructure:"RP_DISPLAY_NAME"`
+			RPOrigins     []string      `mapstructure:"RP_ORIGINS"`
+			CeremonyTTL   time.Duration `mapstructure:"CEREMONY_TTL"`
+		}
+		MagicLink struct {
+			Secret string        `mapstructure:"SECRET"`
+			TTL    time.Duration `mapstructure:"TTL"`
+			URL    string        `mapstructure:"URL"`
+		} `mapstructure:"MAGIC_LINK"`
 	}
 
 	Cache struct {
@@ -62,6 +113,48 @@ type Config struct {
 		LoginAttemptTTL  time.Duration `mapstructure:"LOGIN_ATTEMPT_TTL"`
 		MaxSuspendAmount int           `mapstructure:"MAX_SUSPEND_AMOUNT"`
 		SuspendAmountTTL time.Duration `mapstructure:"SUSPEND_AMOUNT_TTL"`
+		PasswordPolicy   struct {
+			MinLength     int    `mapstructure:"MIN_LENGTH"`
+			MaxLength     int    `mapstructure:"MAX_LENGTH"`
+			RequireUpper  bool   `mapstructure:"REQUIRE_UPPER"`
+			RequireLower  bool   `mapstructure:"REQUIRE_LOWER"`
+			RequireDigit  bool   `mapstructure:"REQUIRE_DIGIT"`
+			RequireSymbol bool   `mapstructure:"REQUIRE_SYMBOL"`
+			DenyListFile  string `mapstructure:"DENY_LIST_FILE"`
+		} `mapstructure:"PASSWORD_POLICY"`
+	}
+
+	Notifier struct {
+		Driver        string        `mapstructure:"DRIVER"`
+		From          string        `mapstructure:"FROM"`
+		DefaultLocale string        `mapstructure:"DEFAULT_LOCALE"`
+		MaxRetry      int           `mapstructure:"MAX_RETRY"`
+		RetryDelay    time.Duration `mapstructure:"RETRY_DELAY"`
+		SendTimeout   time.Duration `mapstructure:"SEND_TIMEOUT"`
+		File          struct {
+			Dir string `mapstructure:"DIR"`
+		}
+		SMTP struct {
+			Host     string `mapstructure:"HOST"`
+			Port     string `mapstructure:"PORT"`
+			Username string `mapstructure:"USERNAME"`
+			Password string `mapstructure:"PASSWORD"`
+		}
+	}
+
+	PubSub struct {
+		Workers       int `mapstructure:"WORKERS"`
+		MessageBuffer int `mapstructure:"MESSAGE_BUFFER"`
+	}
+
+	Scheduler struct {
+		Enable            bool   `mapstructure:"ENABLE"`
+		LockPrefix        string `mapstructure:"LOCK_PREFIX"`
+		PurgeDeletedUsers struct {
+			Interval  time.Duration `mapstructure:"INTERVAL"`
+			Retention time.Duration `mapstructure:"RETENTION"`
+			BatchSize int           `mapstructure:"BATCH_SIZE"`
+		} `mapstructure:"PURGE_DELETED_USERS"`
 	}
 
 	Server struct {
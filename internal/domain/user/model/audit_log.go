@@ -0,0 +1,69 @@
+package model
+
+import (
+	"github.com/google/uuid"
+
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	AuditActionActivate   = "activate"
+	AuditActionLock       = "lock"
+	AuditActionSuspend    = "suspend"
+	AuditActionDeactivate = "deactivate"
+	AuditActionDelete     = "delete"
+	AuditActionRestore    = "restore"
+	AuditActionPurge      = "purge"
+)
+
+// AuditActionForTransition returns the audit log action of moving a user from one
+// status to another. Moving a user out of the deleted status restores it.
+func AuditActionForTransition(from, to UserStatus) string {
+	if from == StatusDeleted {
+		return AuditActionRestore
+	}
+	return AuditActionForStatus(to)
+}
+
+// AuditActionForStatus returns the audit log action of moving a user to status.
+func AuditActionForStatus(status UserStatus) string {
+	switch status {
+	case StatusActive:
+		return AuditActionActivate
+	case StatusLocked:
+		return AuditActionLock
+	case StatusSuspended:
+		return AuditActionSuspend
+	case StatusDeactivated:
+		return AuditActionDeactivate
+	case StatusDeleted:
+		return AuditActionDelete
+	}
+	return "status:" + string(status)
+}
+
+// ActorSystem is recorded as the actor of changes the service makes on its own.
+const ActorSystem = "system"
+
+// AuditLog records who changed a user account, how and why.
+type AuditLog struct {
+	Id        uuid.UUID `db:"id"`
+	UserId    uuid.UUID `db:"user_id"`
+	Action    string    `db:"action"`
+	Reason    string    `db:"reason"`
+	Actor     string    `db:"actor"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// NewAuditLog returns an audit log entry of an action taken now.
+func NewAuditLog(userID uuid.UUID, action, reason, actor string) AuditLog {
+	return AuditLog{
+		Id:        uuid.New(),
+		UserId:    userID,
+		Action:    action,
+		Reason:    reason,
+		Actor:     actor,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type contextKey string
+
+const principalContextKey contextKey = "principal"
+
+// Principal is the authenticated caller of a request, as much of the user as
+// authorization needs.
+type Principal struct {
+	UserID    uuid.UUID
+	Role      string
+	SessionID uuid.UUID
+}
+
+// IsStaff reports whether the caller may manage accounts other than their own.
+func (p Principal) IsStaff() bool {
+	return isStaffRole(p.Role)
+}
+
+// NewPrincipalContext returns a copy of ctx carrying the authenticated caller.
+func NewPrincipalContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey, principal)
+}
+
+// PrincipalFromContext returns the authenticated caller carried by ctx, if any.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"github.com/IlhamRobyana/user/shared"
+)
+
+type UserMFAEnrollResponse struct {
+	Secret string `json:"secret" validate:"required" example:"JBSWY3DPEHPK3PXP"`
+	URI    string `json:"uri" validate:"required" example:"otpauth://totp/user:jane@example.com?issuer=user&secret=JBSWY3DPEHPK3PXP"`
+}
+
+type UserMFAConfirmRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+func (d *UserMFAConfirmRequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
+
+type UserMFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes" validate:"required"`
+}
+
+type UserMFADisableRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+func (d *UserMFADisableRequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
+
+// UserLoginMFARequest completes a login challenged for a second factor. Code is
+// either a TOTP code or a recovery code.
+type UserLoginMFARequest struct {
+	MfaToken string     `json:"mfaToken" validate:"required"`
+	Code     string     `json:"code" validate:"required"`
+	Client   ClientInfo `json:"-"`
+}
+
+func (d *UserLoginMFARequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
@@ -0,0 +1,39 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+)
+
+type SessionResponse struct {
+	Id         uuid.UUID `json:"id" swaggertype:"string" validate:"required" example:"cb6b3eeb-2fa0-4492-91eb-67a7101a5424"`
+	UserId     uuid.UUID `json:"userId" swaggertype:"string" validate:"required" example:"cb6b3eeb-2fa0-4492-91eb-67a7101a5424"`
+	IpAddress  string    `json:"ipAddress" example:"127.0.0.1"`
+	UserAgent  string    `json:"userAgent" example:"Mozilla/5.0"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"createdAt" swaggertype:"string" validate:"required" example:"2006-01-02T15:04:05+07:00"`
+	LastSeenAt time.Time `json:"lastSeenAt" swaggertype:"string" validate:"required" example:"2006-01-02T15:04:05+07:00"`
+}
+
+func NewSessionResponse(session model.Session, currentSessionID uuid.UUID) SessionResponse {
+	return SessionResponse{
+		Id:         session.Id,
+		UserId:     session.UserId,
+		IpAddress:  session.IpAddress,
+		UserAgent:  session.UserAgent,
+		Current:    session.Id == currentSessionID,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+	}
+}
+
+func NewSessionListResponse(sessions model.SessionList, currentSessionID uuid.UUID) []SessionResponse {
+	responses := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, NewSessionResponse(*session, currentSessionID))
+	}
+	return responses
+}
@@ -5,11 +5,20 @@ import (
 	"github.com/google/uuid"
 	"github.com/guregu/null/v5"
 
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/IlhamRobyana/user/internal/domain/user/model"
 	"github.com/IlhamRobyana/user/shared"
 	"github.com/IlhamRobyana/user/shared/crypt"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/search"
 )
 
 type UserDTOFieldNameType string
@@ -20,6 +29,7 @@ type userDTOFieldName struct {
 	Password  UserDTOFieldNameType
 	Fullname  UserDTOFieldNameType
 	Status    UserDTOFieldNameType
+	Role      UserDTOFieldNameType
 	CreatedAt UserDTOFieldNameType
 	UpdatedAt UserDTOFieldNameType
 	DeletedAt UserDTOFieldNameType
@@ -34,6 +44,7 @@ var UserDTOFieldName = userDTOFieldName{
 	Password:  "password",
 	Fullname:  "fullname",
 	Status:    "status",
+	Role:      "role",
 	CreatedAt: "createdAt",
 	UpdatedAt: "updatedAt",
 	DeletedAt: "deletedAt",
@@ -44,20 +55,20 @@ var UserDTOFieldName = userDTOFieldName{
 
 type UserCreateRequest struct {
 	Email    string `json:"email" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Password string `json:"password" validate:"required,password"`
 	Fullname string `json:"fullname" validate:"required"`
 }
 
 func (d *UserCreateRequest) Validate() (err error) {
 	validator := shared.GetValidator()
-	return validator.Struct(d)
+	return shared.ValidationError(validator.Struct(d))
 }
 
 func (d UserCreateRequest) ToModel() (model.User, error) {
 	id := uuid.New()
 
-	// encrypt password using bcrypt
-	password, err := crypt.HashByBcrypt(d.Password)
+	// hash password using the configured password hasher
+	password, err := crypt.HashPassword(d.Password)
 	if err != nil {
 		return model.User{}, err
 	}
@@ -67,7 +78,8 @@ func (d UserCreateRequest) ToModel() (model.User, error) {
 		Email:     d.Email,
 		Password:  password,
 		Fullname:  d.Fullname,
-		Status:    model.Active,
+		Status:    model.StatusPendingVerification,
+		Role:      model.RoleUser,
 		CreatedBy: id.String(),
 		UpdatedBy: id.String(),
 	}, nil
@@ -78,6 +90,7 @@ type UserResponse struct {
 	Email     string      `json:"email" validate:"required"`
 	Fullname  string      `json:"fullname" validate:"required"`
 	Status    string      `json:"status" validate:"required"`
+	Role      string      `json:"role" validate:"required"`
 	CreatedAt time.Time   `json:"createdAt" swaggertype:"string" validate:"required" example:"2006-01-02T15:04:05+07:00"`
 	UpdatedAt time.Time   `swaggertype:"string" validate:"required" example:"2006-01-02T15:04:05+07:00" json:"updatedAt"`
 	DeletedAt null.Time   `swaggertype:"string" example:"2006-01-02T15:04:05+07:00" json:"deletedAt"`
@@ -91,7 +104,8 @@ func NewUserResponse(user model.User) UserResponse {
 		Id:        user.Id,
 		Email:     user.Email,
 		Fullname:  user.Fullname,
-		Status:    user.Status,
+		Status:    string(user.Status),
+		Role:      user.Role,
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
 		DeletedAt: user.DeletedAt,
@@ -101,12 +115,540 @@ func NewUserResponse(user model.User) UserResponse {
 	}
 }
 
+// userSelectableFields maps the fields of a user response that may be selected to
+// their columns. The password is never selectable.
+var userSelectableFields = map[UserDTOFieldNameType]model.UserDBFieldNameType{
+	UserDTOFieldName.Id:        model.UserDBFieldName.Id,
+	UserDTOFieldName.Email:     model.UserDBFieldName.Email,
+	UserDTOFieldName.Fullname:  model.UserDBFieldName.Fullname,
+	UserDTOFieldName.Status:    model.UserDBFieldName.Status,
+	UserDTOFieldName.Role:      model.UserDBFieldName.Role,
+	UserDTOFieldName.CreatedAt: model.UserDBFieldName.CreatedAt,
+	UserDTOFieldName.UpdatedAt: model.UserDBFieldName.UpdatedAt,
+	UserDTOFieldName.DeletedAt: model.UserDBFieldName.DeletedAt,
+	UserDTOFieldName.CreatedBy: model.UserDBFieldName.CreatedBy,
+	UserDTOFieldName.UpdatedBy: model.UserDBFieldName.UpdatedBy,
+	UserDTOFieldName.DeletedBy: model.UserDBFieldName.DeletedBy,
+}
+
+// ParseUserFields reads a sparse fieldset, a comma separated list of fields of a
+// user response. An empty fieldset selects every field.
+func ParseUserFields(value string) ([]UserDTOFieldNameType, error) {
+	fields, reasons := parseUserFields(value)
+	if len(reasons) > 0 {
+		return nil, failure.ValidationFailed(map[string][]string{"fields": reasons})
+	}
+	return fields, nil
+}
+
+func parseUserFields(value string) (fields []UserDTOFieldNameType, reasons []string) {
+	seen := make(map[UserDTOFieldNameType]bool)
+	for _, name := range strings.Split(value, ",") {
+		field := UserDTOFieldNameType(strings.TrimSpace(name))
+		if field == "" || seen[field] {
+			continue
+		}
+		seen[field] = true
+		if _, ok := userSelectableFields[field]; !ok {
+			if field == UserDTOFieldName.Password {
+				reasons = append(reasons, fmt.Sprintf("'%s' cannot be selected", field))
+			} else {
+				reasons = append(reasons, fmt.Sprintf("'%s' is not a field", field))
+			}
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields, reasons
+}
+
+// UserDBFieldNames returns the columns of the fields of a user response.
+func UserDBFieldNames(fields []UserDTOFieldNameType) []model.UserDBFieldNameType {
+	columns := make([]model.UserDBFieldNameType, 0, len(fields))
+	for _, field := range fields {
+		if column, ok := userSelectableFields[field]; ok {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// Only returns the response holding the given fields only, keyed by their JSON
+// names.
+func (d UserResponse) Only(fields []UserDTOFieldNameType) map[UserDTOFieldNameType]interface{} {
+	only := make(map[UserDTOFieldNameType]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := d.value(field); ok {
+			only[field] = value
+		}
+	}
+	return only
+}
+
+// value returns a field of the response, or false when there is no such field.
+func (d UserResponse) value(field UserDTOFieldNameType) (interface{}, bool) {
+	switch field {
+	case UserDTOFieldName.Id:
+		return d.Id, true
+	case UserDTOFieldName.Email:
+		return d.Email, true
+	case UserDTOFieldName.Fullname:
+		return d.Fullname, true
+	case UserDTOFieldName.Status:
+		return d.Status, true
+	case UserDTOFieldName.Role:
+		return d.Role, true
+	case UserDTOFieldName.CreatedAt:
+		return d.CreatedAt, true
+	case UserDTOFieldName.UpdatedAt:
+		return d.UpdatedAt, true
+	case UserDTOFieldName.DeletedAt:
+		return d.DeletedAt, true
+	case UserDTOFieldName.CreatedBy:
+		return d.CreatedBy, true
+	case UserDTOFieldName.UpdatedBy:
+		return d.UpdatedBy, true
+	case UserDTOFieldName.DeletedBy:
+		return d.DeletedBy, true
+	}
+	return nil, false
+}
+
+// ClientInfo describes the client a request was sent from.
+type ClientInfo struct {
+	IpAddress string
+	UserAgent string
+}
+
 type UserLoginRequest struct {
-	Email    string `json:"email" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Email    string     `json:"email" validate:"required"`
+	Password string     `json:"password" validate:"required"`
+	Client   ClientInfo `json:"-"`
 }
 
 func (d *UserLoginRequest) Validate() (err error) {
 	validator := shared.GetValidator()
-	return validator.Struct(d)
+	return shared.ValidationError(validator.Struct(d))
+}
+
+// ChallengeMFARequired is the challenge of a login that has to be completed with
+// a second factor.
+const ChallengeMFARequired = "mfa_required"
+
+// UserLoginResponse holds either the tokens of a new session or, when the login
+// is challenged, the challenge and the token to complete it with.
+type UserLoginResponse struct {
+	AccessToken           string     `json:"accessToken,omitempty"`
+	AccessTokenExpiresAt  *time.Time `json:"accessTokenExpiresAt,omitempty" swaggertype:"string" example:"2006-01-02T15:04:05+07:00"`
+	RefreshToken          string     `json:"refreshToken,omitempty"`
+	RefreshTokenExpiresAt *time.Time `json:"refreshTokenExpiresAt,omitempty" swaggertype:"string" example:"2006-01-02T15:04:05+07:00"`
+	Challenge             string     `json:"challenge,omitempty" example:"mfa_required"`
+	MfaToken              string     `json:"mfaToken,omitempty"`
+	MfaTokenExpiresAt     *time.Time `json:"mfaTokenExpiresAt,omitempty" swaggertype:"string" example:"2006-01-02T15:04:05+07:00"`
+}
+
+// UserPatchRequest is a JSON Merge Patch (RFC 7396) of the mutable fields of a
+// user. Fields left out of the patch are nil and keep their value.
+type UserPatchRequest struct {
+	Fullname *string `json:"fullname,omitempty"`
+	Role     *string `json:"role,omitempty" enums:"user,support,admin"`
+	invalid  map[string][]string
+}
+
+const maxFullnameLength = 255
+
+func (d *UserPatchRequest) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return errors.New("patch must be a JSON object")
+	}
+
+	d.invalid = make(map[string][]string)
+	for name, value := range members {
+		var target **string
+		switch name {
+		case string(UserDTOFieldName.Fullname):
+			target = &d.Fullname
+		case string(UserDTOFieldName.Role):
+			target = &d.Role
+		default:
+			d.invalid[name] = append(d.invalid[name], "cannot be changed")
+			continue
+		}
+		// null removes a member in a merge patch, these fields cannot be removed
+		if bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			d.invalid[name] = append(d.invalid[name], "cannot be removed")
+			continue
+		}
+		if err := json.Unmarshal(value, target); err != nil {
+			d.invalid[name] = append(d.invalid[name], "must be a string")
+		}
+	}
+	return nil
+}
+
+func (d *UserPatchRequest) Validate() (err error) {
+	invalid := make(map[string][]string)
+	for name, reasons := range d.invalid {
+		invalid[name] = append(invalid[name], reasons...)
+	}
+	if d.Fullname != nil {
+		field := string(UserDTOFieldName.Fullname)
+		fullname := strings.TrimSpace(*d.Fullname)
+		if fullname == "" {
+			invalid[field] = append(invalid[field], "is required")
+		}
+		if len(fullname) > maxFullnameLength {
+			invalid[field] = append(invalid[field], fmt.Sprintf("must be at most %d characters", maxFullnameLength))
+		}
+		d.Fullname = &fullname
+	}
+	if d.Role != nil {
+		switch *d.Role {
+		case model.RoleUser, model.RoleSupport, model.RoleAdmin:
+		default:
+			field := string(UserDTOFieldName.Role)
+			invalid[field] = append(invalid[field], fmt.Sprintf("must be one of %s, %s, %s", model.RoleUser, model.RoleSupport, model.RoleAdmin))
+		}
+	}
+	if len(invalid) > 0 {
+		return failure.ValidationFailed(invalid)
+	}
+	return nil
+}
+
+type UserMagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (d *UserMagicLinkRequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
+
+type UserMagicLinkLoginRequest struct {
+	Token  string     `json:"token" validate:"required"`
+	Client ClientInfo `json:"-"`
+}
+
+func (d *UserMagicLinkLoginRequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
+
+type UserStatusChangeRequest struct {
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
+func (d *UserStatusChangeRequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
+
+type UserRefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+func (d *UserRefreshTokenRequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
+
+type UserForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (d *UserForgotPasswordRequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
+
+type UserResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,password"`
+}
+
+func (d *UserResetPasswordRequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
+
+type UserVerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func (d *UserVerifyEmailRequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
+
+type UserResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (d *UserResendVerificationRequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
+
+type UserChangePasswordRequest struct {
+	CurrentPassword     string `json:"currentPassword" validate:"required"`
+	NewPassword         string `json:"newPassword" validate:"required,password,nefield=CurrentPassword"`
+	RevokeOtherSessions bool   `json:"revokeOtherSessions"`
+}
+
+func (d *UserChangePasswordRequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
+
+// Directions a listing is sorted in.
+const (
+	SortAscending  = "asc"
+	SortDescending = "desc"
+)
+
+// Scopes of soft deleted users in a listing.
+const (
+	DeletedExclude = "exclude"
+	DeletedInclude = "include"
+	DeletedOnly    = "only"
+)
+
+const (
+	defaultUserListLimit = 20
+	maxUserListLimit     = 100
+	maxUserListPage      = 1000000
+	maxEmailPrefixLength = 255
+)
+
+// userSortFields are the fields users may be sorted by, every field but the
+// password.
+var userSortFields = []UserDTOFieldNameType{
+	UserDTOFieldName.Id,
+	UserDTOFieldName.Email,
+	UserDTOFieldName.Fullname,
+	UserDTOFieldName.Status,
+	UserDTOFieldName.Role,
+	UserDTOFieldName.CreatedAt,
+	UserDTOFieldName.UpdatedAt,
+	UserDTOFieldName.DeletedAt,
+	UserDTOFieldName.CreatedBy,
+	UserDTOFieldName.UpdatedBy,
+	UserDTOFieldName.DeletedBy,
+}
+
+// UserListRequest filters, sorts and paginates a listing of users. Pages are
+// either numbered, or follow on from the cursor returned with the previous page.
+type UserListRequest struct {
+	Statuses    []model.UserStatus
+	EmailPrefix string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Deleted     string
+	SortBy      UserDTOFieldNameType
+	Order       string
+	Limit       int
+	Page        int
+	Cursor      string
+	Fields      []UserDTOFieldNameType
+	invalid     map[string][]string
+}
+
+// NewUserListRequest reads a listing request from the query string of a URL.
+// Statuses are given as repeated or comma separated values.
+func NewUserListRequest(query url.Values) UserListRequest {
+	d := UserListRequest{
+		EmailPrefix: strings.TrimSpace(query.Get("emailPrefix")),
+		Deleted:     DeletedExclude,
+		SortBy:      UserDTOFieldName.CreatedAt,
+		Order:       SortDescending,
+		Limit:       defaultUserListLimit,
+		Cursor:      query.Get("cursor"),
+		invalid:     make(map[string][]string),
+	}
+	for _, statuses := range query["status"] {
+		for _, status := range strings.Split(statuses, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				d.Statuses = append(d.Statuses, model.UserStatus(status))
+			}
+		}
+	}
+	if value := query.Get("deleted"); value != "" {
+		d.Deleted = value
+	}
+	if value := query.Get("sortBy"); value != "" {
+		d.SortBy = UserDTOFieldNameType(value)
+	}
+	if value := query.Get("order"); value != "" {
+		d.Order = value
+	}
+	d.CreatedFrom = d.parseTime(query, "createdFrom")
+	d.CreatedTo = d.parseTime(query, "createdTo")
+	d.Limit = d.parseInt(query, "limit", d.Limit)
+	d.Page = d.parseInt(query, "page", d.Page)
+	var reasons []string
+	if d.Fields, reasons = parseUserFields(query.Get("fields")); len(reasons) > 0 {
+		d.invalid["fields"] = reasons
+	}
+	return d
+}
+
+func (d *UserListRequest) parseTime(query url.Values, name string) time.Time {
+	value := query.Get(name)
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		d.invalid[name] = append(d.invalid[name], "must be an RFC 3339 date and time")
+	}
+	return t
+}
+
+func (d *UserListRequest) parseInt(query url.Values, name string, fallback int) int {
+	value := query.Get(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		d.invalid[name] = append(d.invalid[name], "must be a whole number")
+		return fallback
+	}
+	return n
+}
+
+// IsKeyset reports whether the page follows on from a cursor rather than being
+// numbered.
+func (d UserListRequest) IsKeyset() bool {
+	return d.Cursor != ""
+}
+
+func (d *UserListRequest) Validate() (err error) {
+	invalid := make(map[string][]string)
+	for name, reasons := range d.invalid {
+		invalid[name] = append(invalid[name], reasons...)
+	}
+	for _, status := range d.Statuses {
+		if !status.IsValid() {
+			invalid["status"] = append(invalid["status"], fmt.Sprintf("'%s' is not a status", status))
+		}
+	}
+	if len(d.EmailPrefix) > maxEmailPrefixLength {
+		invalid["emailPrefix"] = append(invalid["emailPrefix"], fmt.Sprintf("must be at most %d characters", maxEmailPrefixLength))
+	}
+	if !d.CreatedFrom.IsZero() && !d.CreatedTo.IsZero() && d.CreatedTo.Before(d.CreatedFrom) {
+		invalid["createdTo"] = append(invalid["createdTo"], "must not be before createdFrom")
+	}
+	switch d.Deleted {
+	case DeletedExclude, DeletedInclude, DeletedOnly:
+	default:
+		invalid["deleted"] = append(invalid["deleted"], fmt.Sprintf("must be one of %s, %s, %s", DeletedExclude, DeletedInclude, DeletedOnly))
+	}
+	if !isUserSortField(d.SortBy) {
+		invalid["sortBy"] = append(invalid["sortBy"], "is not a sortable field")
+	}
+	switch d.Order {
+	case SortAscending, SortDescending:
+	default:
+		invalid["order"] = append(invalid["order"], fmt.Sprintf("must be one of %s, %s", SortAscending, SortDescending))
+	}
+	if d.Limit < 1 || d.Limit > maxUserListLimit {
+		invalid["limit"] = append(invalid["limit"], fmt.Sprintf("must be between 1 and %d", maxUserListLimit))
+	}
+	if d.IsKeyset() && d.Page != 0 {
+		invalid["page"] = append(invalid["page"], "cannot be combined with cursor")
+	}
+	if !d.IsKeyset() {
+		if d.Page == 0 {
+			d.Page = 1
+		}
+		if d.Page < 1 || d.Page > maxUserListPage {
+			invalid["page"] = append(invalid["page"], fmt.Sprintf("must be between 1 and %d", maxUserListPage))
+		}
+	}
+	if len(invalid) > 0 {
+		return failure.ValidationFailed(invalid)
+	}
+	return nil
+}
+
+func isUserSortField(field UserDTOFieldNameType) bool {
+	for _, sortable := range userSortFields {
+		if field == sortable {
+			return true
+		}
+	}
+	return false
+}
+
+// UserListMetadata describes a page of a listing of users. Numbered pages come
+// with the total number of users, pages following on from a cursor do not.
+// NextCursor is left out on the last page.
+type UserListMetadata struct {
+	Limit      int    `json:"limit"`
+	Page       int    `json:"page,omitempty"`
+	TotalCount *int64 `json:"totalCount,omitempty"`
+	TotalPages *int64 `json:"totalPages,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+const (
+	defaultUserSearchLimit = 20
+	maxUserSearchLimit     = 50
+	maxUserSearchLength    = 255
+)
+
+// UserSearchRequest searches users by partial name or email.
+type UserSearchRequest struct {
+	Query   string
+	Limit   int
+	invalid map[string][]string
+}
+
+// NewUserSearchRequest reads a search request from the query string of a URL.
+func NewUserSearchRequest(query url.Values) UserSearchRequest {
+	d := UserSearchRequest{
+		Query:   strings.TrimSpace(query.Get("q")),
+		Limit:   defaultUserSearchLimit,
+		invalid: make(map[string][]string),
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			d.invalid["limit"] = append(d.invalid["limit"], "must be a whole number")
+		} else {
+			d.Limit = limit
+		}
+	}
+	return d
+}
+
+func (d *UserSearchRequest) Validate() (err error) {
+	invalid := make(map[string][]string)
+	for name, reasons := range d.invalid {
+		invalid[name] = append(invalid[name], reasons...)
+	}
+	if len(search.Terms(d.Query)) == 0 {
+		invalid["q"] = append(invalid["q"], "must contain a letter or digit")
+	}
+	if len(d.Query) > maxUserSearchLength {
+		invalid["q"] = append(invalid["q"], fmt.Sprintf("must be at most %d characters", maxUserSearchLength))
+	}
+	if d.Limit < 1 || d.Limit > maxUserSearchLimit {
+		invalid["limit"] = append(invalid["limit"], fmt.Sprintf("must be between 1 and %d", maxUserSearchLimit))
+	}
+	if len(invalid) > 0 {
+		return failure.ValidationFailed(invalid)
+	}
+	return nil
+}
+
+// UserSearchResult is a user matching a search. Highlights hold the matching
+// fields, HTML escaped, with the matching fragments wrapped in <mark> elements.
+type UserSearchResult struct {
+	UserResponse
+	Score      float64                         `json:"score"`
+	Highlights map[UserDTOFieldNameType]string `json:"highlights"`
 }
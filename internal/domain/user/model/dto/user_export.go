@@ -0,0 +1,269 @@
+package dto
+
+import (
+	"github.com/guregu/null/v5"
+
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+)
+
+// Formats users are exported in. The columnar format is NDJSON with a fixed
+// schema: every line holds every column, in the same order and with the same
+// type, timestamps being Unix milliseconds. It loads as is into columnar
+// stores and converts to Parquet without type inference.
+const (
+	UserExportFormatCSV      = "csv"
+	UserExportFormatJSONL    = "jsonl"
+	UserExportFormatColumnar = "columnar"
+)
+
+var userExportContentTypes = map[string]string{
+	UserExportFormatCSV:      "text/csv; charset=utf-8",
+	UserExportFormatJSONL:    "application/x-ndjson",
+	UserExportFormatColumnar: "application/x-ndjson",
+}
+
+// Types of the columns of a columnar export.
+const (
+	UserExportTypeString      = "string"
+	UserExportTypeTimestampMs = "timestamp_ms"
+)
+
+// UserExportColumn is a column of a columnar export.
+type UserExportColumn struct {
+	Name     UserDTOFieldNameType `json:"name"`
+	Type     string               `json:"type"`
+	Nullable bool                 `json:"nullable"`
+}
+
+// UserExportRequest exports every user of a listing. It takes the filters, sort
+// and fieldset of a listing, but is not paginated.
+type UserExportRequest struct {
+	UserListRequest
+	Format string
+}
+
+// NewUserExportRequest reads an export request from the query string of a URL.
+func NewUserExportRequest(query url.Values) UserExportRequest {
+	d := UserExportRequest{
+		UserListRequest: NewUserListRequest(query),
+		Format:          UserExportFormatCSV,
+	}
+	for _, name := range []string{"limit", "page", "cursor"} {
+		if query.Has(name) {
+			d.invalid[name] = append(d.invalid[name], "cannot be used with an export")
+		}
+	}
+	if value := query.Get("format"); value != "" {
+		d.Format = value
+	}
+	return d
+}
+
+func (d *UserExportRequest) Validate() (err error) {
+	if _, ok := userExportContentTypes[d.Format]; !ok {
+		d.invalid["format"] = append(d.invalid["format"], fmt.Sprintf("must be one of %s, %s, %s", UserExportFormatCSV, UserExportFormatJSONL, UserExportFormatColumnar))
+	}
+	return d.UserListRequest.Validate()
+}
+
+// ContentType returns the media type of the export.
+func (d UserExportRequest) ContentType() string {
+	return userExportContentTypes[d.Format]
+}
+
+// FileName returns the name of the export file, stamped with the time it was
+// made at.
+func (d UserExportRequest) FileName(at time.Time) string {
+	extension := "jsonl"
+	if d.Format == UserExportFormatCSV {
+		extension = "csv"
+	}
+	return fmt.Sprintf("users-%s.%s", at.UTC().Format("20060102T150405Z"), extension)
+}
+
+// Columns returns the fields exported, the fieldset or every field but the
+// password.
+func (d UserExportRequest) Columns() []UserDTOFieldNameType {
+	if len(d.Fields) > 0 {
+		return d.Fields
+	}
+	// the sortable fields are every field but the password
+	return append([]UserDTOFieldNameType(nil), userSortFields...)
+}
+
+// UserExportSchema returns the schema of a columnar export of the fields.
+func UserExportSchema(fields []UserDTOFieldNameType) []UserExportColumn {
+	schema := make([]UserExportColumn, 0, len(fields))
+	for _, field := range fields {
+		column := UserExportColumn{Name: field, Type: UserExportTypeString}
+		switch field {
+		case UserDTOFieldName.CreatedAt, UserDTOFieldName.UpdatedAt:
+			column.Type = UserExportTypeTimestampMs
+		case UserDTOFieldName.DeletedAt:
+			column.Type = UserExportTypeTimestampMs
+			column.Nullable = true
+		case UserDTOFieldName.DeletedBy:
+			column.Nullable = true
+		}
+		schema = append(schema, column)
+	}
+	return schema
+}
+
+// UserExportWriter writes users one after the other in an export format. The
+// output is buffered, Flush writes it through.
+type UserExportWriter interface {
+	Write(user UserResponse) error
+	Flush() error
+}
+
+// NewUserExportWriter returns a writer of users in the format, holding the given
+// fields. The format must be valid.
+func NewUserExportWriter(w io.Writer, format string, fields []UserDTOFieldNameType) UserExportWriter {
+	switch format {
+	case UserExportFormatJSONL:
+		buffer := bufio.NewWriter(w)
+		return &userJSONLWriter{buffer: buffer, encoder: json.NewEncoder(buffer), fields: fields}
+	case UserExportFormatColumnar:
+		names := make([][]byte, 0, len(fields))
+		for _, field := range fields {
+			name, _ := json.Marshal(field)
+			names = append(names, name)
+		}
+		return &userColumnarWriter{buffer: bufio.NewWriter(w), fields: fields, names: names}
+	}
+	return &userCSVWriter{csv: csv.NewWriter(w), fields: fields}
+}
+
+type userCSVWriter struct {
+	csv           *csv.Writer
+	fields        []UserDTOFieldNameType
+	headerWritten bool
+	record        []string
+}
+
+func (e *userCSVWriter) Write(user UserResponse) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.record = e.record[:0]
+	for _, field := range e.fields {
+		e.record = append(e.record, userCSVValue(user, field))
+	}
+	return e.csv.Write(e.record)
+}
+
+func (e *userCSVWriter) Flush() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+// writeHeader writes the names of the fields before the first user, or on its
+// own when there is no user.
+func (e *userCSVWriter) writeHeader() error {
+	if e.headerWritten {
+		return nil
+	}
+	e.headerWritten = true
+	header := make([]string, 0, len(e.fields))
+	for _, field := range e.fields {
+		header = append(header, string(field))
+	}
+	return e.csv.Write(header)
+}
+
+// userCSVValue returns a field of a user as a CSV value. Times are in UTC and
+// nulls are empty.
+func userCSVValue(user UserResponse, field UserDTOFieldNameType) string {
+	value, _ := user.value(field)
+	switch value := value.(type) {
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano)
+	case null.Time:
+		if !value.Valid {
+			return ""
+		}
+		return value.Time.UTC().Format(time.RFC3339Nano)
+	case null.String:
+		return value.String
+	case fmt.Stringer:
+		return value.String()
+	case string:
+		return value
+	}
+	return ""
+}
+
+type userJSONLWriter struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+	fields  []UserDTOFieldNameType
+}
+
+func (e *userJSONLWriter) Write(user UserResponse) error {
+	return e.encoder.Encode(user.Only(e.fields))
+}
+
+func (e *userJSONLWriter) Flush() error {
+	return e.buffer.Flush()
+}
+
+type userColumnarWriter struct {
+	buffer *bufio.Writer
+	fields []UserDTOFieldNameType
+	names  [][]byte
+}
+
+func (e *userColumnarWriter) Write(user UserResponse) error {
+	e.buffer.WriteByte('{')
+	for i, field := range e.fields {
+		if i > 0 {
+			e.buffer.WriteByte(',')
+		}
+		value, err := json.Marshal(userColumnarValue(user, field))
+		if err != nil {
+			return err
+		}
+		e.buffer.Write(e.names[i])
+		e.buffer.WriteByte(':')
+		e.buffer.Write(value)
+	}
+	_, err := e.buffer.WriteString("}\n")
+	return err
+}
+
+func (e *userColumnarWriter) Flush() error {
+	return e.buffer.Flush()
+}
+
+// userColumnarValue returns a field of a user as typed by the columnar schema.
+func userColumnarValue(user UserResponse, field UserDTOFieldNameType) interface{} {
+	value, _ := user.value(field)
+	switch value := value.(type) {
+	case time.Time:
+		return value.UnixMilli()
+	case null.Time:
+		if !value.Valid {
+			return nil
+		}
+		return value.Time.UnixMilli()
+	case null.String:
+		if !value.Valid {
+			return nil
+		}
+		return value.String
+	case fmt.Stringer:
+		return value.String()
+	default:
+		return value
+	}
+}
@@ -0,0 +1,93 @@
+package dto_test
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/guregu/null/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+)
+
+func TestUserExportRequest(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		exportRequest := dto.NewUserExportRequest(url.Values{})
+		assert.NoError(t, exportRequest.Validate())
+		assert.Equal(t, dto.UserExportFormatCSV, exportRequest.Format)
+		assert.NotContains(t, exportRequest.Columns(), dto.UserDTOFieldName.Password)
+		assert.Len(t, exportRequest.Columns(), 11)
+		assert.Equal(t, "users-20240102T030405Z.csv", exportRequest.FileName(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+	})
+
+	t.Run("No pagination", func(t *testing.T) {
+		exportRequest := dto.NewUserExportRequest(url.Values{"limit": {"10"}, "format": {"xml"}})
+
+		var f *failure.Failure
+		if assert.ErrorAs(t, exportRequest.Validate(), &f) {
+			assert.Equal(t, []string{"cannot be used with an export"}, f.Fields["limit"])
+			assert.Equal(t, []string{"must be one of csv, jsonl, columnar"}, f.Fields["format"])
+		}
+	})
+
+	t.Run("Password cannot be exported", func(t *testing.T) {
+		exportRequest := dto.NewUserExportRequest(url.Values{"fields": {"email,password"}})
+		assert.Error(t, exportRequest.Validate())
+	})
+}
+
+func TestUserExportWriter(t *testing.T) {
+	id := uuid.MustParse("cb6b3eeb-2fa0-4492-91eb-67a7101a5424")
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("WIB", 7*60*60))
+	user := dto.UserResponse{Id: id, Email: "jane@example.com", Fullname: "Doe, Jane", Status: "active", CreatedAt: createdAt}
+	fields := []dto.UserDTOFieldNameType{
+		dto.UserDTOFieldName.Id,
+		dto.UserDTOFieldName.Fullname,
+		dto.UserDTOFieldName.CreatedAt,
+		dto.UserDTOFieldName.DeletedAt,
+		dto.UserDTOFieldName.DeletedBy,
+	}
+
+	export := func(format string, users ...dto.UserResponse) string {
+		var output bytes.Buffer
+		writer := dto.NewUserExportWriter(&output, format, fields)
+		for _, user := range users {
+			assert.NoError(t, writer.Write(user))
+		}
+		assert.NoError(t, writer.Flush())
+		return output.String()
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		assert.Equal(t,
+			"id,fullname,createdAt,deletedAt,deletedBy\n"+
+				"cb6b3eeb-2fa0-4492-91eb-67a7101a5424,\"Doe, Jane\",2024-01-01T20:04:05Z,,\n",
+			export(dto.UserExportFormatCSV, user))
+		assert.Equal(t, "id,fullname,createdAt,deletedAt,deletedBy\n", export(dto.UserExportFormatCSV))
+	})
+
+	t.Run("JSONL", func(t *testing.T) {
+		assert.JSONEq(t,
+			`{"id":"cb6b3eeb-2fa0-4492-91eb-67a7101a5424","fullname":"Doe, Jane","createdAt":"2024-01-02T03:04:05+07:00","deletedAt":null,"deletedBy":null}`,
+			export(dto.UserExportFormatJSONL, user))
+	})
+
+	t.Run("Columnar", func(t *testing.T) {
+		deleted := user
+		deleted.DeletedAt = null.TimeFrom(createdAt.Add(time.Second))
+		deleted.DeletedBy = null.StringFrom("admin")
+		assert.Equal(t,
+			`{"id":"cb6b3eeb-2fa0-4492-91eb-67a7101a5424","fullname":"Doe, Jane","createdAt":1704139445000,"deletedAt":null,"deletedBy":null}`+"\n"+
+				`{"id":"cb6b3eeb-2fa0-4492-91eb-67a7101a5424","fullname":"Doe, Jane","createdAt":1704139445000,"deletedAt":1704139446000,"deletedBy":"admin"}`+"\n",
+			export(dto.UserExportFormatColumnar, user, deleted))
+
+		schema := dto.UserExportSchema(fields)
+		assert.Equal(t, dto.UserExportColumn{Name: dto.UserDTOFieldName.CreatedAt, Type: dto.UserExportTypeTimestampMs}, schema[2])
+		assert.Equal(t, dto.UserExportColumn{Name: dto.UserDTOFieldName.DeletedAt, Type: dto.UserExportTypeTimestampMs, Nullable: true}, schema[3])
+		assert.Equal(t, dto.UserExportColumn{Name: dto.UserDTOFieldName.DeletedBy, Type: dto.UserExportTypeString, Nullable: true}, schema[4])
+	})
+}
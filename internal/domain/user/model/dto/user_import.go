@@ -0,0 +1,273 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/shared"
+	"github.com/IlhamRobyana/user/shared/crypt"
+	"github.com/IlhamRobyana/user/shared/failure"
+)
+
+// Formats users are imported from.
+const (
+	UserImportFormatCSV   = "csv"
+	UserImportFormatJSONL = "jsonl"
+)
+
+const (
+	// MaxUserImportRows is the number of users a single import may hold.
+	MaxUserImportRows = 10000
+	// MaxUserImportLineLength is the longest line of an import, in bytes.
+	MaxUserImportLineLength = 64 * 1024
+)
+
+// UserImportFormatFromContentType returns the import format of a request body.
+func UserImportFormatFromContentType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", failure.BadRequestFromString("content type must be text/csv or application/x-ndjson")
+	}
+	switch mediaType {
+	case "text/csv":
+		return UserImportFormatCSV, nil
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return UserImportFormatJSONL, nil
+	}
+	return "", failure.BadRequestFromString("content type must be text/csv or application/x-ndjson")
+}
+
+// UserImportRow is a user to import. The password is given either in plain text,
+// in which case it must follow the password policy, or already hashed with
+// bcrypt or argon2id.
+type UserImportRow struct {
+	Line         int    `json:"-"`
+	Email        string `json:"email" validate:"required,email,max=255"`
+	Fullname     string `json:"fullname" validate:"required,max=255"`
+	Password     string `json:"password" validate:"omitempty,password"`
+	PasswordHash string `json:"passwordHash" validate:"omitempty,password_hash"`
+	Role         string `json:"role" validate:"omitempty,oneof=user support admin"`
+	Status       string `json:"status" validate:"omitempty,oneof=pending_verification active"`
+	invalid      map[string][]string
+}
+
+// Validate returns the reasons per field the row cannot be imported for.
+func (d *UserImportRow) Validate() (err error) {
+	invalid := make(map[string][]string)
+	for name, reasons := range d.invalid {
+		invalid[name] = append(invalid[name], reasons...)
+	}
+	d.Email = strings.TrimSpace(d.Email)
+	d.Fullname = strings.TrimSpace(d.Fullname)
+	if err = shared.ValidationError(shared.GetValidator().Struct(d)); err != nil {
+		var validationFailure *failure.Failure
+		if !errors.As(err, &validationFailure) || validationFailure.Fields == nil {
+			return err
+		}
+		for name, reasons := range validationFailure.Fields {
+			invalid[name] = append(invalid[name], reasons...)
+		}
+	}
+	switch {
+	case d.Password == "" && d.PasswordHash == "":
+		invalid["password"] = append(invalid["password"], "is required unless passwordHash is given")
+	case d.Password != "" && d.PasswordHash != "":
+		invalid["passwordHash"] = append(invalid["passwordHash"], "cannot be given along with password")
+	}
+	if len(invalid) > 0 {
+		return failure.ValidationFailed(invalid)
+	}
+	return nil
+}
+
+// ToModel returns the user the row imports, created by the given actor. Plain
+// text passwords are hashed, hashed ones are kept as they are.
+func (d UserImportRow) ToModel(createdBy string) (model.User, error) {
+	password := d.PasswordHash
+	if password == "" {
+		var err error
+		if password, err = crypt.HashPassword(d.Password); err != nil {
+			return model.User{}, err
+		}
+	}
+	user := model.User{
+		Id:        uuid.New(),
+		Email:     d.Email,
+		Password:  password,
+		Fullname:  d.Fullname,
+		Status:    model.StatusPendingVerification,
+		Role:      model.RoleUser,
+		CreatedBy: createdBy,
+		UpdatedBy: createdBy,
+	}
+	if d.Status != "" {
+		user.Status = model.UserStatus(d.Status)
+	}
+	if d.Role != "" {
+		user.Role = d.Role
+	}
+	return user, nil
+}
+
+// ParseUserImport reads the rows of an import. CSV input starts with a header
+// naming the columns, JSONL input holds a JSON object per line. Rows that cannot
+// be read are returned along with the others and fail validation.
+func ParseUserImport(r io.Reader, format string) ([]UserImportRow, error) {
+	switch format {
+	case UserImportFormatCSV:
+		return parseUserImportCSV(r)
+	case UserImportFormatJSONL:
+		return parseUserImportJSONL(r)
+	}
+	return nil, failure.BadRequestFromString(fmt.Sprintf("'%s' is not an import format", format))
+}
+
+func parseUserImportCSV(r io.Reader) (rows []UserImportRow, err error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, failure.BadRequestFromString("import is empty")
+	}
+	if err != nil {
+		return nil, userImportReadError(err)
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		switch name {
+		case "email", "fullname", "password", "passwordHash", "role", "status":
+		default:
+			return nil, failure.BadRequestFromString(fmt.Sprintf("'%s' is not an import column", name))
+		}
+		for _, column := range columns[:i] {
+			if column == name {
+				return nil, failure.BadRequestFromString(fmt.Sprintf("column '%s' is given twice", name))
+			}
+		}
+		columns[i] = name
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			if len(rows) == 0 {
+				return nil, failure.BadRequestFromString("import is empty")
+			}
+			return rows, nil
+		}
+		// a syntax error leaves no field positions to read the line from
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, userImportReadError(err)
+		}
+		line, _ := reader.FieldPos(0)
+		row := UserImportRow{Line: line, invalid: make(map[string][]string)}
+		if err != nil {
+			row.invalid["row"] = append(row.invalid["row"], fmt.Sprintf("must have %d columns", len(columns)))
+		}
+		for i, value := range record {
+			if i >= len(columns) {
+				break
+			}
+			switch columns[i] {
+			case "email":
+				row.Email = value
+			case "fullname":
+				row.Fullname = value
+			case "password":
+				row.Password = value
+			case "passwordHash":
+				row.PasswordHash = value
+			case "role":
+				row.Role = value
+			case "status":
+				row.Status = value
+			}
+		}
+		if len(rows) == MaxUserImportRows {
+			return nil, failure.BadRequestFromString(fmt.Sprintf("import must hold at most %d users", MaxUserImportRows))
+		}
+		rows = append(rows, row)
+	}
+}
+
+func parseUserImportJSONL(r io.Reader) (rows []UserImportRow, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), MaxUserImportLineLength)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		row := UserImportRow{Line: line, invalid: make(map[string][]string)}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row); err != nil {
+			row = UserImportRow{Line: line, invalid: map[string][]string{
+				"row": {fmt.Sprintf("must be a JSON object of user fields: %s", strings.TrimPrefix(err.Error(), "json: "))},
+			}}
+		} else if decoder.More() {
+			row.invalid["row"] = append(row.invalid["row"], "must hold a single JSON object")
+		}
+		if len(rows) == MaxUserImportRows {
+			return nil, failure.BadRequestFromString(fmt.Sprintf("import must hold at most %d users", MaxUserImportRows))
+		}
+		rows = append(rows, row)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, userImportReadError(err)
+	}
+	if len(rows) == 0 {
+		return nil, failure.BadRequestFromString("import is empty")
+	}
+	return rows, nil
+}
+
+// userImportReadError turns an error reading an import into a bad request,
+// unless the import could not be received at all.
+func userImportReadError(err error) error {
+	var (
+		parseError    *csv.ParseError
+		maxBytesError *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &parseError):
+		return failure.BadRequestFromString(fmt.Sprintf("import is not valid CSV: %s", parseError.Error()))
+	case errors.Is(err, bufio.ErrTooLong):
+		return failure.BadRequestFromString(fmt.Sprintf("import lines must be at most %d bytes", MaxUserImportLineLength))
+	case errors.As(err, &maxBytesError):
+		return failure.BadRequestFromString(fmt.Sprintf("import must be at most %d bytes", maxBytesError.Limit))
+	}
+	return failure.BadRequest(err)
+}
+
+// UserImportRowError tells why a row of an import cannot be imported. Line is the
+// line of the row in the import.
+type UserImportRowError struct {
+	Line   int                 `json:"line"`
+	Email  string              `json:"email,omitempty"`
+	Fields map[string][]string `json:"fields"`
+}
+
+// UserImportReport sums up an import. Users are only imported when every row is
+// valid and the import is not a dry run.
+type UserImportReport struct {
+	DryRun   bool                 `json:"dryRun"`
+	Total    int                  `json:"total"`
+	Valid    int                  `json:"valid"`
+	Imported int                  `json:"imported"`
+	Errors   []UserImportRowError `json:"errors"`
+}
@@ -0,0 +1,137 @@
+package dto_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/crypt"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserImportFormat(t *testing.T) {
+	format, err := dto.UserImportFormatFromContentType("text/csv; charset=utf-8")
+	assert.NoError(t, err)
+	assert.Equal(t, dto.UserImportFormatCSV, format)
+
+	format, err = dto.UserImportFormatFromContentType("application/x-ndjson")
+	assert.NoError(t, err)
+	assert.Equal(t, dto.UserImportFormatJSONL, format)
+
+	_, err = dto.UserImportFormatFromContentType("application/json")
+	assert.Error(t, err)
+}
+
+func TestParseUserImport(t *testing.T) {
+	t.Run("CSV", func(t *testing.T) {
+		rows, err := dto.ParseUserImport(strings.NewReader(
+			"email,fullname,password,role\n"+
+				"jane@example.com,Jane Doe,Str0ngPassword,admin\n"+
+				"\"john@example.com\",\"Doe, John\",Str0ngPassword\n",
+		), dto.UserImportFormatCSV)
+		assert.NoError(t, err)
+		if assert.Len(t, rows, 2) {
+			assert.Equal(t, 2, rows[0].Line)
+			assert.Equal(t, model.RoleAdmin, rows[0].Role)
+			assert.NoError(t, rows[0].Validate())
+
+			assert.Equal(t, 3, rows[1].Line)
+			assert.Equal(t, "Doe, John", rows[1].Fullname)
+			var f *failure.Failure
+			if assert.ErrorAs(t, rows[1].Validate(), &f) {
+				assert.Equal(t, []string{"must have 4 columns"}, f.Fields["row"])
+			}
+		}
+	})
+
+	t.Run("CSV unknown column", func(t *testing.T) {
+		_, err := dto.ParseUserImport(strings.NewReader("email,fullname,nickname\n"), dto.UserImportFormatCSV)
+		assert.Error(t, err)
+	})
+
+	t.Run("CSV syntax error", func(t *testing.T) {
+		_, err := dto.ParseUserImport(strings.NewReader("email,fullname,password\na\"b,c,d\n"), dto.UserImportFormatCSV)
+		assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+	})
+
+	t.Run("JSONL", func(t *testing.T) {
+		rows, err := dto.ParseUserImport(strings.NewReader(
+			`{"email":"jane@example.com","fullname":"Jane Doe","password":"Str0ngPassword"}`+"\n\n"+
+				`{"email":"john@example.com","nickname":"john"}`+"\n"+
+				`not json`+"\n",
+		), dto.UserImportFormatJSONL)
+		assert.NoError(t, err)
+		if assert.Len(t, rows, 3) {
+			assert.NoError(t, rows[0].Validate())
+			assert.Equal(t, 3, rows[1].Line)
+			assert.Error(t, rows[1].Validate())
+			assert.Equal(t, 4, rows[2].Line)
+			assert.Error(t, rows[2].Validate())
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		_, err := dto.ParseUserImport(strings.NewReader("email,fullname,password\n"), dto.UserImportFormatCSV)
+		assert.Error(t, err)
+		_, err = dto.ParseUserImport(strings.NewReader("\n"), dto.UserImportFormatJSONL)
+		assert.Error(t, err)
+	})
+}
+
+func TestUserImportRow(t *testing.T) {
+	bcryptHash, err := crypt.Bcrypt{Cost: 4}.Hash("Str0ngPassword")
+	assert.NoError(t, err)
+
+	t.Run("Hashed password", func(t *testing.T) {
+		row := dto.UserImportRow{Email: " jane@example.com ", Fullname: "Jane Doe", PasswordHash: bcryptHash}
+		assert.NoError(t, row.Validate())
+
+		user, err := row.ToModel("admin-id")
+		assert.NoError(t, err)
+		assert.Equal(t, "jane@example.com", user.Email)
+		assert.Equal(t, bcryptHash, user.Password)
+		assert.Equal(t, model.StatusPendingVerification, user.Status)
+		assert.Equal(t, model.RoleUser, user.Role)
+		assert.Equal(t, "admin-id", user.CreatedBy)
+	})
+
+	t.Run("Invalid fields", func(t *testing.T) {
+		row := dto.UserImportRow{Email: "jane", Fullname: "Jane Doe", PasswordHash: "$2a$10$short", Role: "owner", Status: "locked"}
+
+		var f *failure.Failure
+		if assert.ErrorAs(t, row.Validate(), &f) {
+			assert.Equal(t, []string{"must be a valid email"}, f.Fields["email"])
+			assert.Equal(t, []string{"must be a bcrypt or argon2id hash"}, f.Fields["passwordHash"])
+			assert.Equal(t, []string{"must be one of user, support, admin"}, f.Fields["role"])
+			assert.Equal(t, []string{"must be one of pending_verification, active"}, f.Fields["status"])
+		}
+	})
+
+	t.Run("Malformed argon2id hash", func(t *testing.T) {
+		valid := dto.UserImportRow{Email: "jane@example.com", Fullname: "Jane Doe",
+			PasswordHash: "$argon2id$v=19$m=65536,t=3,p=2$AAAAAAAAAAAAAAAAAAAAAA$AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}
+		assert.NoError(t, valid.Validate())
+
+		row := valid
+		row.PasswordHash = strings.Replace(row.PasswordHash, "t=3", "t=0", 1)
+		var f *failure.Failure
+		if assert.ErrorAs(t, row.Validate(), &f) {
+			assert.Equal(t, []string{"must be a bcrypt or argon2id hash"}, f.Fields["passwordHash"])
+		}
+	})
+
+	t.Run("Exactly one password", func(t *testing.T) {
+		var f *failure.Failure
+		row := dto.UserImportRow{Email: "jane@example.com", Fullname: "Jane Doe"}
+		if assert.ErrorAs(t, row.Validate(), &f) {
+			assert.Equal(t, []string{"is required unless passwordHash is given"}, f.Fields["password"])
+		}
+		row = dto.UserImportRow{Email: "jane@example.com", Fullname: "Jane Doe", Password: "Str0ngPassword", PasswordHash: bcryptHash}
+		if assert.ErrorAs(t, row.Validate(), &f) {
+			assert.Equal(t, []string{"cannot be given along with password"}, f.Fields["passwordHash"])
+		}
+	})
+}
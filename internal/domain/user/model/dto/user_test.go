@@ -0,0 +1,154 @@
+package dto_test
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserPatchRequest(t *testing.T) {
+	t.Run("Changed fields only", func(t *testing.T) {
+		var patch dto.UserPatchRequest
+		assert.NoError(t, json.Unmarshal([]byte(`{"fullname":"  Jane Doe "}`), &patch))
+		assert.NoError(t, patch.Validate())
+		if assert.NotNil(t, patch.Fullname) {
+			assert.Equal(t, "Jane Doe", *patch.Fullname)
+		}
+		assert.Nil(t, patch.Role)
+	})
+
+	t.Run("Empty patch", func(t *testing.T) {
+		var patch dto.UserPatchRequest
+		assert.NoError(t, json.Unmarshal([]byte(`{}`), &patch))
+		assert.NoError(t, patch.Validate())
+		assert.Nil(t, patch.Fullname)
+		assert.Nil(t, patch.Role)
+	})
+
+	t.Run("Invalid members", func(t *testing.T) {
+		var patch dto.UserPatchRequest
+		body := `{"fullname":null,"role":"owner","email":"jane@example.com","status":1}`
+		assert.NoError(t, json.Unmarshal([]byte(body), &patch))
+
+		var f *failure.Failure
+		if assert.ErrorAs(t, patch.Validate(), &f) {
+			assert.Equal(t, []string{"cannot be removed"}, f.Fields["fullname"])
+			assert.Equal(t, []string{"must be one of user, support, admin"}, f.Fields["role"])
+			assert.Equal(t, []string{"cannot be changed"}, f.Fields["email"])
+			assert.Equal(t, []string{"cannot be changed"}, f.Fields["status"])
+		}
+	})
+
+	t.Run("Blank fullname", func(t *testing.T) {
+		var patch dto.UserPatchRequest
+		assert.NoError(t, json.Unmarshal([]byte(`{"fullname":" "}`), &patch))
+		var f *failure.Failure
+		if assert.ErrorAs(t, patch.Validate(), &f) {
+			assert.Equal(t, []string{"is required"}, f.Fields["fullname"])
+		}
+	})
+
+	t.Run("Not an object", func(t *testing.T) {
+		var patch dto.UserPatchRequest
+		assert.Error(t, json.Unmarshal([]byte(`["fullname"]`), &patch))
+	})
+}
+
+func TestUserListRequest(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		listRequest := dto.NewUserListRequest(url.Values{})
+		assert.NoError(t, listRequest.Validate())
+		assert.Equal(t, dto.DeletedExclude, listRequest.Deleted)
+		assert.Equal(t, dto.UserDTOFieldName.CreatedAt, listRequest.SortBy)
+		assert.Equal(t, dto.SortDescending, listRequest.Order)
+		assert.Equal(t, 20, listRequest.Limit)
+		assert.Equal(t, 1, listRequest.Page)
+		assert.False(t, listRequest.IsKeyset())
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		query, _ := url.ParseQuery("status=active,locked&status=suspended&emailPrefix=jane&createdFrom=2024-01-01T00:00:00Z&createdTo=2024-02-01T00:00:00Z&deleted=include&sortBy=email&order=asc&limit=50&cursor=abc")
+		listRequest := dto.NewUserListRequest(query)
+		assert.NoError(t, listRequest.Validate())
+		assert.Equal(t, []model.UserStatus{model.StatusActive, model.StatusLocked, model.StatusSuspended}, listRequest.Statuses)
+		assert.Equal(t, "jane", listRequest.EmailPrefix)
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), listRequest.CreatedFrom)
+		assert.Equal(t, dto.DeletedInclude, listRequest.Deleted)
+		assert.Equal(t, dto.UserDTOFieldName.Email, listRequest.SortBy)
+		assert.Equal(t, 50, listRequest.Limit)
+		assert.Zero(t, listRequest.Page)
+		assert.True(t, listRequest.IsKeyset())
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		query, _ := url.ParseQuery("status=inactive&createdFrom=yesterday&deleted=all&sortBy=password&order=up&limit=1000&page=2&cursor=abc")
+		listRequest := dto.NewUserListRequest(query)
+
+		var f *failure.Failure
+		if assert.ErrorAs(t, listRequest.Validate(), &f) {
+			assert.Contains(t, f.Fields, "status")
+			assert.Contains(t, f.Fields, "createdFrom")
+			assert.Contains(t, f.Fields, "deleted")
+			assert.Contains(t, f.Fields, "sortBy")
+			assert.Contains(t, f.Fields, "order")
+			assert.Contains(t, f.Fields, "limit")
+			assert.Equal(t, []string{"cannot be combined with cursor"}, f.Fields["page"])
+		}
+	})
+
+	t.Run("Reversed created range", func(t *testing.T) {
+		query, _ := url.ParseQuery("createdFrom=2024-02-01T00:00:00Z&createdTo=2024-01-01T00:00:00Z&page=0")
+		listRequest := dto.NewUserListRequest(query)
+
+		var f *failure.Failure
+		if assert.ErrorAs(t, listRequest.Validate(), &f) {
+			assert.Equal(t, []string{"must not be before createdFrom"}, f.Fields["createdTo"])
+		}
+	})
+}
+
+func TestUserFields(t *testing.T) {
+	t.Run("Sparse fieldset", func(t *testing.T) {
+		fields, err := dto.ParseUserFields(" id,email , fullname,email")
+		assert.NoError(t, err)
+		assert.Equal(t, []dto.UserDTOFieldNameType{dto.UserDTOFieldName.Id, dto.UserDTOFieldName.Email, dto.UserDTOFieldName.Fullname}, fields)
+		assert.Equal(t, []model.UserDBFieldNameType{model.UserDBFieldName.Id, model.UserDBFieldName.Email, model.UserDBFieldName.Fullname}, dto.UserDBFieldNames(fields))
+	})
+
+	t.Run("Every field by default", func(t *testing.T) {
+		fields, err := dto.ParseUserFields("")
+		assert.NoError(t, err)
+		assert.Empty(t, fields)
+	})
+
+	t.Run("Unknown fields and password", func(t *testing.T) {
+		_, err := dto.ParseUserFields("id,password,created_at")
+
+		var f *failure.Failure
+		if assert.ErrorAs(t, err, &f) {
+			assert.Equal(t, []string{"'password' cannot be selected", "'created_at' is not a field"}, f.Fields["fields"])
+		}
+	})
+
+	t.Run("Only requested keys", func(t *testing.T) {
+		userResponse := dto.UserResponse{Email: "jane@example.com", Fullname: "Jane Doe"}
+		payload, err := json.Marshal(userResponse.Only([]dto.UserDTOFieldNameType{dto.UserDTOFieldName.Email}))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"email":"jane@example.com"}`, string(payload))
+	})
+
+	t.Run("Listing fieldset", func(t *testing.T) {
+		listRequest := dto.NewUserListRequest(url.Values{"fields": {"id,password"}})
+
+		var f *failure.Failure
+		if assert.ErrorAs(t, listRequest.Validate(), &f) {
+			assert.Equal(t, []string{"'password' cannot be selected"}, f.Fields["fields"])
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"github.com/guregu/null/v5"
+
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/shared"
+)
+
+// WebAuthnCeremonyResponse holds the options to pass to navigator.credentials and
+// the id of the ceremony to finish it with.
+type WebAuthnCeremonyResponse struct {
+	CeremonyId string      `json:"ceremonyId" validate:"required"`
+	Options    interface{} `json:"options" validate:"required"`
+}
+
+type WebAuthnRegistrationRequest struct {
+	CeremonyId string          `json:"ceremonyId" validate:"required"`
+	Name       string          `json:"name" validate:"max=64" example:"My laptop"`
+	Credential json.RawMessage `json:"credential" swaggertype:"object" validate:"required"`
+}
+
+func (d *WebAuthnRegistrationRequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
+
+type WebAuthnLoginRequest struct {
+	CeremonyId string          `json:"ceremonyId" validate:"required"`
+	Credential json.RawMessage `json:"credential" swaggertype:"object" validate:"required"`
+	Client     ClientInfo      `json:"-"`
+}
+
+func (d *WebAuthnLoginRequest) Validate() (err error) {
+	validator := shared.GetValidator()
+	return shared.ValidationError(validator.Struct(d))
+}
+
+type WebAuthnCredentialResponse struct {
+	Id         uuid.UUID `json:"id" swaggertype:"string" validate:"required" example:"cb6b3eeb-2fa0-4492-91eb-67a7101a5424"`
+	Name       string    `json:"name" example:"My laptop"`
+	Transports []string  `json:"transports" example:"internal"`
+	CreatedAt  time.Time `json:"createdAt" swaggertype:"string" validate:"required" example:"2006-01-02T15:04:05+07:00"`
+	LastUsedAt null.Time `json:"lastUsedAt" swaggertype:"string" example:"2006-01-02T15:04:05+07:00"`
+}
+
+func NewWebAuthnCredentialResponse(credential model.WebAuthnCredential) WebAuthnCredentialResponse {
+	transports := []string{}
+	if credential.Transports != "" {
+		transports = strings.Split(credential.Transports, ",")
+	}
+	return WebAuthnCredentialResponse{
+		Id:         credential.Id,
+		Name:       credential.Name,
+		Transports: transports,
+		CreatedAt:  credential.CreatedAt,
+		LastUsedAt: credential.LastUsedAt,
+	}
+}
+
+func NewWebAuthnCredentialListResponse(credentials model.WebAuthnCredentialList) []WebAuthnCredentialResponse {
+	responses := make([]WebAuthnCredentialResponse, 0, len(credentials))
+	for _, credential := range credentials {
+		responses = append(responses, NewWebAuthnCredentialResponse(*credential))
+	}
+	return responses
+}
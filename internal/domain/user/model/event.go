@@ -0,0 +1,45 @@
+package model
+
+import (
+	"github.com/google/uuid"
+
+	"time"
+)
+
+// Topics of the user domain events published on the PubSub.
+const (
+	TopicUserLockedOut     = "user.locked_out"
+	TopicUserStatusChanged = "user.status_changed"
+)
+
+// Reasons given in user domain events.
+const (
+	ReasonFailedLoginAttempts = "failed_login_attempts"
+	ReasonRepeatedLockouts    = "repeated_lockouts"
+	ReasonDeletedByOwner      = "deleted_by_owner"
+	ReasonDeletedByStaff      = "deleted_by_staff"
+	ReasonRetentionExpired    = "retention_expired"
+)
+
+// UserLockedOutEvent is published when a user is temporarily locked out of
+// logging in.
+type UserLockedOutEvent struct {
+	UserId      uuid.UUID `json:"userId"`
+	Email       string    `json:"email"`
+	Fullname    string    `json:"fullname"`
+	Reason      string    `json:"reason"`
+	LockedUntil time.Time `json:"lockedUntil"`
+	OccurredAt  time.Time `json:"occurredAt"`
+}
+
+// UserStatusChangedEvent is published on every change of the status of a user.
+type UserStatusChangedEvent struct {
+	UserId     uuid.UUID  `json:"userId"`
+	Email      string     `json:"email"`
+	Fullname   string     `json:"fullname"`
+	From       UserStatus `json:"from"`
+	To         UserStatus `json:"to"`
+	Actor      Actor      `json:"actor"`
+	Reason     string     `json:"reason"`
+	OccurredAt time.Time  `json:"occurredAt"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/guregu/null/v5"
+
+	"time"
+)
+
+// MFA is the TOTP second factor of a user. Secret is stored encrypted and the
+// factor only takes part in logins once Enabled is set by a confirmed code.
+type MFA struct {
+	UserId    uuid.UUID `db:"user_id"`
+	Secret    string    `db:"secret"`
+	Enabled   bool      `db:"enabled"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// RecoveryCode is a one-time code replacing a TOTP code, stored as a hash.
+type RecoveryCode struct {
+	Id        uuid.UUID `db:"id"`
+	UserId    uuid.UUID `db:"user_id"`
+	CodeHash  string    `db:"code_hash"`
+	UsedAt    null.Time `db:"used_at"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type RecoveryCodeList []*RecoveryCode
@@ -0,0 +1,12 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// RefreshToken is a stored refresh token. Tokens issued by rotating each other
+// share the same FamilyId so the whole chain can be revoked at once.
+type RefreshToken struct {
+	UserId   uuid.UUID
+	FamilyId uuid.UUID
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"github.com/google/uuid"
+
+	"time"
+)
+
+// Session is a login of a user from a client. Its id is shared with the refresh
+// token family and the access tokens issued for the login.
+type Session struct {
+	Id         uuid.UUID `db:"id"`
+	UserId     uuid.UUID `db:"user_id"`
+	IpAddress  string    `db:"ip_address"`
+	UserAgent  string    `db:"user_agent"`
+	CreatedAt  time.Time `db:"created_at"`
+	LastSeenAt time.Time `db:"last_seen_at"`
+}
+
+type SessionList []*Session
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// UserStatus is the lifecycle state of a user account. Changes between states go
+// through the transitions below only.
+type UserStatus string
+
+const (
+	StatusPendingVerification UserStatus = "pending_verification"
+	StatusActive              UserStatus = "active"
+	StatusLocked              UserStatus = "locked"
+	StatusSuspended           UserStatus = "suspended"
+	StatusDeactivated         UserStatus = "deactivated"
+	StatusDeleted             UserStatus = "deleted"
+)
+
+// Kinds of actors that change the status of a user.
+const (
+	ActorKindSystem = "system"
+	ActorKindOwner  = "owner"
+	ActorKindStaff  = "staff"
+)
+
+// Actor is who changes a user: the service itself, the owner of the account or a
+// staff member.
+type Actor struct {
+	Kind string `json:"kind"`
+	Id   string `json:"id"`
+}
+
+// SystemActor is the actor of changes the service makes on its own.
+func SystemActor() Actor {
+	return Actor{Kind: ActorKindSystem, Id: ActorSystem}
+}
+
+// OwnerActor is the actor of changes users make to their own account.
+func OwnerActor(userID uuid.UUID) Actor {
+	return Actor{Kind: ActorKindOwner, Id: userID.String()}
+}
+
+// StaffActor is the actor of changes staff members make to other accounts.
+func StaffActor(userID uuid.UUID) Actor {
+	return Actor{Kind: ActorKindStaff, Id: userID.String()}
+}
+
+// userStatusTransitions lists, per current status, the statuses a user may move
+// to and the kinds of actors allowed to make that move.
+var userStatusTransitions = map[UserStatus]map[UserStatus][]string{
+	StatusPendingVerification: {
+		StatusActive:      {ActorKindOwner, ActorKindStaff},
+		StatusDeactivated: {ActorKindStaff},
+		StatusDeleted:     {ActorKindOwner, ActorKindStaff},
+	},
+	StatusActive: {
+		StatusLocked:      {ActorKindSystem},
+		StatusSuspended:   {ActorKindStaff},
+		StatusDeactivated: {ActorKindStaff},
+		StatusDeleted:     {ActorKindOwner, ActorKindStaff},
+	},
+	StatusLocked: {
+		StatusActive:      {ActorKindStaff},
+		StatusDeactivated: {ActorKindStaff},
+		StatusDeleted:     {ActorKindOwner, ActorKindStaff},
+	},
+	StatusSuspended: {
+		StatusActive:      {ActorKindStaff},
+		StatusDeactivated: {ActorKindStaff},
+		StatusDeleted:     {ActorKindOwner, ActorKindStaff},
+	},
+	StatusDeactivated: {
+		StatusActive:  {ActorKindStaff},
+		StatusDeleted: {ActorKindStaff},
+	},
+	// a deleted user is restored to the status it had before
+	StatusDeleted: {
+		StatusPendingVerification: {ActorKindStaff},
+		StatusActive:              {ActorKindStaff},
+		StatusLocked:              {ActorKindStaff},
+		StatusSuspended:           {ActorKindStaff},
+		StatusDeactivated:         {ActorKindStaff},
+	},
+}
+
+// IsValid reports whether s is a known status.
+func (s UserStatus) IsValid() bool {
+	_, ok := userStatusTransitions[s]
+	return ok
+}
+
+// CanLogin reports whether users in this status may log in.
+func (s UserStatus) CanLogin() bool {
+	return s == StatusActive
+}
+
+// CanTransitionTo reports whether an actor of the given kind may move a user from
+// this status to the other one.
+func (s UserStatus) CanTransitionTo(to UserStatus, actorKind string) bool {
+	for _, kind := range userStatusTransitions[s][to] {
+		if kind == actorKind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/guregu/null/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserStatusTransitions(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      model.UserStatus
+		to        model.UserStatus
+		actorKind string
+		allowed   bool
+	}{
+		{"Owner verifies email", model.StatusPendingVerification, model.StatusActive, model.ActorKindOwner, true},
+		{"System locks active user", model.StatusActive, model.StatusLocked, model.ActorKindSystem, true},
+		{"Staff cannot lock", model.StatusActive, model.StatusLocked, model.ActorKindStaff, false},
+		{"Staff suspends", model.StatusActive, model.StatusSuspended, model.ActorKindStaff, true},
+		{"Owner cannot suspend", model.StatusActive, model.StatusSuspended, model.ActorKindOwner, false},
+		{"Staff unlocks", model.StatusLocked, model.StatusActive, model.ActorKindStaff, true},
+		{"Owner cannot unlock", model.StatusLocked, model.StatusActive, model.ActorKindOwner, false},
+		{"Staff reactivates", model.StatusDeactivated, model.StatusActive, model.ActorKindStaff, true},
+		{"Active to active", model.StatusActive, model.StatusActive, model.ActorKindStaff, false},
+		{"Pending cannot be locked", model.StatusPendingVerification, model.StatusLocked, model.ActorKindSystem, false},
+		{"Staff restores deleted", model.StatusDeleted, model.StatusActive, model.ActorKindStaff, true},
+		{"Owner cannot restore", model.StatusDeleted, model.StatusActive, model.ActorKindOwner, false},
+		{"Staff restores deleted as unverified", model.StatusDeleted, model.StatusPendingVerification, model.ActorKindStaff, true},
+		{"Staff restores deleted as suspended", model.StatusDeleted, model.StatusSuspended, model.ActorKindStaff, true},
+		{"Deleted stays deleted", model.StatusDeleted, model.StatusDeleted, model.ActorKindStaff, false},
+		{"Unknown status", model.UserStatus("inactive"), model.StatusActive, model.ActorKindStaff, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, tt.from.CanTransitionTo(tt.to, tt.actorKind))
+		})
+	}
+}
+
+func TestUserStatus(t *testing.T) {
+	assert.True(t, model.StatusSuspended.IsValid())
+	assert.False(t, model.UserStatus("inactive").IsValid())
+	assert.True(t, model.StatusActive.CanLogin())
+	assert.False(t, model.StatusLocked.CanLogin())
+}
+
+func TestUserRestoreStatus(t *testing.T) {
+	user := model.User{Status: model.StatusDeleted, StatusBeforeDelete: null.StringFrom(string(model.StatusSuspended))}
+	assert.Equal(t, model.StatusSuspended, user.RestoreStatus())
+
+	// users deleted before the status was kept must verify their email again
+	user.StatusBeforeDelete = null.String{}
+	assert.Equal(t, model.StatusPendingVerification, user.RestoreStatus())
+	user.StatusBeforeDelete = null.StringFrom("inactive")
+	assert.Equal(t, model.StatusPendingVerification, user.RestoreStatus())
+}
+
+func TestRoleOutranks(t *testing.T) {
+	assert.True(t, model.RoleOutranks(model.RoleAdmin, model.RoleSupport))
+	assert.True(t, model.RoleOutranks(model.RoleSupport, model.RoleUser))
+	assert.False(t, model.RoleOutranks(model.RoleSupport, model.RoleSupport))
+	assert.False(t, model.RoleOutranks(model.RoleSupport, model.RoleAdmin))
+	assert.True(t, model.RoleOutranks(model.RoleUser, "unknown"))
+}
+
+func TestAuditActionForTransition(t *testing.T) {
+	assert.Equal(t, model.AuditActionSuspend, model.AuditActionForTransition(model.StatusActive, model.StatusSuspended))
+	assert.Equal(t, model.AuditActionDelete, model.AuditActionForTransition(model.StatusActive, model.StatusDeleted))
+	assert.Equal(t, model.AuditActionRestore, model.AuditActionForTransition(model.StatusDeleted, model.StatusActive))
+}
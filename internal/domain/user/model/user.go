@@ -10,8 +10,9 @@ import (
 )
 
 const (
-	Active   = "active"
-	Inactive = "inactive"
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
 )
 
 type UserDBFieldNameType string
@@ -22,12 +23,15 @@ type userDBFieldName struct {
 	Password  UserDBFieldNameType
 	Fullname  UserDBFieldNameType
 	Status    UserDBFieldNameType
+	Role      UserDBFieldNameType
 	CreatedAt UserDBFieldNameType
 	UpdatedAt UserDBFieldNameType
 	DeletedAt UserDBFieldNameType
 	CreatedBy UserDBFieldNameType
 	UpdatedBy UserDBFieldNameType
 	DeletedBy UserDBFieldNameType
+
+	StatusBeforeDelete UserDBFieldNameType
 }
 
 var UserDBFieldName = userDBFieldName{
@@ -36,12 +40,15 @@ var UserDBFieldName = userDBFieldName{
 	Password:  "password",
 	Fullname:  "fullname",
 	Status:    "status",
+	Role:      "role",
 	CreatedAt: "created_at",
 	UpdatedAt: "updated_at",
 	DeletedAt: "deleted_at",
 	CreatedBy: "created_by",
 	UpdatedBy: "updated_by",
 	DeletedBy: "deleted_by",
+
+	StatusBeforeDelete: "status_before_delete",
 }
 
 type User struct {
@@ -49,17 +56,59 @@ type User struct {
 	Email     string      `db:"email"`
 	Password  string      `db:"password"`
 	Fullname  string      `db:"fullname"`
-	Status    string      `db:"status"`
+	Status    UserStatus  `db:"status"`
+	Role      string      `db:"role"`
 	CreatedAt time.Time   `db:"created_at"`
 	UpdatedAt time.Time   `db:"updated_at"`
 	DeletedAt null.Time   `db:"deleted_at"`
 	CreatedBy string      `db:"created_by"`
 	UpdatedBy string      `db:"updated_by"`
 	DeletedBy null.String `db:"deleted_by"`
+
+	// StatusBeforeDelete is the status a soft deleted user is restored to.
+	StatusBeforeDelete null.String `db:"status_before_delete"`
 }
 
 type UserList []*User
 
 func (u User) ComparePassword(password string) (bool, error) {
-	return crypt.CompareBcrypt(password, u.Password)
+	return crypt.ComparePassword(password, u.Password)
+}
+
+// PasswordNeedsRehash reports whether the stored password hash was made with an
+// outdated algorithm or parameters.
+func (u User) PasswordNeedsRehash() bool {
+	return crypt.PasswordNeedsRehash(u.Password)
+}
+
+// IsStaff reports whether the user may manage accounts other than their own.
+func (u User) IsStaff() bool {
+	return isStaffRole(u.Role)
+}
+
+func isStaffRole(role string) bool {
+	return role == RoleAdmin || role == RoleSupport
+}
+
+// RestoreStatus returns the status a soft deleted user is restored to, the one it
+// had when it was deleted. Users deleted before that status was kept have to
+// verify their email again.
+func (u User) RestoreStatus() UserStatus {
+	status := UserStatus(u.StatusBeforeDelete.String)
+	if !u.StatusBeforeDelete.Valid || !status.IsValid() || status == StatusDeleted {
+		return StatusPendingVerification
+	}
+	return status
+}
+
+var roleRanks = map[string]int{
+	RoleUser:    1,
+	RoleSupport: 2,
+	RoleAdmin:   3,
+}
+
+// RoleOutranks reports whether role ranks above other. Unknown roles rank below
+// every known one.
+func RoleOutranks(role, other string) bool {
+	return roleRanks[role] > roleRanks[other]
 }
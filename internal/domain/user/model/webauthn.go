@@ -0,0 +1,26 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/guregu/null/v5"
+
+	"time"
+)
+
+// WebAuthnCredential is a passkey of a user. CredentialId is the id assigned by the
+// authenticator and SignCount its signature counter as of the last login.
+type WebAuthnCredential struct {
+	Id              uuid.UUID `db:"id"`
+	UserId          uuid.UUID `db:"user_id"`
+	CredentialId    []byte    `db:"credential_id"`
+	PublicKey       []byte    `db:"public_key"`
+	AttestationType string    `db:"attestation_type"`
+	AAGUID          []byte    `db:"aaguid"`
+	SignCount       uint32    `db:"sign_count"`
+	Transports      string    `db:"transports"`
+	Name            string    `db:"name"`
+	CreatedAt       time.Time `db:"created_at"`
+	LastUsedAt      null.Time `db:"last_used_at"`
+}
+
+type WebAuthnCredentialList []*WebAuthnCredential
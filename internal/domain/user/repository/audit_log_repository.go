@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+
+	"context"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/shared/failure"
+)
+
+// insertAuditLog records an audit log entry as part of the change it describes.
+func insertAuditLog(ctx context.Context, tx *sqlx.Tx, auditLog model.AuditLog) error {
+	_, err := tx.ExecContext(ctx, auditLogQueries.insertAuditLog,
+		auditLog.Id, auditLog.UserId, auditLog.Action, auditLog.Reason, auditLog.Actor, auditLog.CreatedAt)
+	if err != nil {
+		log.Error().Err(err).Msg("[insertAuditLog] failed insert audit log")
+		return failure.InternalError(err)
+	}
+	return nil
+}
+
+var (
+	auditLogQueries = struct {
+		insertAuditLog string
+	}{
+		insertAuditLog: "INSERT INTO `user_audit_log` (`id`, `user_id`, `action`, `reason`, `actor`, `created_at`) VALUES (?, ?, ?, ?, ?, ?)",
+	}
+)
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/shared/failure"
+)
+
+func (repo *MFARepositoryMySQL) ResolveMFAByUserID(ctx context.Context, userID uuid.UUID) (mfa model.MFA, err error) {
+	query := mfaQueries.selectMFA + " WHERE `user_id` = ?"
+	err = repo.DB.Read.GetContext(ctx, &mfa, query, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = failure.NotFound(fmt.Sprintf("mfa of user with id '%s' not found", userID))
+			return
+		}
+		log.Error().Err(err).Msg("[ResolveMFAByUserID] failed get mfa")
+		err = failure.InternalError(err)
+	}
+	return
+}
+
+// UpsertMFA stores a new, not yet enabled secret for the user replacing any
+// previous one.
+func (repo *MFARepositoryMySQL) UpsertMFA(ctx context.Context, mfa *model.MFA) (err error) {
+	_, err = exec(ctx, repo.DB, mfaQueries.upsertMFA, []interface{}{
+		mfa.UserId,
+		mfa.Secret,
+		mfa.Enabled,
+		mfa.CreatedAt,
+		mfa.UpdatedAt,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("[UpsertMFA] failed exec upsert mfa query")
+	}
+	return
+}
+
+// EnableMFA enables the second factor of the user and replaces its recovery codes.
+func (repo *MFARepositoryMySQL) EnableMFA(ctx context.Context, userID uuid.UUID, recoveryCodes model.RecoveryCodeList) (err error) {
+	return repo.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		_, err := tx.ExecContext(ctx, mfaQueries.enableMFA, time.Now(), userID)
+		if err != nil {
+			log.Error().Err(err).Msg("[EnableMFA] failed enable mfa")
+			e <- failure.InternalError(err)
+			return
+		}
+		if err = deleteRecoveryCodes(ctx, tx, userID); err != nil {
+			e <- err
+			return
+		}
+		for _, recoveryCode := range recoveryCodes {
+			_, err = tx.ExecContext(ctx, mfaQueries.insertRecoveryCode,
+				recoveryCode.Id, recoveryCode.UserId, recoveryCode.CodeHash, recoveryCode.CreatedAt)
+			if err != nil {
+				log.Error().Err(err).Msg("[EnableMFA] failed insert recovery code")
+				e <- failure.InternalError(err)
+				return
+			}
+		}
+		e <- nil
+	})
+}
+
+// UseRecoveryCode marks an unused recovery code of the user as used and reports
+// whether there was one.
+func (repo *MFARepositoryMySQL) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (used bool, err error) {
+	result, err := exec(ctx, repo.DB, mfaQueries.useRecoveryCode, []interface{}{time.Now(), userID, codeHash})
+	if err != nil {
+		log.Error().Err(err).Msg("[UseRecoveryCode] failed use recovery code")
+		return
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Error().Err(err).Msg("[UseRecoveryCode] failed get affected rows")
+		return false, failure.InternalError(err)
+	}
+	return affected == 1, nil
+}
+
+// DeleteMFA removes the second factor of the user together with its recovery codes.
+func (repo *MFARepositoryMySQL) DeleteMFA(ctx context.Context, userID uuid.UUID) (err error) {
+	return repo.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := deleteRecoveryCodes(ctx, tx, userID); err != nil {
+			e <- err
+			return
+		}
+		if _, err := tx.ExecContext(ctx, mfaQueries.deleteMFA, userID); err != nil {
+			log.Error().Err(err).Msg("[DeleteMFA] failed delete mfa")
+			e <- failure.InternalError(err)
+			return
+		}
+		e <- nil
+	})
+}
+
+func deleteRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx, mfaQueries.deleteRecoveryCodes, userID); err != nil {
+		log.Error().Err(err).Msg("[deleteRecoveryCodes] failed delete recovery codes")
+		return failure.InternalError(err)
+	}
+	return nil
+}
+
+var (
+	mfaQueries = struct {
+		selectMFA           string
+		upsertMFA           string
+		enableMFA           string
+		deleteMFA           string
+		insertRecoveryCode  string
+		useRecoveryCode     string
+		deleteRecoveryCodes string
+	}{
+		selectMFA: "SELECT `user_id`,`secret`,`enabled`,`created_at`,`updated_at` FROM `user_mfa`",
+		upsertMFA: "INSERT INTO `user_mfa` (`user_id`,`secret`,`enabled`,`created_at`,`updated_at`) VALUES (?,?,?,?,?) " +
+			"ON DUPLICATE KEY UPDATE `secret` = VALUES(`secret`), `enabled` = VALUES(`enabled`), `updated_at` = VALUES(`updated_at`)",
+		enableMFA:           "UPDATE `user_mfa` SET `enabled` = 1, `updated_at` = ? WHERE `user_id` = ?",
+		deleteMFA:           "DELETE FROM `user_mfa` WHERE `user_id` = ?",
+		insertRecoveryCode:  "INSERT INTO `user_mfa_recovery_code` (`id`,`user_id`,`code_hash`,`created_at`) VALUES (?,?,?,?)",
+		useRecoveryCode:     "UPDATE `user_mfa_recovery_code` SET `used_at` = ? WHERE `user_id` = ? AND `code_hash` = ? AND `used_at` IS NULL",
+		deleteRecoveryCodes: "DELETE FROM `user_mfa_recovery_code` WHERE `user_id` = ?",
+	}
+)
+
+type MFARepository interface {
+	ResolveMFAByUserID(ctx context.Context, userID uuid.UUID) (model.MFA, error)
+	UpsertMFA(ctx context.Context, mfa *model.MFA) error
+	EnableMFA(ctx context.Context, userID uuid.UUID, recoveryCodes model.RecoveryCodeList) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	DeleteMFA(ctx context.Context, userID uuid.UUID) error
+}
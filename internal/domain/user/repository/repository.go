@@ -14,6 +14,9 @@ import (
 
 type Repository interface {
 	UserRepository
+	SessionRepository
+	MFARepository
+	WebAuthnRepository
 }
 
 // UserRepositoryMySQL is the MySQL-backed implementation of UserRepository.
@@ -28,12 +31,52 @@ func ProvideUserRepositoryMySQL(db *infras.MySQLConn) *UserRepositoryMySQL {
 	return s
 }
 
+// SessionRepositoryMySQL is the MySQL-backed implementation of SessionRepository.
+type SessionRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+// ProvideSessionRepositoryMySQL is the provider for this repository.
+func ProvideSessionRepositoryMySQL(db *infras.MySQLConn) *SessionRepositoryMySQL {
+	s := new(SessionRepositoryMySQL)
+	s.DB = db
+	return s
+}
+
+// MFARepositoryMySQL is the MySQL-backed implementation of MFARepository.
+type MFARepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+// ProvideMFARepositoryMySQL is the provider for this repository.
+func ProvideMFARepositoryMySQL(db *infras.MySQLConn) *MFARepositoryMySQL {
+	s := new(MFARepositoryMySQL)
+	s.DB = db
+	return s
+}
+
+// WebAuthnRepositoryMySQL is the MySQL-backed implementation of WebAuthnRepository.
+type WebAuthnRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+// ProvideWebAuthnRepositoryMySQL is the provider for this repository.
+func ProvideWebAuthnRepositoryMySQL(db *infras.MySQLConn) *WebAuthnRepositoryMySQL {
+	s := new(WebAuthnRepositoryMySQL)
+	s.DB = db
+	return s
+}
+
 func (repo *UserRepositoryMySQL) exec(ctx context.Context, command string, args []interface{}) (sql.Result, error) {
+	return exec(ctx, repo.DB, command, args)
+}
+
+func exec(ctx context.Context, db *infras.MySQLConn, command string, args []interface{}) (sql.Result, error) {
 	var (
 		stmt *sqlx.Stmt
 		err  error
 	)
-	stmt, err = repo.DB.Write.PreparexContext(ctx, command)
+	stmt, err = db.Write.PreparexContext(ctx, command)
 	if err != nil {
 		log.Error().Err(err).Msg("[exec] failed prepare query")
 		return nil, failure.InternalError(err)
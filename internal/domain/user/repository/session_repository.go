@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/shared/failure"
+)
+
+func (repo *SessionRepositoryMySQL) CreateSession(ctx context.Context, session *model.Session) (err error) {
+	_, err = exec(ctx, repo.DB, sessionQueries.insertSession, []interface{}{
+		session.Id,
+		session.UserId,
+		session.IpAddress,
+		session.UserAgent,
+		session.CreatedAt,
+		session.LastSeenAt,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("[CreateSession] failed exec create session query")
+	}
+	return
+}
+
+func (repo *SessionRepositoryMySQL) ResolveSessionByID(ctx context.Context, sessionID uuid.UUID) (session model.Session, err error) {
+	query := sessionQueries.selectSession + " WHERE `id` = ?"
+	err = repo.DB.Read.GetContext(ctx, &session, query, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = failure.NotFound(fmt.Sprintf("session with id '%s' not found", sessionID))
+			return
+		}
+		log.Error().Err(err).Msg("[ResolveSessionByID] failed get session")
+		err = failure.InternalError(err)
+	}
+	return
+}
+
+func (repo *SessionRepositoryMySQL) ResolveSessionsByUserID(ctx context.Context, userID uuid.UUID) (sessions model.SessionList, err error) {
+	query := sessionQueries.selectSession + " WHERE `user_id` = ? ORDER BY `last_seen_at` DESC"
+	err = repo.DB.Read.SelectContext(ctx, &sessions, query, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("[ResolveSessionsByUserID] failed get sessions")
+		err = failure.InternalError(err)
+	}
+	return
+}
+
+func (repo *SessionRepositoryMySQL) UpdateSessionLastSeen(ctx context.Context, sessionID uuid.UUID, lastSeenAt time.Time) (err error) {
+	query := fmt.Sprintf(sessionQueries.updateSession, "`last_seen_at` = ? WHERE `id` = ?")
+	_, err = exec(ctx, repo.DB, query, []interface{}{lastSeenAt, sessionID})
+	if err != nil {
+		log.Error().Err(err).Msg("[UpdateSessionLastSeen] failed update session")
+	}
+	return
+}
+
+func (repo *SessionRepositoryMySQL) DeleteSessionByID(ctx context.Context, sessionID uuid.UUID) (err error) {
+	_, err = exec(ctx, repo.DB, sessionQueries.deleteSession+" WHERE `id` = ?", []interface{}{sessionID})
+	if err != nil {
+		log.Error().Err(err).Msg("[DeleteSessionByID] failed delete session")
+	}
+	return
+}
+
+func (repo *SessionRepositoryMySQL) DeleteSessionsByUserID(ctx context.Context, userID uuid.UUID) (err error) {
+	_, err = exec(ctx, repo.DB, sessionQueries.deleteSession+" WHERE `user_id` = ?", []interface{}{userID})
+	if err != nil {
+		log.Error().Err(err).Msg("[DeleteSessionsByUserID] failed delete sessions")
+	}
+	return
+}
+
+var (
+	sessionQueries = struct {
+		selectSession string
+		insertSession string
+		updateSession string
+		deleteSession string
+	}{
+		selectSession: "SELECT `id`,`user_id`,`ip_address`,`user_agent`,`created_at`,`last_seen_at` FROM `user_session`",
+		insertSession: "INSERT INTO `user_session` (`id`,`user_id`,`ip_address`,`user_agent`,`created_at`,`last_seen_at`) VALUES (?,?,?,?,?,?)",
+		updateSession: "UPDATE `user_session` SET %s ",
+		deleteSession: "DELETE FROM `user_session`",
+	}
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *model.Session) error
+	ResolveSessionByID(ctx context.Context, sessionID uuid.UUID) (model.Session, error)
+	ResolveSessionsByUserID(ctx context.Context, userID uuid.UUID) (model.SessionList, error)
+	UpdateSessionLastSeen(ctx context.Context, sessionID uuid.UUID, lastSeenAt time.Time) error
+	DeleteSessionByID(ctx context.Context, sessionID uuid.UUID) error
+	DeleteSessionsByUserID(ctx context.Context, userID uuid.UUID) error
+}
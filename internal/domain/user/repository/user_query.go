@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/guregu/null/v5"
+
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/shared/failure"
+)
+
+// UserDeletedScope tells whether a listing of users holds soft deleted users.
+type UserDeletedScope int
+
+const (
+	// UserDeletedExcluded leaves soft deleted users out, the default.
+	UserDeletedExcluded UserDeletedScope = iota
+	// UserDeletedIncluded lists soft deleted users along with the others.
+	UserDeletedIncluded
+	// UserDeletedOnly lists soft deleted users only.
+	UserDeletedOnly
+)
+
+// UserFilter narrows down a listing of users. Zero values do not filter.
+type UserFilter struct {
+	Statuses    []model.UserStatus
+	EmailPrefix string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Deleted     UserDeletedScope
+}
+
+// UserSort orders a listing of users by a field. Users with the same value are
+// ordered by id in the same direction, so the order is stable across pages.
+type UserSort struct {
+	Field      UserField
+	Descending bool
+}
+
+// UserCursor points at the last user of a page, the next page starts right after
+// it. A cursor is only valid for the sort it was made with.
+type UserCursor struct {
+	Sort  UserSort
+	Value null.String
+	Id    uuid.UUID
+}
+
+// UserListQuery is a page of a listing of users. The page starts after Cursor
+// when there is one, and Offset users into the listing otherwise.
+type UserListQuery struct {
+	Filter UserFilter
+	Sort   UserSort
+	Limit  int
+	Offset int
+	Cursor *UserCursor
+}
+
+type userCursorJSON struct {
+	Field      UserField   `json:"f"`
+	Descending bool        `json:"d,omitempty"`
+	Value      null.String `json:"v"`
+	Id         uuid.UUID   `json:"i"`
+}
+
+// ErrInvalidUserCursor is returned when a cursor cannot be decoded.
+var ErrInvalidUserCursor = errors.New("invalid user cursor")
+
+// Encode returns the opaque form of the cursor handed out to clients.
+func (c UserCursor) Encode() string {
+	payload, _ := json.Marshal(userCursorJSON{
+		Field:      c.Sort.Field,
+		Descending: c.Sort.Descending,
+		Value:      c.Value,
+		Id:         c.Id,
+	})
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// DecodeUserCursor reads a cursor from its opaque form.
+func DecodeUserCursor(encoded string) (UserCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return UserCursor{}, ErrInvalidUserCursor
+	}
+	var cursor userCursorJSON
+	if err = json.Unmarshal(payload, &cursor); err != nil || !isSortableUserField(cursor.Field) {
+		return UserCursor{}, ErrInvalidUserCursor
+	}
+	if cursor.Value.Valid && isTimeUserField(cursor.Field) {
+		if _, err = time.Parse(time.RFC3339Nano, cursor.Value.String); err != nil {
+			return UserCursor{}, ErrInvalidUserCursor
+		}
+	}
+	return UserCursor{
+		Sort:  UserSort{Field: cursor.Field, Descending: cursor.Descending},
+		Value: cursor.Value,
+		Id:    cursor.Id,
+	}, nil
+}
+
+// NewUserCursor returns the cursor pointing at user in a listing sorted by sort.
+func NewUserCursor(user model.User, sort UserSort) UserCursor {
+	return UserCursor{
+		Sort:  sort,
+		Value: userSortValue(user, sort.Field),
+		Id:    user.Id,
+	}
+}
+
+// ForSort lists the fields users may be sorted by.
+func (ss UserSelectFields) ForSort() UserFieldList {
+	return ss.Public()
+}
+
+func isSortableUserField(field UserField) bool {
+	for _, sortable := range NewUserSelectFields().ForSort() {
+		if field == sortable {
+			return true
+		}
+	}
+	return false
+}
+
+func isTimeUserField(field UserField) bool {
+	selectField := NewUserSelectFields()
+	switch field {
+	case selectField.CreatedAt(), selectField.UpdatedAt(), selectField.DeletedAt():
+		return true
+	}
+	return false
+}
+
+// userSortValue returns the value of a field of user as it is kept in a cursor.
+func userSortValue(user model.User, field UserField) null.String {
+	selectField := NewUserSelectFields()
+	switch field {
+	case selectField.Id():
+		return null.StringFrom(user.Id.String())
+	case selectField.Email():
+		return null.StringFrom(user.Email)
+	case selectField.Fullname():
+		return null.StringFrom(user.Fullname)
+	case selectField.Status():
+		return null.StringFrom(string(user.Status))
+	case selectField.Role():
+		return null.StringFrom(user.Role)
+	case selectField.CreatedAt():
+		return null.StringFrom(user.CreatedAt.Format(time.RFC3339Nano))
+	case selectField.UpdatedAt():
+		return null.StringFrom(user.UpdatedAt.Format(time.RFC3339Nano))
+	case selectField.DeletedAt():
+		if !user.DeletedAt.Valid {
+			return null.String{}
+		}
+		return null.StringFrom(user.DeletedAt.Time.Format(time.RFC3339Nano))
+	case selectField.CreatedBy():
+		return null.StringFrom(user.CreatedBy)
+	case selectField.UpdatedBy():
+		return null.StringFrom(user.UpdatedBy)
+	case selectField.DeletedBy():
+		return user.DeletedBy
+	}
+	return null.String{}
+}
+
+// userCursorArg returns the query argument comparing a field with the value kept
+// in a cursor.
+func userCursorArg(cursor UserCursor) interface{} {
+	if isTimeUserField(cursor.Sort.Field) {
+		t, _ := time.Parse(time.RFC3339Nano, cursor.Value.String)
+		return t
+	}
+	return cursor.Value.String
+}
+
+// composeUserListWhere returns the where clause of a listing of users, or an
+// empty string when nothing is filtered.
+func composeUserListWhere(filter UserFilter, cursor *UserCursor) (whereQry string, args []interface{}) {
+	var conditions []string
+	switch filter.Deleted {
+	case UserDeletedExcluded:
+		conditions = append(conditions, "`deleted_at` IS NULL")
+	case UserDeletedOnly:
+		conditions = append(conditions, "`deleted_at` IS NOT NULL")
+	}
+	if len(filter.Statuses) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(filter.Statuses)), ",")
+		conditions = append(conditions, fmt.Sprintf("`status` IN (%s)", placeholders))
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if filter.EmailPrefix != "" {
+		conditions = append(conditions, "`email` LIKE ?")
+		args = append(args, escapeLike(filter.EmailPrefix)+"%")
+	}
+	if !filter.CreatedFrom.IsZero() {
+		conditions = append(conditions, "`created_at` >= ?")
+		args = append(args, filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		conditions = append(conditions, "`created_at` <= ?")
+		args = append(args, filter.CreatedTo)
+	}
+	if cursor != nil {
+		condition, cursorArgs := composeUserCursorWhere(*cursor)
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// composeUserCursorWhere returns the condition of the users coming after the
+// cursor. NULLs come first in ascending order and last in descending order.
+func composeUserCursorWhere(cursor UserCursor) (string, []interface{}) {
+	field := fmt.Sprintf("`%s`", string(cursor.Sort.Field))
+	comparison := ">"
+	if cursor.Sort.Descending {
+		comparison = "<"
+	}
+
+	if !cursor.Value.Valid {
+		condition := fmt.Sprintf("(%s IS NULL AND `id` %s ?)", field, comparison)
+		if !cursor.Sort.Descending {
+			condition = fmt.Sprintf("(%s IS NULL AND `id` > ?) OR %s IS NOT NULL", field, field)
+		}
+		return "(" + condition + ")", []interface{}{cursor.Id}
+	}
+
+	value := userCursorArg(cursor)
+	condition := fmt.Sprintf("%s %s ? OR (%s = ? AND `id` %s ?)", field, comparison, field, comparison)
+	if cursor.Sort.Descending {
+		condition += fmt.Sprintf(" OR %s IS NULL", field)
+	}
+	return "(" + condition + ")", []interface{}{value, value, cursor.Id}
+}
+
+// composeUserOrderBy returns the order by clause of a listing of users. The field
+// must be sortable.
+func composeUserOrderBy(sort UserSort) (string, error) {
+	if !isSortableUserField(sort.Field) {
+		return "", failure.BadRequestFromString(fmt.Sprintf("users cannot be sorted by %s", sort.Field))
+	}
+	direction := "ASC"
+	if sort.Descending {
+		direction = "DESC"
+	}
+	return fmt.Sprintf(" ORDER BY `%s` %s, `id` %s", string(sort.Field), direction, direction), nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// minFullTextTermLength is the shortest term the full-text index holds, the
+// default innodb_ft_min_token_size.
+const minFullTextTermLength = 3
+
+// userSearchCandidates is the number of users ranked in Go when a search cannot
+// use the full-text index.
+const userSearchCandidates = 500
+
+// UserSearchResult is a user matching a search, along with how well it matches.
+type UserSearchResult struct {
+	model.User
+	Score float64 `db:"score"`
+}
+
+// canUseFullText reports whether the full-text index holds every term.
+func canUseFullText(terms []string) bool {
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < minFullTextTermLength {
+			return false
+		}
+	}
+	return len(terms) > 0
+}
+
+// composeUserFullTextAgainst returns the boolean mode search of users holding a
+// token starting with every term. Terms hold letters and digits only, so they
+// cannot carry operators.
+func composeUserFullTextAgainst(terms []string) string {
+	required := make([]string, 0, len(terms))
+	for _, term := range terms {
+		required = append(required, "+"+term+"*")
+	}
+	return strings.Join(required, " ")
+}
+
+// composeUserSearchLikeWhere returns the conditions of users holding every term
+// somewhere in their email or fullname.
+func composeUserSearchLikeWhere(terms []string) (string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		conditions = append(conditions, "(`email_normalized` LIKE ? OR `fullname` LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+	return strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/guregu/null/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+)
+
+func TestUserCursor(t *testing.T) {
+	selectField := NewUserSelectFields()
+
+	t.Run("Round trip", func(t *testing.T) {
+		user := model.User{Id: uuid.New(), CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+		sort := UserSort{Field: selectField.CreatedAt(), Descending: true}
+		cursor := NewUserCursor(user, sort)
+
+		decoded, err := DecodeUserCursor(cursor.Encode())
+		assert.NoError(t, err)
+		assert.Equal(t, cursor, decoded)
+		assert.Equal(t, user.CreatedAt, userCursorArg(decoded))
+	})
+
+	t.Run("Null value", func(t *testing.T) {
+		cursor := NewUserCursor(model.User{Id: uuid.New()}, UserSort{Field: selectField.DeletedAt()})
+		decoded, err := DecodeUserCursor(cursor.Encode())
+		assert.NoError(t, err)
+		assert.False(t, decoded.Value.Valid)
+	})
+
+	t.Run("Invalid cursors", func(t *testing.T) {
+		for _, encoded := range []string{
+			"not base64!",
+			"bm90IGpzb24",
+			(UserCursor{Sort: UserSort{Field: selectField.Password()}, Value: null.StringFrom("x")}).Encode(),
+			(UserCursor{Sort: UserSort{Field: selectField.CreatedAt()}, Value: null.StringFrom("yesterday")}).Encode(),
+		} {
+			_, err := DecodeUserCursor(encoded)
+			assert.ErrorIs(t, err, ErrInvalidUserCursor, encoded)
+		}
+	})
+}
+
+func TestComposeUserListWhere(t *testing.T) {
+	selectField := NewUserSelectFields()
+
+	t.Run("Default excludes deleted users", func(t *testing.T) {
+		whereQry, args := composeUserListWhere(UserFilter{}, nil)
+		assert.Equal(t, " WHERE `deleted_at` IS NULL", whereQry)
+		assert.Empty(t, args)
+	})
+
+	t.Run("Including deleted users filters nothing", func(t *testing.T) {
+		whereQry, args := composeUserListWhere(UserFilter{Deleted: UserDeletedIncluded}, nil)
+		assert.Empty(t, whereQry)
+		assert.Empty(t, args)
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		whereQry, args := composeUserListWhere(UserFilter{
+			Statuses:    []model.UserStatus{model.StatusActive, model.StatusLocked},
+			EmailPrefix: "jane_100%",
+			CreatedFrom: from,
+			Deleted:     UserDeletedOnly,
+		}, nil)
+		assert.Equal(t, " WHERE `deleted_at` IS NOT NULL AND `status` IN (?,?) AND `email` LIKE ? AND `created_at` >= ?", whereQry)
+		assert.Equal(t, []interface{}{model.StatusActive, model.StatusLocked, `jane\_100\%%`, from}, args)
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		id := uuid.New()
+		whereQry, args := composeUserListWhere(UserFilter{Deleted: UserDeletedIncluded}, &UserCursor{
+			Sort:  UserSort{Field: selectField.Email()},
+			Value: null.StringFrom("jane@example.com"),
+			Id:    id,
+		})
+		assert.Equal(t, " WHERE (`email` > ? OR (`email` = ? AND `id` > ?))", whereQry)
+		assert.Equal(t, []interface{}{"jane@example.com", "jane@example.com", id}, args)
+	})
+
+	t.Run("Descending cursor keeps nulls last", func(t *testing.T) {
+		id := uuid.New()
+		condition, _ := composeUserCursorWhere(UserCursor{
+			Sort:  UserSort{Field: selectField.DeletedBy(), Descending: true},
+			Value: null.StringFrom("admin"),
+			Id:    id,
+		})
+		assert.Equal(t, "(`deleted_by` < ? OR (`deleted_by` = ? AND `id` < ?) OR `deleted_by` IS NULL)", condition)
+
+		condition, args := composeUserCursorWhere(UserCursor{
+			Sort: UserSort{Field: selectField.DeletedBy(), Descending: true},
+			Id:   id,
+		})
+		assert.Equal(t, "((`deleted_by` IS NULL AND `id` < ?))", condition)
+		assert.Equal(t, []interface{}{id}, args)
+	})
+
+	t.Run("Ascending cursor keeps nulls first", func(t *testing.T) {
+		condition, _ := composeUserCursorWhere(UserCursor{
+			Sort: UserSort{Field: selectField.DeletedBy()},
+			Id:   uuid.New(),
+		})
+		assert.Equal(t, "((`deleted_by` IS NULL AND `id` > ?) OR `deleted_by` IS NOT NULL)", condition)
+	})
+}
+
+func TestComposeUserOrderBy(t *testing.T) {
+	selectField := NewUserSelectFields()
+
+	orderBy, err := composeUserOrderBy(UserSort{Field: selectField.Fullname(), Descending: true})
+	assert.NoError(t, err)
+	assert.Equal(t, " ORDER BY `fullname` DESC, `id` DESC", orderBy)
+
+	_, err = composeUserOrderBy(UserSort{Field: selectField.Password()})
+	assert.Error(t, err)
+	_, err = composeUserOrderBy(UserSort{Field: UserField("id`; DROP TABLE `user")})
+	assert.Error(t, err)
+}
+
+func TestUserSearch(t *testing.T) {
+	t.Run("Full-text query", func(t *testing.T) {
+		assert.True(t, canUseFullText([]string{"jane", "doe"}))
+		assert.False(t, canUseFullText([]string{"jane", "li"}))
+		assert.False(t, canUseFullText(nil))
+		assert.Equal(t, "+jane* +doe*", composeUserFullTextAgainst([]string{"jane", "doe"}))
+	})
+
+	t.Run("Like query", func(t *testing.T) {
+		whereQry, args := composeUserSearchLikeWhere([]string{"li", "a_b"})
+		assert.Equal(t, "(`email_normalized` LIKE ? OR `fullname` LIKE ?) AND (`email_normalized` LIKE ? OR `fullname` LIKE ?)", whereQry)
+		assert.Equal(t, []interface{}{"%li%", "%li%", `%a\_b%`, `%a\_b%`}, args)
+	})
+
+	t.Run("Ranks in Go", func(t *testing.T) {
+		exact := &model.User{Id: uuid.New(), Email: "LI@example.com", Fullname: "Li Wei"}
+		prefix := &model.User{Id: uuid.New(), Email: "lisa@example.com", Fullname: "Lisa Ray"}
+		inside := &model.User{Id: uuid.New(), Email: "ali@example.com", Fullname: "Ali Khan"}
+
+		deleted := &model.User{Id: uuid.New(), Email: "li.old@example.com", Fullname: "Li Old", DeletedAt: null.TimeFrom(time.Now())}
+
+		results := SearchUserList(model.UserList{inside, prefix, exact, deleted}, []string{"li"}, 10)
+		if assert.Len(t, results, 2) {
+			assert.Equal(t, exact.Id, results[0].Id)
+			assert.Equal(t, prefix.Id, results[1].Id)
+		}
+		assert.Len(t, SearchUserList(model.UserList{inside, prefix, exact}, []string{"li"}, 1), 1)
+		assert.Empty(t, SearchUserList(model.UserList{inside, prefix, exact}, []string{"li", "khan"}, 10))
+	})
+}
@@ -3,16 +3,20 @@ package repository
 
 import (
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog/log"
 
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/IlhamRobyana/user/internal/domain/user/model"
 	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/search"
 )
 
 func (repo *UserRepositoryMySQL) CreateUser(ctx context.Context, user *model.User, fieldsInsert ...UserField) (err error) {
@@ -21,7 +25,8 @@ func (repo *UserRepositoryMySQL) CreateUser(ctx context.Context, user *model.Use
 		fieldsInsert = selectField.ForCreate()
 	}
 	primaryID := user.Id
-	exists, err := repo.IsExistUserByID(ctx, primaryID)
+	// soft deleted users keep their id
+	exists, err := repo.IsExistUserByIDIncludingDeleted(ctx, primaryID)
 	if err != nil {
 		log.Error().Err(err).Msg("[CreateUser] failed checking user whether already exists or not")
 		return err
@@ -41,7 +46,71 @@ func (repo *UserRepositoryMySQL) CreateUser(ctx context.Context, user *model.Use
 	return
 }
 
-func (repo *UserRepositoryMySQL) ResolveUserByID(ctx context.Context, primaryID uuid.UUID, selectFields ...UserField) (user model.User, err error) {
+// createUsersChunkSize is the number of users inserted by a single statement.
+const createUsersChunkSize = 500
+
+// CreateUsers inserts users in chunks within a single transaction, so either all
+// of them are created or none is.
+func (repo *UserRepositoryMySQL) CreateUsers(ctx context.Context, users []model.User) (err error) {
+	if len(users) == 0 {
+		return
+	}
+	fieldsInsert := NewUserSelectFields().ForCreate()
+	return repo.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		for start := 0; start < len(users); start += createUsersChunkSize {
+			end := start + createUsersChunkSize
+			if end > len(users) {
+				end = len(users)
+			}
+			fieldsStr, valueListStr, args := composeInsertFieldsAndParamsUser(users[start:end], fieldsInsert...)
+			commandQuery := fmt.Sprintf(userQueries.insertUser, fieldsStr, strings.Join(valueListStr, ","))
+			if _, err := tx.ExecContext(ctx, commandQuery, args...); err != nil {
+				log.Error().Err(err).Int("offset", start).Msg("[CreateUsers] failed exec create users query")
+				e <- failure.InternalError(err)
+				return
+			}
+		}
+		e <- nil
+	})
+}
+
+// ResolveUsedEmails returns which of the emails, lower cased, already belong to a
+// user. Soft deleted users keep their email.
+func (repo *UserRepositoryMySQL) ResolveUsedEmails(ctx context.Context, emails []string) (used []string, err error) {
+	for start := 0; start < len(emails); start += createUsersChunkSize {
+		end := start + createUsersChunkSize
+		if end > len(emails) {
+			end = len(emails)
+		}
+		normalized := make([]string, 0, end-start)
+		for _, email := range emails[start:end] {
+			normalized = append(normalized, strings.ToLower(email))
+		}
+		query, args, err := sqlx.In(fmt.Sprintf(userQueries.selectUser, "`email_normalized`")+" WHERE `email_normalized` IN (?)", normalized)
+		if err != nil {
+			return nil, failure.InternalError(err)
+		}
+		var chunk []string
+		if err = repo.DB.Read.SelectContext(ctx, &chunk, query, args...); err != nil {
+			log.Error().Err(err).Msg("[ResolveUsedEmails] failed select used emails")
+			return nil, failure.InternalError(err)
+		}
+		used = append(used, chunk...)
+	}
+	return
+}
+
+// ResolveUserByID returns a user that is not soft deleted.
+func (repo *UserRepositoryMySQL) ResolveUserByID(ctx context.Context, primaryID uuid.UUID, selectFields ...UserField) (model.User, error) {
+	return repo.resolveUserByID(ctx, primaryID, false, selectFields...)
+}
+
+// ResolveUserByIDIncludingDeleted returns a user whether it is soft deleted or not.
+func (repo *UserRepositoryMySQL) ResolveUserByIDIncludingDeleted(ctx context.Context, primaryID uuid.UUID, selectFields ...UserField) (model.User, error) {
+	return repo.resolveUserByID(ctx, primaryID, true, selectFields...)
+}
+
+func (repo *UserRepositoryMySQL) resolveUserByID(ctx context.Context, primaryID uuid.UUID, includeDeleted bool, selectFields ...UserField) (user model.User, err error) {
 	var (
 		defaultUserSelectFields = defaultUserSelectFields()
 	)
@@ -49,7 +118,7 @@ func (repo *UserRepositoryMySQL) ResolveUserByID(ctx context.Context, primaryID
 		defaultUserSelectFields = composeUserSelectFields(selectFields...)
 	}
 	whereQry, params := composeUserCompositePrimaryKeyWhere([]uuid.UUID{primaryID})
-	query := fmt.Sprintf(userQueries.selectUser+" WHERE "+whereQry, defaultUserSelectFields)
+	query := fmt.Sprintf(userQueries.selectUser+" WHERE "+composeUserNotDeletedWhere(whereQry, includeDeleted), defaultUserSelectFields)
 	err = repo.DB.Read.Get(&user, query, params...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -62,14 +131,24 @@ func (repo *UserRepositoryMySQL) ResolveUserByID(ctx context.Context, primaryID
 	return
 }
 
-func (repo *UserRepositoryMySQL) ResolveUserByEmail(ctx context.Context, email string, selectFields ...UserField) (user model.User, err error) {
+// ResolveUserByEmail returns a user that is not soft deleted.
+func (repo *UserRepositoryMySQL) ResolveUserByEmail(ctx context.Context, email string, selectFields ...UserField) (model.User, error) {
+	return repo.resolveUserByEmail(ctx, email, false, selectFields...)
+}
+
+// ResolveUserByEmailIncludingDeleted returns a user whether it is soft deleted or not.
+func (repo *UserRepositoryMySQL) ResolveUserByEmailIncludingDeleted(ctx context.Context, email string, selectFields ...UserField) (model.User, error) {
+	return repo.resolveUserByEmail(ctx, email, true, selectFields...)
+}
+
+func (repo *UserRepositoryMySQL) resolveUserByEmail(ctx context.Context, email string, includeDeleted bool, selectFields ...UserField) (user model.User, err error) {
 	var (
 		defaultUserSelectFields = defaultUserSelectFields()
 	)
 	if len(selectFields) > 0 {
 		defaultUserSelectFields = composeUserSelectFields(selectFields...)
 	}
-	query := fmt.Sprintf(userQueries.selectUser+" WHERE user.email = ?", defaultUserSelectFields)
+	query := fmt.Sprintf(userQueries.selectUser+" WHERE "+composeUserNotDeletedWhere("user.email = ?", includeDeleted), defaultUserSelectFields)
 	err = repo.DB.Read.Get(&user, query, email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -82,9 +161,19 @@ func (repo *UserRepositoryMySQL) ResolveUserByEmail(ctx context.Context, email s
 	return
 }
 
-func (repo *UserRepositoryMySQL) IsExistUserByID(ctx context.Context, primaryID uuid.UUID) (exists bool, err error) {
+// IsExistUserByID reports whether a user that is not soft deleted exists.
+func (repo *UserRepositoryMySQL) IsExistUserByID(ctx context.Context, primaryID uuid.UUID) (bool, error) {
+	return repo.isExistUserByID(ctx, primaryID, false)
+}
+
+// IsExistUserByIDIncludingDeleted reports whether a user exists, soft deleted or not.
+func (repo *UserRepositoryMySQL) IsExistUserByIDIncludingDeleted(ctx context.Context, primaryID uuid.UUID) (bool, error) {
+	return repo.isExistUserByID(ctx, primaryID, true)
+}
+
+func (repo *UserRepositoryMySQL) isExistUserByID(ctx context.Context, primaryID uuid.UUID, includeDeleted bool) (exists bool, err error) {
 	whereQuery, params := composeUserCompositePrimaryKeyWhere([]uuid.UUID{primaryID})
-	query := fmt.Sprintf("%s WHERE %s ", userQueries.selectCountUser, whereQuery)
+	query := fmt.Sprintf("%s WHERE %s ", userQueries.selectCountUser, composeUserNotDeletedWhere(whereQuery, includeDeleted))
 	err = repo.DB.Read.Get(&exists, query, params...)
 	if err != nil {
 		log.Error().Err(err).Msg("[IsExistUserByID] failed get count")
@@ -93,16 +182,265 @@ func (repo *UserRepositoryMySQL) IsExistUserByID(ctx context.Context, primaryID
 	return
 }
 
-func (repo *UserRepositoryMySQL) UpdateUserStatus(ctx context.Context, primaryID uuid.UUID, status string) (err error) {
-	query := fmt.Sprintf(userQueries.updateUser, "`status` = ? WHERE `id` = ?")
-	_, err = repo.exec(ctx, query, []interface{}{status, primaryID})
+// ResolveUsers returns a page of a listing of users, and the cursor of the next
+// page when there is one. Selected fields must hold the id and the sort field.
+func (repo *UserRepositoryMySQL) ResolveUsers(ctx context.Context, listQuery UserListQuery, selectFields ...UserField) (users model.UserList, next *UserCursor, err error) {
+	var (
+		defaultUserSelectFields = defaultUserSelectFields()
+	)
+	if len(selectFields) > 0 {
+		defaultUserSelectFields = composeUserSelectFields(selectFields...)
+	}
+	if listQuery.Cursor != nil && listQuery.Cursor.Sort != listQuery.Sort {
+		err = failure.BadRequestFromString("cursor does not match the sort of the listing")
+		return
+	}
+	orderBy, err := composeUserOrderBy(listQuery.Sort)
+	if err != nil {
+		return
+	}
+	whereQry, args := composeUserListWhere(listQuery.Filter, listQuery.Cursor)
+	// one more user than asked for tells whether there is a next page
+	query := fmt.Sprintf(userQueries.selectUser, defaultUserSelectFields) + whereQry + orderBy + " LIMIT ?"
+	args = append(args, listQuery.Limit+1)
+	if listQuery.Cursor == nil {
+		query += " OFFSET ?"
+		args = append(args, listQuery.Offset)
+	}
+	err = repo.DB.Read.SelectContext(ctx, &users, query, args...)
+	if err != nil {
+		log.Error().Err(err).Msg("[ResolveUsers] failed get users")
+		err = failure.InternalError(err)
+		return
+	}
+	if len(users) > listQuery.Limit {
+		users = users[:listQuery.Limit]
+		cursor := NewUserCursor(*users[len(users)-1], listQuery.Sort)
+		next = &cursor
+	}
+	return
+}
+
+// StreamUsers calls fn with every user of a listing in turn. Rows are read from
+// the database as fn consumes them, so memory use does not grow with the size of
+// the listing. Reading stops at the first error fn returns.
+func (repo *UserRepositoryMySQL) StreamUsers(ctx context.Context, filter UserFilter, sort UserSort, fn func(user model.User) error, selectFields ...UserField) (err error) {
+	var (
+		defaultUserSelectFields = defaultUserSelectFields()
+	)
+	if len(selectFields) > 0 {
+		defaultUserSelectFields = composeUserSelectFields(selectFields...)
+	}
+	orderBy, err := composeUserOrderBy(sort)
+	if err != nil {
+		return
+	}
+	whereQry, args := composeUserListWhere(filter, nil)
+	query := fmt.Sprintf(userQueries.selectUser, defaultUserSelectFields) + whereQry + orderBy
+	rows, err := repo.DB.Read.QueryxContext(ctx, query, args...)
+	if err != nil {
+		log.Error().Err(err).Msg("[StreamUsers] failed query users")
+		return failure.InternalError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user model.User
+		if err = rows.StructScan(&user); err != nil {
+			log.Error().Err(err).Msg("[StreamUsers] failed scan user")
+			return failure.InternalError(err)
+		}
+		if err = fn(user); err != nil {
+			return
+		}
+	}
+	if err = rows.Err(); err != nil {
+		log.Error().Err(err).Msg("[StreamUsers] failed read users")
+		return failure.InternalError(err)
+	}
+	return
+}
+
+// SearchUsers returns up to limit users that are not soft deleted, holding every
+// search term at the start of a token of their email or fullname, best matches
+// first. Terms must be lower-cased. The full-text index ranks the users, unless a
+// term is too short for it, then the users are ranked in Go.
+func (repo *UserRepositoryMySQL) SearchUsers(ctx context.Context, terms []string, limit int) (results []UserSearchResult, err error) {
+	fields := composeUserSelectFields(NewUserSelectFields().Public()...)
+	if canUseFullText(terms) {
+		match := "MATCH(`email_normalized`, `fullname`) AGAINST (? IN BOOLEAN MODE)"
+		against := composeUserFullTextAgainst(terms)
+		query := fmt.Sprintf(userQueries.selectUser, fields+", "+match+" AS `score`") +
+			" WHERE " + composeUserNotDeletedWhere(match, false) + " ORDER BY `score` DESC, `id` LIMIT ?"
+		err = repo.DB.Read.SelectContext(ctx, &results, query, against, against, limit)
+		if err != nil {
+			log.Error().Err(err).Msg("[SearchUsers] failed search users")
+			err = failure.InternalError(err)
+		}
+		return
+	}
+
+	whereQry, args := composeUserSearchLikeWhere(terms)
+	query := fmt.Sprintf(userQueries.selectUser, fields) + " WHERE " + composeUserNotDeletedWhere(whereQry, false) + " LIMIT ?"
+	var candidates model.UserList
+	err = repo.DB.Read.SelectContext(ctx, &candidates, query, append(args, userSearchCandidates)...)
+	if err != nil {
+		log.Error().Err(err).Msg("[SearchUsers] failed search users")
+		err = failure.InternalError(err)
+		return
+	}
+	return SearchUserList(candidates, terms, limit), nil
+}
+
+// SearchUserList is the pure-Go counterpart of SearchUsers, for repositories
+// without a full-text index such as in-memory ones. It ranks users against
+// lower-cased search terms and returns up to limit of the ones that are not soft
+// deleted and match every term, best matches first.
+func SearchUserList(users model.UserList, terms []string, limit int) []UserSearchResult {
+	results := make([]UserSearchResult, 0, len(users))
+	for _, user := range users {
+		if user.DeletedAt.Valid {
+			continue
+		}
+		if score := search.Score(terms, user.Email, user.Fullname); score > 0 {
+			results = append(results, UserSearchResult{User: *user, Score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Id.String() < results[j].Id.String()
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// CountUsers returns the number of users in a listing.
+func (repo *UserRepositoryMySQL) CountUsers(ctx context.Context, filter UserFilter) (count int64, err error) {
+	whereQry, args := composeUserListWhere(filter, nil)
+	err = repo.DB.Read.GetContext(ctx, &count, userQueries.selectCountUser+whereQry, args...)
 	if err != nil {
-		log.Error().Err(err).Msg("[UpdateUserStatus] failed update user status")
+		log.Error().Err(err).Msg("[CountUsers] failed get count")
 		err = failure.InternalError(err)
 	}
 	return
 }
 
+// ChangeUserStatus moves a user from one status to another on behalf of the actor
+// of the audit log entry, and records the entry along with it. It fails with a
+// conflict when the user is no longer in the from status. Moving a user to the
+// deleted status soft deletes it, moving it out of there restores it.
+func (repo *UserRepositoryMySQL) ChangeUserStatus(ctx context.Context, primaryID uuid.UUID, from model.UserStatus, to model.UserStatus, auditLog model.AuditLog) (err error) {
+	return repo.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		fields := "`status` = ?, `updated_at` = ?, `updated_by` = ?"
+		args := []interface{}{to, auditLog.CreatedAt, auditLog.Actor}
+		switch {
+		case to == model.StatusDeleted:
+			fields += ", `deleted_at` = ?, `deleted_by` = ?, `status_before_delete` = ?"
+			args = append(args, auditLog.CreatedAt, auditLog.Actor, from)
+		case from == model.StatusDeleted:
+			fields += ", `deleted_at` = NULL, `deleted_by` = NULL, `status_before_delete` = NULL"
+		}
+		query := fmt.Sprintf(userQueries.updateUser, fields+" WHERE `id` = ? AND `status` = ?")
+		result, err := tx.ExecContext(ctx, query, append(args, primaryID, from)...)
+		if err != nil {
+			log.Error().Err(err).Msg("[ChangeUserStatus] failed update user status")
+			e <- failure.InternalError(err)
+			return
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			e <- failure.InternalError(err)
+			return
+		}
+		if affected == 0 {
+			e <- failure.Conflict("change status", "user", fmt.Sprintf("user with id '%s' is no longer %s", primaryID, from))
+			return
+		}
+		if err = insertAuditLog(ctx, tx, auditLog); err != nil {
+			e <- err
+			return
+		}
+		e <- nil
+	})
+}
+
+// PurgeDeletedUsers permanently erases up to limit users soft deleted before
+// deletedBefore, along with their sessions and credentials, and returns how many
+// were erased. Each erasure is recorded in the audit log.
+func (repo *UserRepositoryMySQL) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, limit int) (purged int64, err error) {
+	err = repo.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		var userIDs []uuid.UUID
+		query := fmt.Sprintf(userQueries.selectUser, "`id`") + " WHERE `deleted_at` IS NOT NULL AND `deleted_at` < ? ORDER BY `deleted_at` LIMIT ? FOR UPDATE"
+		if err := tx.SelectContext(ctx, &userIDs, query, deletedBefore, limit); err != nil {
+			log.Error().Err(err).Msg("[PurgeDeletedUsers] failed select deleted users")
+			e <- failure.InternalError(err)
+			return
+		}
+		if len(userIDs) == 0 {
+			e <- nil
+			return
+		}
+
+		for _, deleteQuery := range userQueries.purgeUserData {
+			query, args, err := sqlx.In(deleteQuery+" WHERE `user_id` IN (?)", userIDs)
+			if err != nil {
+				e <- failure.InternalError(err)
+				return
+			}
+			if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+				log.Error().Err(err).Msg("[PurgeDeletedUsers] failed delete user data")
+				e <- failure.InternalError(err)
+				return
+			}
+		}
+		query, args, err := sqlx.In(userQueries.deleteUser+" WHERE `id` IN (?) AND `deleted_at` IS NOT NULL", userIDs)
+		if err != nil {
+			e <- failure.InternalError(err)
+			return
+		}
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			log.Error().Err(err).Msg("[PurgeDeletedUsers] failed delete users")
+			e <- failure.InternalError(err)
+			return
+		}
+		if purged, err = result.RowsAffected(); err != nil {
+			e <- failure.InternalError(err)
+			return
+		}
+		for _, userID := range userIDs {
+			auditLog := model.NewAuditLog(userID, model.AuditActionPurge, model.ReasonRetentionExpired, model.ActorSystem)
+			if err = insertAuditLog(ctx, tx, auditLog); err != nil {
+				e <- err
+				return
+			}
+		}
+		e <- nil
+	})
+	if err != nil {
+		purged = 0
+	}
+	return
+}
+
+func (repo *UserRepositoryMySQL) UpdateUser(ctx context.Context, primaryID uuid.UUID, updateFields UserUpdateFieldList) (err error) {
+	if len(updateFields) == 0 {
+		return
+	}
+	updatedFieldsQuery, args := composeUpdateFieldsUserCommand(updateFields)
+	whereQry, params := composeUserCompositePrimaryKeyWhere([]uuid.UUID{primaryID})
+	query := fmt.Sprintf(userQueries.updateUser, strings.Join(updatedFieldsQuery, ",")) + "WHERE " + whereQry
+	_, err = repo.exec(ctx, query, append(args, params...))
+	if err != nil {
+		log.Error().Err(err).Msg("[UpdateUser] failed update user")
+	}
+	return
+}
+
 type UserFieldParameter struct {
 	param string
 	args  []interface{}
@@ -160,6 +498,10 @@ func (ss UserSelectFields) Status() UserField {
 	return UserField("status")
 }
 
+func (ss UserSelectFields) Role() UserField {
+	return UserField("role")
+}
+
 func (ss UserSelectFields) CreatedAt() UserField {
 	return UserField("created_at")
 }
@@ -184,6 +526,10 @@ func (ss UserSelectFields) DeletedBy() UserField {
 	return UserField("deleted_by")
 }
 
+func (ss UserSelectFields) StatusBeforeDelete() UserField {
+	return UserField("status_before_delete")
+}
+
 func (ss UserSelectFields) All() UserFieldList {
 	return []UserField{
 		ss.Id(),
@@ -191,12 +537,14 @@ func (ss UserSelectFields) All() UserFieldList {
 		ss.Password(),
 		ss.Fullname(),
 		ss.Status(),
+		ss.Role(),
 		ss.CreatedAt(),
 		ss.UpdatedAt(),
 		ss.DeletedAt(),
 		ss.CreatedBy(),
 		ss.UpdatedBy(),
 		ss.DeletedBy(),
+		ss.StatusBeforeDelete(),
 	}
 }
 
@@ -207,11 +555,30 @@ func (ss UserSelectFields) ForCreate() UserFieldList {
 		ss.Password(),
 		ss.Fullname(),
 		ss.Status(),
+		ss.Role(),
 		ss.CreatedBy(),
 		ss.UpdatedBy(),
 	}
 }
 
+// Public lists every field but the password, the fields that may leave the
+// service.
+func (ss UserSelectFields) Public() UserFieldList {
+	return []UserField{
+		ss.Id(),
+		ss.Email(),
+		ss.Fullname(),
+		ss.Status(),
+		ss.Role(),
+		ss.CreatedAt(),
+		ss.UpdatedAt(),
+		ss.DeletedAt(),
+		ss.CreatedBy(),
+		ss.UpdatedBy(),
+		ss.DeletedBy(),
+	}
+}
+
 func NewUserSelectFields() UserSelectFields {
 	return UserSelectFields{}
 }
@@ -292,6 +659,8 @@ func composeInsertFieldsAndParamsUser(userList []model.User, fieldsInsert ...Use
 				args = append(args, user.Fullname)
 			case selectField.Status():
 				args = append(args, user.Status)
+			case selectField.Role():
+				args = append(args, user.Role)
 			case selectField.CreatedAt():
 				args = append(args, user.CreatedAt)
 			case selectField.UpdatedAt():
@@ -304,6 +673,8 @@ func composeInsertFieldsAndParamsUser(userList []model.User, fieldsInsert ...Use
 				args = append(args, user.UpdatedBy)
 			case selectField.DeletedBy():
 				args = append(args, user.DeletedBy)
+			case selectField.StatusBeforeDelete():
+				args = append(args, user.StatusBeforeDelete)
 
 			}
 		}
@@ -312,6 +683,15 @@ func composeInsertFieldsAndParamsUser(userList []model.User, fieldsInsert ...Use
 	return
 }
 
+// composeUserNotDeletedWhere narrows a where clause down to users that are not
+// soft deleted, unless they are explicitly included.
+func composeUserNotDeletedWhere(whereQry string, includeDeleted bool) string {
+	if includeDeleted {
+		return whereQry
+	}
+	return fmt.Sprintf("(%s) AND `user`.`deleted_at` IS NULL", whereQry)
+}
+
 func composeUserCompositePrimaryKeyWhere(primaryIDs []uuid.UUID) (whereQry string, params []interface{}) {
 	var primaryKeyQry []string
 	for _, primaryID := range primaryIDs {
@@ -334,19 +714,37 @@ var (
 		deleteUser      string
 		updateUser      string
 		insertUser      string
+		purgeUserData   []string
 	}{
 		selectUser:      "SELECT %s FROM `user`",
 		selectCountUser: "SELECT COUNT(`id`) FROM `user`",
 		deleteUser:      "DELETE FROM `user`",
 		updateUser:      "UPDATE `user` SET %s ",
 		insertUser:      "INSERT INTO `user` %s VALUES %s",
+		purgeUserData: []string{
+			"DELETE FROM `user_session`",
+			"DELETE FROM `user_mfa`",
+			"DELETE FROM `user_mfa_recovery_code`",
+			"DELETE FROM `user_webauthn_credential`",
+		},
 	}
 )
 
 type UserRepository interface {
 	ResolveUserByID(ctx context.Context, userID uuid.UUID, selectFields ...UserField) (model.User, error)
+	ResolveUserByIDIncludingDeleted(ctx context.Context, userID uuid.UUID, selectFields ...UserField) (model.User, error)
 	CreateUser(ctx context.Context, user *model.User, fieldsInsert ...UserField) error
+	CreateUsers(ctx context.Context, users []model.User) error
+	ResolveUsedEmails(ctx context.Context, emails []string) ([]string, error)
 	IsExistUserByID(ctx context.Context, userID uuid.UUID) (bool, error)
+	IsExistUserByIDIncludingDeleted(ctx context.Context, userID uuid.UUID) (bool, error)
 	ResolveUserByEmail(ctx context.Context, email string, selectFields ...UserField) (model.User, error)
-	UpdateUserStatus(ctx context.Context, primaryID uuid.UUID, status string) (err error)
+	ResolveUserByEmailIncludingDeleted(ctx context.Context, email string, selectFields ...UserField) (model.User, error)
+	ResolveUsers(ctx context.Context, listQuery UserListQuery, selectFields ...UserField) (model.UserList, *UserCursor, error)
+	CountUsers(ctx context.Context, filter UserFilter) (int64, error)
+	StreamUsers(ctx context.Context, filter UserFilter, sort UserSort, fn func(user model.User) error, selectFields ...UserField) error
+	SearchUsers(ctx context.Context, terms []string, limit int) ([]UserSearchResult, error)
+	ChangeUserStatus(ctx context.Context, primaryID uuid.UUID, from model.UserStatus, to model.UserStatus, auditLog model.AuditLog) (err error)
+	UpdateUser(ctx context.Context, primaryID uuid.UUID, updateFields UserUpdateFieldList) (err error)
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, limit int) (purged int64, err error)
 }
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/shared/failure"
+)
+
+func (repo *WebAuthnRepositoryMySQL) CreateWebAuthnCredential(ctx context.Context, credential *model.WebAuthnCredential) (err error) {
+	_, err = exec(ctx, repo.DB, webAuthnQueries.insertCredential, []interface{}{
+		credential.Id,
+		credential.UserId,
+		credential.CredentialId,
+		credential.PublicKey,
+		credential.AttestationType,
+		credential.AAGUID,
+		credential.SignCount,
+		credential.Transports,
+		credential.Name,
+		credential.CreatedAt,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("[CreateWebAuthnCredential] failed exec create credential query")
+	}
+	return
+}
+
+func (repo *WebAuthnRepositoryMySQL) ResolveWebAuthnCredentialsByUserID(ctx context.Context, userID uuid.UUID) (credentials model.WebAuthnCredentialList, err error) {
+	query := webAuthnQueries.selectCredential + " WHERE `user_id` = ? ORDER BY `created_at`"
+	err = repo.DB.Read.SelectContext(ctx, &credentials, query, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("[ResolveWebAuthnCredentialsByUserID] failed get credentials")
+		err = failure.InternalError(err)
+	}
+	return
+}
+
+// UpdateWebAuthnCredentialUsage stores the signature counter of a credential as of
+// a login at lastUsedAt.
+func (repo *WebAuthnRepositoryMySQL) UpdateWebAuthnCredentialUsage(ctx context.Context, id uuid.UUID, signCount uint32, lastUsedAt time.Time) (err error) {
+	query := fmt.Sprintf(webAuthnQueries.updateCredential, "`sign_count` = ?, `last_used_at` = ? WHERE `id` = ?")
+	_, err = exec(ctx, repo.DB, query, []interface{}{signCount, lastUsedAt, id})
+	if err != nil {
+		log.Error().Err(err).Msg("[UpdateWebAuthnCredentialUsage] failed update credential")
+	}
+	return
+}
+
+func (repo *WebAuthnRepositoryMySQL) DeleteWebAuthnCredential(ctx context.Context, userID uuid.UUID, id uuid.UUID) (err error) {
+	result, err := exec(ctx, repo.DB, webAuthnQueries.deleteCredential+" WHERE `id` = ? AND `user_id` = ?", []interface{}{id, userID})
+	if err != nil {
+		log.Error().Err(err).Msg("[DeleteWebAuthnCredential] failed delete credential")
+		return
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Error().Err(err).Msg("[DeleteWebAuthnCredential] failed get affected rows")
+		return failure.InternalError(err)
+	}
+	if affected == 0 {
+		return failure.NotFound(fmt.Sprintf("credential with id '%s' not found", id))
+	}
+	return nil
+}
+
+var (
+	webAuthnQueries = struct {
+		selectCredential string
+		insertCredential string
+		updateCredential string
+		deleteCredential string
+	}{
+		selectCredential: "SELECT `id`,`user_id`,`credential_id`,`public_key`,`attestation_type`,`aaguid`,`sign_count`,`transports`,`name`,`created_at`,`last_used_at` FROM `user_webauthn_credential`",
+		insertCredential: "INSERT INTO `user_webauthn_credential` (`id`,`user_id`,`credential_id`,`public_key`,`attestation_type`,`aaguid`,`sign_count`,`transports`,`name`,`created_at`) VALUES (?,?,?,?,?,?,?,?,?,?)",
+		updateCredential: "UPDATE `user_webauthn_credential` SET %s ",
+		deleteCredential: "DELETE FROM `user_webauthn_credential`",
+	}
+)
+
+type WebAuthnRepository interface {
+	CreateWebAuthnCredential(ctx context.Context, credential *model.WebAuthnCredential) error
+	ResolveWebAuthnCredentialsByUserID(ctx context.Context, userID uuid.UUID) (model.WebAuthnCredentialList, error)
+	UpdateWebAuthnCredentialUsage(ctx context.Context, id uuid.UUID, signCount uint32, lastUsedAt time.Time) error
+	DeleteWebAuthnCredential(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+}
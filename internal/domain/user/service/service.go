@@ -5,6 +5,9 @@ import (
 	"github.com/IlhamRobyana/user/configs"
 	"github.com/IlhamRobyana/user/infras"
 	"github.com/IlhamRobyana/user/internal/domain/user/repository"
+	"github.com/IlhamRobyana/user/shared"
+	"github.com/IlhamRobyana/user/shared/notifier"
+	"github.com/IlhamRobyana/user/shared/scheduler"
 	"github.com/go-redis/redis/v8"
 )
 
@@ -15,16 +18,30 @@ type Service interface {
 
 // UserServiceImpl is the service implementation for User entities.
 type UserServiceImpl struct {
-	UserRepository repository.UserRepository
-	cfg            *configs.Config
-	cache          *redis.Client
+	UserRepository     repository.UserRepository
+	SessionRepository  repository.SessionRepository
+	MFARepository      repository.MFARepository
+	WebAuthnRepository repository.WebAuthnRepository
+	Notifications      *notifier.Dispatcher
+	events             shared.PubSub
+	jobs               *scheduler.Scheduler
+	cfg                *configs.Config
+	cache              *redis.Client
 }
 
 // ProvideUserService is the provider for this service.
-func ProvideUserService(repo repository.UserRepository, cfg *configs.Config) *UserServiceImpl {
+func ProvideUserService(repo repository.UserRepository, sessionRepo repository.SessionRepository, mfaRepo repository.MFARepository, webAuthnRepo repository.WebAuthnRepository, notifications *notifier.Dispatcher, events shared.PubSub, jobs *scheduler.Scheduler, cfg *configs.Config) *UserServiceImpl {
 	s := new(UserServiceImpl)
 	s.UserRepository = repo
+	s.SessionRepository = sessionRepo
+	s.MFARepository = mfaRepo
+	s.WebAuthnRepository = webAuthnRepo
+	s.Notifications = notifications
+	s.events = events
+	s.jobs = jobs
 	s.cfg = cfg
 	s.cache = infras.RedisNewClient(*cfg)
+	s.subscribeEvents()
+	s.registerJobs()
 	return s
 }
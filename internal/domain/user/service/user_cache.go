@@ -1,10 +1,16 @@
 package service
 
 import (
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
 	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
 )
 
 func (s *UserServiceImpl) GetLoginAttemptKey(email string) string {
@@ -30,6 +36,10 @@ func (s *UserServiceImpl) SetSuspendAmount(ctx context.Context, email string, at
 	return s.cache.Set(ctx, s.GetSuspendAmountKey(email), attempt, ttl).Err()
 }
 
+func (s *UserServiceImpl) DeleteSuspendAmount(ctx context.Context, email string) error {
+	return s.cache.Del(ctx, s.GetSuspendAmountKey(email)).Err()
+}
+
 func (s *UserServiceImpl) GetLoginAttempt(ctx context.Context, email string) (int, error) {
 	attemptStr, err := s.cache.Get(ctx, s.GetLoginAttemptKey(email)).Result()
 	if err != nil {
@@ -44,3 +54,274 @@ func (s *UserServiceImpl) GetLoginAttempt(ctx context.Context, email string) (in
 func (s *UserServiceImpl) SetLoginAttempt(ctx context.Context, email string, attempt int, ttl time.Duration) error {
 	return s.cache.Set(ctx, s.GetLoginAttemptKey(email), attempt, ttl).Err()
 }
+
+func (s *UserServiceImpl) DeleteLoginAttempt(ctx context.Context, email string) error {
+	return s.cache.Del(ctx, s.GetLoginAttemptKey(email)).Err()
+}
+
+func (s *UserServiceImpl) GetMagicLinkAttemptKey(email string) string {
+	return fmt.Sprintf("user:magic:attempt:%s", email)
+}
+
+func (s *UserServiceImpl) GetUsedMagicLinkKey(tokenID string) string {
+	return fmt.Sprintf("user:magic:used:%s", tokenID)
+}
+
+func (s *UserServiceImpl) GetMagicLinkAttempt(ctx context.Context, email string) (int, error) {
+	attemptStr, err := s.cache.Get(ctx, s.GetMagicLinkAttemptKey(email)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if attemptStr == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(attemptStr)
+}
+
+func (s *UserServiceImpl) SetMagicLinkAttempt(ctx context.Context, email string, attempt int, ttl time.Duration) error {
+	return s.cache.Set(ctx, s.GetMagicLinkAttemptKey(email), attempt, ttl).Err()
+}
+
+func (s *UserServiceImpl) DeleteMagicLinkAttempt(ctx context.Context, email string) error {
+	return s.cache.Del(ctx, s.GetMagicLinkAttemptKey(email)).Err()
+}
+
+// SetMagicLinkUsed marks a magic link as used and reports whether this call was
+// the first one to do so.
+func (s *UserServiceImpl) SetMagicLinkUsed(ctx context.Context, tokenID string, ttl time.Duration) (bool, error) {
+	return s.cache.SetNX(ctx, s.GetUsedMagicLinkKey(tokenID), time.Now().Unix(), ttl).Result()
+}
+
+func (s *UserServiceImpl) GetRefreshTokenKey(tokenHash string) string {
+	return fmt.Sprintf("user:refresh:token:%s", tokenHash)
+}
+
+func (s *UserServiceImpl) GetRefreshTokenRotatedKey(tokenHash string) string {
+	return fmt.Sprintf("user:refresh:rotated:%s", tokenHash)
+}
+
+func (s *UserServiceImpl) GetRefreshTokenFamilyKey(familyID uuid.UUID) string {
+	return fmt.Sprintf("user:refresh:family:%s", familyID)
+}
+
+func (s *UserServiceImpl) GetUserRefreshTokenFamiliesKey(userID uuid.UUID) string {
+	return fmt.Sprintf("user:refresh:user:%s", userID)
+}
+
+func (s *UserServiceImpl) GetRevokedSessionKey(sessionID uuid.UUID) string {
+	return fmt.Sprintf("user:session:revoked:%s", sessionID)
+}
+
+func (s *UserServiceImpl) GetPasswordResetTokenKey(tokenHash string) string {
+	return fmt.Sprintf("user:password:reset:%s", tokenHash)
+}
+
+func (s *UserServiceImpl) GetUserPasswordResetTokenKey(userID uuid.UUID) string {
+	return fmt.Sprintf("user:password:reset:user:%s", userID)
+}
+
+func (s *UserServiceImpl) GetVerificationResendKey(email string) string {
+	return fmt.Sprintf("user:verification:resend:%s", email)
+}
+
+func (s *UserServiceImpl) GetMFAChallengeKey(tokenHash string) string {
+	return fmt.Sprintf("user:mfa:challenge:%s", tokenHash)
+}
+
+func (s *UserServiceImpl) GetMFAChallengeAttemptKey(tokenHash string) string {
+	return fmt.Sprintf("user:mfa:challenge:attempt:%s", tokenHash)
+}
+
+func (s *UserServiceImpl) GetUsedTOTPCodeKey(userID uuid.UUID, code string) string {
+	return fmt.Sprintf("user:mfa:totp:used:%s:%s", userID, code)
+}
+
+func (s *UserServiceImpl) GetWebAuthnCeremonyKey(ceremonyID string) string {
+	return fmt.Sprintf("user:webauthn:ceremony:%s", ceremonyID)
+}
+
+func (s *UserServiceImpl) GetRefreshToken(ctx context.Context, tokenHash string) (model.RefreshToken, error) {
+	fields, err := s.cache.HGetAll(ctx, s.GetRefreshTokenKey(tokenHash)).Result()
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+	if len(fields) == 0 {
+		return model.RefreshToken{}, redis.Nil
+	}
+	userID, err := uuid.Parse(fields["userId"])
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+	familyID, err := uuid.Parse(fields["familyId"])
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+	return model.RefreshToken{UserId: userID, FamilyId: familyID}, nil
+}
+
+func (s *UserServiceImpl) SetRefreshToken(ctx context.Context, tokenHash string, refreshToken model.RefreshToken, ttl time.Duration) error {
+	tokenKey := s.GetRefreshTokenKey(tokenHash)
+	familyKey := s.GetRefreshTokenFamilyKey(refreshToken.FamilyId)
+	userKey := s.GetUserRefreshTokenFamiliesKey(refreshToken.UserId)
+	_, err := s.cache.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, tokenKey, "userId", refreshToken.UserId.String(), "familyId", refreshToken.FamilyId.String())
+		pipe.Expire(ctx, tokenKey, ttl)
+		pipe.SAdd(ctx, familyKey, tokenHash)
+		pipe.Expire(ctx, familyKey, ttl)
+		pipe.SAdd(ctx, userKey, refreshToken.FamilyId.String())
+		pipe.Expire(ctx, userKey, ttl)
+		return nil
+	})
+	return err
+}
+
+// SetRefreshTokenRotated marks the token as used and reports whether this call was
+// the first one to do so.
+func (s *UserServiceImpl) SetRefreshTokenRotated(ctx context.Context, tokenHash string, ttl time.Duration) (bool, error) {
+	return s.cache.SetNX(ctx, s.GetRefreshTokenRotatedKey(tokenHash), time.Now().Unix(), ttl).Result()
+}
+
+func (s *UserServiceImpl) DeleteRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	familyKey := s.GetRefreshTokenFamilyKey(familyID)
+	tokenHashes, err := s.cache.SMembers(ctx, familyKey).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{familyKey}
+	for _, tokenHash := range tokenHashes {
+		keys = append(keys, s.GetRefreshTokenKey(tokenHash))
+	}
+	return s.cache.Del(ctx, keys...).Err()
+}
+
+func (s *UserServiceImpl) DeleteUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	userKey := s.GetUserRefreshTokenFamiliesKey(userID)
+	familyIDs, err := s.cache.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+	for _, familyIDStr := range familyIDs {
+		familyID, err := uuid.Parse(familyIDStr)
+		if err != nil {
+			continue
+		}
+		if err = s.DeleteRefreshTokenFamily(ctx, familyID); err != nil {
+			return err
+		}
+	}
+	return s.cache.Del(ctx, userKey).Err()
+}
+
+func (s *UserServiceImpl) SetRevokedSession(ctx context.Context, sessionID uuid.UUID, ttl time.Duration) error {
+	return s.cache.Set(ctx, s.GetRevokedSessionKey(sessionID), 1, ttl).Err()
+}
+
+func (s *UserServiceImpl) IsRevokedSession(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	count, err := s.cache.Exists(ctx, s.GetRevokedSessionKey(sessionID)).Result()
+	return count > 0, err
+}
+
+// SetPasswordResetToken stores the token for the user, replacing the previous
+// token of the user if there is one.
+func (s *UserServiceImpl) SetPasswordResetToken(ctx context.Context, tokenHash string, userID uuid.UUID, ttl time.Duration) error {
+	userKey := s.GetUserPasswordResetTokenKey(userID)
+	previousHash, err := s.cache.Get(ctx, userKey).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	_, err = s.cache.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previousHash != "" {
+			pipe.Del(ctx, s.GetPasswordResetTokenKey(previousHash))
+		}
+		pipe.Set(ctx, s.GetPasswordResetTokenKey(tokenHash), userID.String(), ttl)
+		pipe.Set(ctx, userKey, tokenHash, ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *UserServiceImpl) GetPasswordResetToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	userIDStr, err := s.cache.Get(ctx, s.GetPasswordResetTokenKey(tokenHash)).Result()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(userIDStr)
+}
+
+// ConsumePasswordResetToken deletes the token and returns the user it was issued
+// for. Only the first call for a token succeeds.
+func (s *UserServiceImpl) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	userIDStr, err := s.cache.GetDel(ctx, s.GetPasswordResetTokenKey(tokenHash)).Result()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return userID, s.cache.Del(ctx, s.GetUserPasswordResetTokenKey(userID)).Err()
+}
+
+// SetVerificationResent records that a verification email was requested for email
+// and reports whether none was requested within the last interval.
+func (s *UserServiceImpl) SetVerificationResent(ctx context.Context, email string, interval time.Duration) (bool, error) {
+	return s.cache.SetNX(ctx, s.GetVerificationResendKey(email), time.Now().Unix(), interval).Result()
+}
+
+func (s *UserServiceImpl) SetMFAChallenge(ctx context.Context, tokenHash string, userID uuid.UUID, ttl time.Duration) error {
+	return s.cache.Set(ctx, s.GetMFAChallengeKey(tokenHash), userID.String(), ttl).Err()
+}
+
+func (s *UserServiceImpl) GetMFAChallenge(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	userIDStr, err := s.cache.Get(ctx, s.GetMFAChallengeKey(tokenHash)).Result()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(userIDStr)
+}
+
+// IncrMFAChallengeAttempt counts an attempt to complete the challenge and returns
+// the number of attempts so far.
+func (s *UserServiceImpl) IncrMFAChallengeAttempt(ctx context.Context, tokenHash string, ttl time.Duration) (int64, error) {
+	attemptKey := s.GetMFAChallengeAttemptKey(tokenHash)
+	var incr *redis.IntCmd
+	_, err := s.cache.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, attemptKey)
+		pipe.Expire(ctx, attemptKey, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (s *UserServiceImpl) DeleteMFAChallenge(ctx context.Context, tokenHash string) error {
+	return s.cache.Del(ctx, s.GetMFAChallengeKey(tokenHash), s.GetMFAChallengeAttemptKey(tokenHash)).Err()
+}
+
+// SetTOTPCodeUsed marks a TOTP code of the user as used and reports whether this
+// call was the first one to do so.
+func (s *UserServiceImpl) SetTOTPCodeUsed(ctx context.Context, userID uuid.UUID, code string, ttl time.Duration) (bool, error) {
+	return s.cache.SetNX(ctx, s.GetUsedTOTPCodeKey(userID, code), time.Now().Unix(), ttl).Result()
+}
+
+func (s *UserServiceImpl) SetWebAuthnCeremony(ctx context.Context, ceremonyID string, ceremony webAuthnCeremony, ttl time.Duration) error {
+	value, err := json.Marshal(ceremony)
+	if err != nil {
+		return err
+	}
+	return s.cache.Set(ctx, s.GetWebAuthnCeremonyKey(ceremonyID), value, ttl).Err()
+}
+
+// ConsumeWebAuthnCeremony returns the state of a ceremony and deletes it, so every
+// ceremony can be finished once.
+func (s *UserServiceImpl) ConsumeWebAuthnCeremony(ctx context.Context, ceremonyID string) (webAuthnCeremony, error) {
+	value, err := s.cache.GetDel(ctx, s.GetWebAuthnCeremonyKey(ceremonyID)).Bytes()
+	if err != nil {
+		return webAuthnCeremony{}, err
+	}
+	var ceremony webAuthnCeremony
+	err = json.Unmarshal(value, &ceremony)
+	return ceremony, err
+}
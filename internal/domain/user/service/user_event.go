@@ -0,0 +1,88 @@
+package service
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"encoding/json"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/shared/notifier"
+)
+
+// unlockTimeLayout is how unlock times are shown in notifications.
+const unlockTimeLayout = "2 Jan 2006 15:04 MST"
+
+// subscribeEvents registers the subscribers of the user domain events.
+func (s *UserServiceImpl) subscribeEvents() {
+	s.events.SubscriberRegistry(model.TopicUserLockedOut, s.onUserLockedOut)
+	s.events.SubscriberRegistry(model.TopicUserStatusChanged, s.onUserStatusChanged)
+}
+
+// publishEvent publishes a user domain event. Events are best effort, failing to
+// encode or queue one is only logged.
+func (s *UserServiceImpl) publishEvent(topic string, event interface{}) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Error().Err(err).Str("topic", topic).Msg("[publishEvent] failed encode event")
+		return
+	}
+	// the request publishing the event must not wait on busy subscribers
+	if !s.events.TryPublish(topic, payload) {
+		log.Warn().Str("topic", topic).Msg("[publishEvent] dropped event, queue is full")
+	}
+}
+
+func (s *UserServiceImpl) onUserLockedOut(payload []byte) error {
+	var event model.UserLockedOutEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		log.Error().Err(err).Msg("[onUserLockedOut] failed decode event")
+		return nil
+	}
+	err := s.Notifications.Send(context.Background(), notifier.Notification{
+		Template: notifier.TemplateAccountLocked,
+		To:       event.Email,
+		Data: map[string]interface{}{
+			"Name":         event.Fullname,
+			"Reason":       event.Reason,
+			"UnlockAt":     event.LockedUntil.UTC().Format(unlockTimeLayout),
+			"SupportEmail": s.cfg.App.SupportEmail,
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("[onUserLockedOut] failed send lockout notification")
+	}
+	return nil
+}
+
+func (s *UserServiceImpl) onUserStatusChanged(payload []byte) error {
+	var event model.UserStatusChangedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		log.Error().Err(err).Msg("[onUserStatusChanged] failed decode event")
+		return nil
+	}
+	// a restored user is back where it was when deleted, it was told about that
+	// status already
+	if event.From == model.StatusDeleted {
+		return nil
+	}
+	switch event.To {
+	case model.StatusLocked, model.StatusSuspended, model.StatusDeactivated:
+	default:
+		return nil
+	}
+	err := s.Notifications.Send(context.Background(), notifier.Notification{
+		Template: notifier.TemplateAccountDeactivated,
+		To:       event.Email,
+		Data: map[string]interface{}{
+			"Name":         event.Fullname,
+			"Status":       string(event.To),
+			"Reason":       event.Reason,
+			"SupportEmail": s.cfg.App.SupportEmail,
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("[onUserStatusChanged] failed send status notification")
+	}
+	return nil
+}
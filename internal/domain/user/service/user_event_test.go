@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/notifier"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// assertNoMessage checks the notifier does not send a message to the address.
+func assertNoMessage(t *testing.T, messages *notifier.MemoryNotifier, to string) {
+	assert.Never(t, func() bool {
+		_, ok := messages.Last(to)
+		return ok
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestLockoutNotification(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Locked out user is told", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
+		assert.NoError(t, s.SetLoginAttempt(ctx, user.Email, s.cfg.Internal.MaxLoginAttempt-1, time.Minute))
+
+		_, err := s.LoginUser(ctx, dto.UserLoginRequest{Email: user.Email, Password: "WrongPassword1"})
+		assert.Error(t, err)
+
+		message := waitForMessage(t, s.messages, user.Email)
+		assert.True(t, strings.HasPrefix(message.Template, notifier.TemplateAccountLocked+"/"), message.Template)
+		s.userRepo.AssertNotCalled(t, "ChangeUserStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown email is not told", func(t *testing.T) {
+		s := newTestUserService(t)
+		s.userRepo.On("ResolveUserByEmail", ctx, "jane@example.com", mock.Anything).Return(model.User{}, failure.NotFound("user"))
+		assert.NoError(t, s.SetLoginAttempt(ctx, "jane@example.com", s.cfg.Internal.MaxLoginAttempt-1, time.Minute))
+
+		_, err := s.LoginUser(ctx, dto.UserLoginRequest{Email: "jane@example.com", Password: "WrongPassword1"})
+		assert.Error(t, err)
+		assertNoMessage(t, s.messages, "jane@example.com")
+	})
+}
+
+func TestStatusChangeNotification(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Deactivated user is told", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		s.userRepo.On("ChangeUserStatus", ctx, user.Id, model.StatusActive, model.StatusDeactivated, mock.AnythingOfType("model.AuditLog")).Return(nil)
+		s.expectSessions(ctx, user.Id)
+
+		assert.NoError(t, s.transitionUserStatus(ctx, user, model.StatusDeactivated, model.StaffActor(uuid.New()), "left the company"))
+
+		message := waitForMessage(t, s.messages, user.Email)
+		assert.True(t, strings.HasPrefix(message.Template, notifier.TemplateAccountDeactivated+"/"), message.Template)
+	})
+
+	t.Run("Reactivated user is not told", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		user.Status = model.StatusSuspended
+		s.userRepo.On("ChangeUserStatus", ctx, user.Id, model.StatusSuspended, model.StatusActive, mock.AnythingOfType("model.AuditLog")).Return(nil)
+
+		assert.NoError(t, s.transitionUserStatus(ctx, user, model.StatusActive, model.StaffActor(uuid.New()), "appeal accepted"))
+		assertNoMessage(t, s.messages, user.Email)
+	})
+}
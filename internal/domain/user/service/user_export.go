@@ -0,0 +1,28 @@
+package service
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"context"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+)
+
+// ExportUsers calls fn with every user of a listing in turn, holding the fields
+// of the export only. Users are streamed from the repository rather than loaded
+// at once, and the password is never read.
+func (s *UserServiceImpl) ExportUsers(ctx context.Context, exportRequest dto.UserExportRequest, fn func(userResponse dto.UserResponse) error) error {
+	err := s.UserRepository.StreamUsers(ctx,
+		userListFilter(exportRequest.UserListRequest),
+		userListSort(exportRequest.UserListRequest),
+		func(user model.User) error {
+			return fn(dto.NewUserResponse(user))
+		},
+		userSelectFields(exportRequest.Columns())...,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("[ExportUsers] failed stream users")
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/internal/domain/user/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExportUsers(t *testing.T) {
+	ctx := context.Background()
+	selectField := repository.NewUserSelectFields()
+	users := []model.User{
+		{Id: uuid.New(), Email: "jane@example.com", Fullname: "Jane Doe", Status: model.StatusActive},
+		{Id: uuid.New(), Email: "john@example.com", Fullname: "John Doe", Status: model.StatusSuspended},
+	}
+	// streamUsers makes the mock repository stream the users to the callback
+	streamUsers := func(args mock.Arguments) {
+		fn := args.Get(3).(func(user model.User) error)
+		for _, user := range users {
+			if err := fn(user); err != nil {
+				return
+			}
+		}
+	}
+
+	t.Run("Streams the fields of the export", func(t *testing.T) {
+		s := newTestUserService(t)
+		exportRequest := dto.NewUserExportRequest(url.Values{"fields": {"id,email"}, "status": {"active,suspended"}})
+		assert.NoError(t, exportRequest.Validate())
+		s.userRepo.On("StreamUsers", ctx, mock.MatchedBy(func(filter repository.UserFilter) bool {
+			return assert.ObjectsAreEqual([]model.UserStatus{model.StatusActive, model.StatusSuspended}, filter.Statuses)
+		}), mock.Anything, mock.Anything, []repository.UserField{selectField.Id(), selectField.Email()}).Run(streamUsers).Return(nil)
+
+		var exported []dto.UserResponse
+		err := s.ExportUsers(ctx, exportRequest, func(userResponse dto.UserResponse) error {
+			exported = append(exported, userResponse)
+			return nil
+		})
+		assert.NoError(t, err)
+		if assert.Len(t, exported, 2) {
+			assert.Equal(t, "jane@example.com", exported[0].Email)
+			assert.Equal(t, "john@example.com", exported[1].Email)
+		}
+		s.userRepo.AssertExpectations(t)
+	})
+
+	t.Run("Never reads the password", func(t *testing.T) {
+		s := newTestUserService(t)
+		exportRequest := dto.NewUserExportRequest(url.Values{})
+		assert.NoError(t, exportRequest.Validate())
+		s.userRepo.On("StreamUsers", ctx, mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(selectFields []repository.UserField) bool {
+			for _, field := range selectFields {
+				if field == selectField.Password() {
+					return false
+				}
+			}
+			return len(selectFields) > 0
+		})).Return(nil)
+
+		assert.NoError(t, s.ExportUsers(ctx, exportRequest, func(dto.UserResponse) error { return nil }))
+		s.userRepo.AssertExpectations(t)
+	})
+
+	t.Run("Stops when the writer fails", func(t *testing.T) {
+		s := newTestUserService(t)
+		exportRequest := dto.NewUserExportRequest(url.Values{})
+		assert.NoError(t, exportRequest.Validate())
+		writeErr := errors.New("connection reset")
+		s.userRepo.On("StreamUsers", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(streamUsers).Return(writeErr)
+
+		calls := 0
+		err := s.ExportUsers(ctx, exportRequest, func(dto.UserResponse) error {
+			calls++
+			return writeErr
+		})
+		assert.ErrorIs(t, err, writeErr)
+		assert.Equal(t, 1, calls)
+	})
+}
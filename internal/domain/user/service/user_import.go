@@ -0,0 +1,112 @@
+package service
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+)
+
+// ImportUsers validates the rows of an import and creates their users on behalf
+// of the caller. Nothing is written on a dry run or when any row is invalid, the
+// report then lists the reasons per row instead.
+func (s *UserServiceImpl) ImportUsers(ctx context.Context, rows []dto.UserImportRow, dryRun bool) (dto.UserImportReport, error) {
+	caller, ok := model.PrincipalFromContext(ctx)
+	if !ok {
+		return dto.UserImportReport{}, failure.Unauthorized("Missing authenticated user")
+	}
+	report := dto.UserImportReport{DryRun: dryRun, Total: len(rows), Errors: []dto.UserImportRowError{}}
+
+	invalid := make([]map[string][]string, len(rows))
+	firstLines := make(map[string]int)
+	var emails []string
+	for i := range rows {
+		invalid[i] = make(map[string][]string)
+		if err := rows[i].Validate(); err != nil {
+			var validationFailure *failure.Failure
+			if !errors.As(err, &validationFailure) || validationFailure.Fields == nil {
+				return dto.UserImportReport{}, err
+			}
+			invalid[i] = validationFailure.Fields
+		}
+		if rows[i].Email == "" {
+			continue
+		}
+		email := strings.ToLower(rows[i].Email)
+		if line, seen := firstLines[email]; seen {
+			invalid[i]["email"] = append(invalid[i]["email"], fmt.Sprintf("is already given on line %d", line))
+			continue
+		}
+		firstLines[email] = rows[i].Line
+		emails = append(emails, email)
+	}
+
+	usedEmails, err := s.UserRepository.ResolveUsedEmails(ctx, emails)
+	if err != nil {
+		log.Error().Err(err).Msg("[ImportUsers] failed resolve used emails")
+		return dto.UserImportReport{}, err
+	}
+	used := make(map[string]bool, len(usedEmails))
+	for _, email := range usedEmails {
+		used[email] = true
+	}
+
+	for i, row := range rows {
+		if used[strings.ToLower(row.Email)] {
+			invalid[i]["email"] = append(invalid[i]["email"], "already belongs to a user")
+		}
+		if len(invalid[i]) > 0 {
+			report.Errors = append(report.Errors, dto.UserImportRowError{Line: row.Line, Email: row.Email, Fields: invalid[i]})
+		}
+	}
+	report.Valid = report.Total - len(report.Errors)
+	if dryRun || len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	users, err := importRowsToModels(rows, caller.UserID.String())
+	if err != nil {
+		log.Error().Err(err).Msg("[ImportUsers] failed convert rows to users")
+		return dto.UserImportReport{}, err
+	}
+	if err = s.UserRepository.CreateUsers(ctx, users); err != nil {
+		log.Error().Err(err).Msg("[ImportUsers] failed create users")
+		return dto.UserImportReport{}, err
+	}
+	report.Imported = len(users)
+	log.Info().Str("actor", caller.UserID.String()).Int("imported", report.Imported).Msg("[ImportUsers] imported users")
+	return report, nil
+}
+
+// importRowsToModels returns the users of the rows. Hashing plain text passwords
+// is slow on purpose, so it is spread over the available CPUs.
+func importRowsToModels(rows []dto.UserImportRow, createdBy string) ([]model.User, error) {
+	users := make([]model.User, len(rows))
+	errs := make([]error, len(rows))
+	slots := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for i := range rows {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			users[i], errs[i] = rows[i].ToModel(createdBy)
+		}(i)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
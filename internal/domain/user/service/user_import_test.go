@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestImportUsers(t *testing.T) {
+	admin := model.Principal{UserID: uuid.New(), Role: model.RoleAdmin}
+	ctx := model.NewPrincipalContext(context.Background(), admin)
+
+	t.Run("Import", func(t *testing.T) {
+		s := newTestUserService(t)
+		s.userRepo.On("ResolveUsedEmails", ctx, []string{"jane@example.com", "john@example.com"}).Return([]string{}, nil)
+		s.userRepo.On("CreateUsers", ctx, mock.AnythingOfType("[]model.User")).Return(nil)
+
+		report, err := s.ImportUsers(ctx, []dto.UserImportRow{
+			{Line: 2, Email: "Jane@example.com", Fullname: "Jane Doe", Password: "Str0ngPassword"},
+			{Line: 3, Email: "john@example.com", Fullname: "John Doe", Password: "Str0ngPassword", Role: model.RoleSupport},
+		}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, dto.UserImportReport{Total: 2, Valid: 2, Imported: 2, Errors: []dto.UserImportRowError{}}, report)
+
+		users := s.userRepo.Calls[1].Arguments.Get(1).([]model.User)
+		if assert.Len(t, users, 2) {
+			assert.Equal(t, admin.UserID.String(), users[0].CreatedBy)
+			assert.Equal(t, model.RoleSupport, users[1].Role)
+			match, err := users[1].ComparePassword("Str0ngPassword")
+			assert.NoError(t, err)
+			assert.True(t, match)
+		}
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		s := newTestUserService(t)
+		s.userRepo.On("ResolveUsedEmails", ctx, []string{"jane@example.com"}).Return([]string{}, nil)
+
+		report, err := s.ImportUsers(ctx, []dto.UserImportRow{
+			{Line: 2, Email: "jane@example.com", Fullname: "Jane Doe", Password: "Str0ngPassword"},
+		}, true)
+		assert.NoError(t, err)
+		assert.Equal(t, dto.UserImportReport{DryRun: true, Total: 1, Valid: 1, Errors: []dto.UserImportRowError{}}, report)
+		s.userRepo.AssertNotCalled(t, "CreateUsers", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid rows", func(t *testing.T) {
+		s := newTestUserService(t)
+		s.userRepo.On("ResolveUsedEmails", ctx, []string{"jane@example.com", "john@example.com", "joan@example.com"}).
+			Return([]string{"john@example.com"}, nil)
+
+		report, err := s.ImportUsers(ctx, []dto.UserImportRow{
+			{Line: 2, Email: "jane@example.com", Fullname: "Jane Doe", Password: "Str0ngPassword"},
+			{Line: 3, Email: "JANE@example.com", Fullname: "Jane Again", Password: "Str0ngPassword"},
+			{Line: 4, Email: "john@example.com", Fullname: "John Doe", Password: "Str0ngPassword"},
+			// a hash that would make argon2 panic when the user logs in
+			{Line: 5, Email: "joan@example.com", Fullname: "Joan Doe",
+				PasswordHash: "$argon2id$v=19$m=65536,t=0,p=2$AAAAAAAAAAAAAAAAAAAAAA$AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"},
+		}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, report.Total)
+		assert.Equal(t, 1, report.Valid)
+		assert.Equal(t, 0, report.Imported)
+		if assert.Len(t, report.Errors, 3) {
+			assert.Equal(t, []string{"is already given on line 2"}, report.Errors[0].Fields["email"])
+			assert.Equal(t, []string{"already belongs to a user"}, report.Errors[1].Fields["email"])
+			assert.Equal(t, 5, report.Errors[2].Line)
+			assert.Equal(t, []string{"must be a bcrypt or argon2id hash"}, report.Errors[2].Fields["passwordHash"])
+		}
+		s.userRepo.AssertNotCalled(t, "CreateUsers", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"context"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/internal/domain/user/repository"
+	"github.com/IlhamRobyana/user/shared/failure"
+)
+
+// userSortFields maps the fields of a user response to the fields users are
+// sorted by in the repository.
+var userSortFields = func() map[dto.UserDTOFieldNameType]repository.UserField {
+	selectField := repository.NewUserSelectFields()
+	return map[dto.UserDTOFieldNameType]repository.UserField{
+		dto.UserDTOFieldName.Id:        selectField.Id(),
+		dto.UserDTOFieldName.Email:     selectField.Email(),
+		dto.UserDTOFieldName.Fullname:  selectField.Fullname(),
+		dto.UserDTOFieldName.Status:    selectField.Status(),
+		dto.UserDTOFieldName.Role:      selectField.Role(),
+		dto.UserDTOFieldName.CreatedAt: selectField.CreatedAt(),
+		dto.UserDTOFieldName.UpdatedAt: selectField.UpdatedAt(),
+		dto.UserDTOFieldName.DeletedAt: selectField.DeletedAt(),
+		dto.UserDTOFieldName.CreatedBy: selectField.CreatedBy(),
+		dto.UserDTOFieldName.UpdatedBy: selectField.UpdatedBy(),
+		dto.UserDTOFieldName.DeletedBy: selectField.DeletedBy(),
+	}
+}()
+
+// userDeletedScopes maps the deleted filter of a listing request to the scope of
+// soft deleted users in the repository.
+var userDeletedScopes = map[string]repository.UserDeletedScope{
+	dto.DeletedExclude: repository.UserDeletedExcluded,
+	dto.DeletedInclude: repository.UserDeletedIncluded,
+	dto.DeletedOnly:    repository.UserDeletedOnly,
+}
+
+// ResolveUsers returns a page of a listing of users. Numbered pages are counted,
+// while pages following on from a cursor are not, which keeps paging through
+// large listings cheap. Given a sparse fieldset, only those fields are fetched.
+func (s *UserServiceImpl) ResolveUsers(ctx context.Context, listRequest dto.UserListRequest) ([]dto.UserResponse, dto.UserListMetadata, error) {
+	listQuery := repository.UserListQuery{
+		Filter: userListFilter(listRequest),
+		Sort:   userListSort(listRequest),
+		Limit:  listRequest.Limit,
+	}
+	metadata := dto.UserListMetadata{Limit: listRequest.Limit}
+
+	if listRequest.IsKeyset() {
+		cursor, err := repository.DecodeUserCursor(listRequest.Cursor)
+		if err != nil || cursor.Sort != listQuery.Sort {
+			return nil, metadata, failure.ValidationFailed(map[string][]string{
+				"cursor": {"is not a cursor of this listing"},
+			})
+		}
+		listQuery.Cursor = &cursor
+	} else {
+		listQuery.Offset = (listRequest.Page - 1) * listRequest.Limit
+		count, err := s.UserRepository.CountUsers(ctx, listQuery.Filter)
+		if err != nil {
+			log.Error().Err(err).Msg("[ResolveUsers] failed count users")
+			return nil, metadata, err
+		}
+		totalPages := (count + int64(listRequest.Limit) - 1) / int64(listRequest.Limit)
+		metadata.Page = listRequest.Page
+		metadata.TotalCount = &count
+		metadata.TotalPages = &totalPages
+	}
+
+	// the cursor of the next page is made of the id and the sort field
+	selectField := repository.NewUserSelectFields()
+	selectFields := userSelectFields(listRequest.Fields, selectField.Id(), listQuery.Sort.Field)
+	users, next, err := s.UserRepository.ResolveUsers(ctx, listQuery, selectFields...)
+	if err != nil {
+		log.Error().Err(err).Msg("[ResolveUsers] failed get users")
+		return nil, metadata, err
+	}
+	if next != nil {
+		metadata.NextCursor = next.Encode()
+	}
+	userResponses := make([]dto.UserResponse, 0, len(users))
+	for _, user := range users {
+		userResponses = append(userResponses, dto.NewUserResponse(*user))
+	}
+	return userResponses, metadata, nil
+}
+
+// userListFilter returns the filter of a listing request.
+func userListFilter(listRequest dto.UserListRequest) repository.UserFilter {
+	return repository.UserFilter{
+		Statuses:    listRequest.Statuses,
+		EmailPrefix: listRequest.EmailPrefix,
+		CreatedFrom: listRequest.CreatedFrom,
+		CreatedTo:   listRequest.CreatedTo,
+		Deleted:     userDeletedScopes[listRequest.Deleted],
+	}
+}
+
+// userListSort returns the sort of a listing request.
+func userListSort(listRequest dto.UserListRequest) repository.UserSort {
+	return repository.UserSort{
+		Field:      userSortFields[listRequest.SortBy],
+		Descending: listRequest.Order == dto.SortDescending,
+	}
+}
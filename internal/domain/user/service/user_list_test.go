@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/internal/domain/user/repository"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestResolveUsers(t *testing.T) {
+	ctx := context.Background()
+	selectField := repository.NewUserSelectFields()
+	user := &model.User{Id: uuid.New(), Email: "jane@example.com", Fullname: "Jane Doe", Status: model.StatusActive}
+
+	t.Run("Numbered page", func(t *testing.T) {
+		s := newTestUserService(t)
+		listRequest := dto.NewUserListRequest(url.Values{"page": {"2"}, "limit": {"20"}, "status": {"active"}})
+		assert.NoError(t, listRequest.Validate())
+		s.userRepo.On("CountUsers", ctx, mock.AnythingOfType("repository.UserFilter")).Return(int64(45), nil)
+		next := repository.NewUserCursor(*user, repository.UserSort{Field: selectField.CreatedAt(), Descending: true})
+		s.userRepo.On("ResolveUsers", ctx, mock.MatchedBy(func(listQuery repository.UserListQuery) bool {
+			return listQuery.Offset == 20 && listQuery.Limit == 20 && listQuery.Cursor == nil
+		}), mock.Anything).Return(model.UserList{user}, &next, nil)
+
+		users, metadata, err := s.ResolveUsers(ctx, listRequest)
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+		assert.Equal(t, 2, metadata.Page)
+		assert.Equal(t, int64(45), *metadata.TotalCount)
+		assert.Equal(t, int64(3), *metadata.TotalPages)
+		assert.Equal(t, next.Encode(), metadata.NextCursor)
+		s.userRepo.AssertExpectations(t)
+	})
+
+	t.Run("Page following a cursor is not counted", func(t *testing.T) {
+		s := newTestUserService(t)
+		cursor := repository.NewUserCursor(*user, repository.UserSort{Field: selectField.CreatedAt(), Descending: true})
+		listRequest := dto.NewUserListRequest(url.Values{"cursor": {cursor.Encode()}})
+		assert.NoError(t, listRequest.Validate())
+		s.userRepo.On("ResolveUsers", ctx, mock.MatchedBy(func(listQuery repository.UserListQuery) bool {
+			return listQuery.Cursor != nil && *listQuery.Cursor == cursor
+		}), mock.Anything).Return(model.UserList{}, (*repository.UserCursor)(nil), nil)
+
+		_, metadata, err := s.ResolveUsers(ctx, listRequest)
+		assert.NoError(t, err)
+		assert.Nil(t, metadata.TotalCount)
+		assert.Empty(t, metadata.NextCursor)
+		s.userRepo.AssertNotCalled(t, "CountUsers", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Cursor of another listing", func(t *testing.T) {
+		s := newTestUserService(t)
+		cursor := repository.NewUserCursor(*user, repository.UserSort{Field: selectField.Email()})
+		listRequest := dto.NewUserListRequest(url.Values{"cursor": {cursor.Encode()}})
+		assert.NoError(t, listRequest.Validate())
+
+		_, _, err := s.ResolveUsers(ctx, listRequest)
+		assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+		s.userRepo.AssertNotCalled(t, "ResolveUsers", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
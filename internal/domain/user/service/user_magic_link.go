@@ -0,0 +1,119 @@
+package service
+
+import (
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/notifier"
+	"github.com/IlhamRobyana/user/shared/token"
+)
+
+// RequestMagicLink emails a single-use login link to an active user. Requests are
+// limited per email like password logins, and it succeeds whether or not the email
+// is registered so it cannot be used to discover accounts: failures past the
+// lookup are only logged, as reporting them would tell a registered email apart.
+func (s *UserServiceImpl) RequestMagicLink(ctx context.Context, magicLinkRequest dto.UserMagicLinkRequest) error {
+	attempt, err := s.GetMagicLinkAttempt(ctx, magicLinkRequest.Email)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Error().Err(err).Msg("[RequestMagicLink] failed get magic link attempt")
+		return failure.InternalError(err)
+	}
+	if attempt >= s.cfg.Internal.MaxLoginAttempt {
+		return failure.Forbidden("Max login attempts exceeded, please wait before requesting another link")
+	}
+	err = s.SetMagicLinkAttempt(ctx, magicLinkRequest.Email, attempt+1, s.cfg.Internal.LoginAttemptTTL)
+	if err != nil {
+		log.Error().Err(err).Msg("[RequestMagicLink] failed set magic link attempt")
+		return failure.InternalError(err)
+	}
+
+	user, err := s.UserRepository.ResolveUserByEmail(ctx, magicLinkRequest.Email)
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			return nil
+		}
+		log.Error().Err(err).Msg("[RequestMagicLink] failed get user by email")
+		return err
+	}
+	if !user.Status.CanLogin() {
+		return nil
+	}
+
+	magicLinkConfig := s.cfg.Auth.MagicLink
+	claims := token.NewEmailClaims(token.AudienceMagicLink, s.cfg.Auth.Issuer, user.Id, user.Email, magicLinkConfig.TTL)
+	magicLinkToken, err := token.SignEmail(claims, magicLinkConfig.Secret)
+	if err != nil {
+		log.Error().Err(err).Msg("[RequestMagicLink] failed sign magic link token")
+		return nil
+	}
+
+	magicLinkURL := fmt.Sprintf("%s?token=%s", magicLinkConfig.URL, url.QueryEscape(magicLinkToken))
+	err = s.Notifications.Dispatch(ctx, notifier.Notification{
+		Template: notifier.TemplateMagicLink,
+		To:       user.Email,
+		Data: map[string]interface{}{
+			"Name":      user.Fullname,
+			"URL":       magicLinkURL,
+			"ExpiresIn": magicLinkConfig.TTL.String(),
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("[RequestMagicLink] failed send magic link email")
+	}
+	return nil
+}
+
+// LoginMagicLink logs the user in with a magic link token. Users with a second
+// factor are challenged for it like on a password login.
+func (s *UserServiceImpl) LoginMagicLink(ctx context.Context, loginRequest dto.UserMagicLinkLoginRequest) (dto.UserLoginResponse, error) {
+	claims, err := token.ParseEmail(loginRequest.Token, s.cfg.Auth.MagicLink.Secret, token.AudienceMagicLink)
+	if err != nil {
+		return dto.UserLoginResponse{}, failure.Unauthorized("Invalid or expired magic link")
+	}
+	first, err := s.SetMagicLinkUsed(ctx, claims.ID, time.Until(claims.ExpiresAt.Time))
+	if err != nil {
+		log.Error().Err(err).Msg("[LoginMagicLink] failed mark magic link as used")
+		return dto.UserLoginResponse{}, failure.InternalError(err)
+	}
+	if !first {
+		return dto.UserLoginResponse{}, failure.Unauthorized("Invalid or expired magic link")
+	}
+
+	user, err := s.UserRepository.ResolveUserByID(ctx, claims.UserID)
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			return dto.UserLoginResponse{}, failure.Unauthorized("Invalid or expired magic link")
+		}
+		log.Error().Err(err).Msg("[LoginMagicLink] failed get user by id")
+		return dto.UserLoginResponse{}, err
+	}
+	// a link sent to a previous address must not log in to the account
+	if user.Email != claims.Email {
+		return dto.UserLoginResponse{}, failure.Unauthorized("Invalid or expired magic link")
+	}
+	if !user.Status.CanLogin() {
+		return dto.UserLoginResponse{}, failure.Forbidden("User is not active")
+	}
+	if err = s.DeleteMagicLinkAttempt(ctx, user.Email); err != nil {
+		log.Warn().Err(err).Msg("[LoginMagicLink] failed clear magic link attempt")
+	}
+
+	mfaEnabled, err := s.isMFAEnabled(ctx, user.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("[LoginMagicLink] failed check mfa")
+		return dto.UserLoginResponse{}, err
+	}
+	if mfaEnabled {
+		return s.startMFAChallenge(ctx, user)
+	}
+	return s.startSession(ctx, user, loginRequest.Client)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRequestMagicLink(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Login with the link once", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.mfaRepo.On("ResolveMFAByUserID", ctx, user.Id).Return(model.MFA{}, failure.NotFound("mfa"))
+		s.sessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
+
+		assert.NoError(t, s.RequestMagicLink(ctx, dto.UserMagicLinkRequest{Email: user.Email}))
+		magicLinkToken := linkToken(t, waitForMessage(t, s.messages, user.Email))
+
+		loginResponse, err := s.LoginMagicLink(ctx, dto.UserMagicLinkLoginRequest{Token: magicLinkToken})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, loginResponse.AccessToken)
+
+		_, err = s.LoginMagicLink(ctx, dto.UserMagicLinkLoginRequest{Token: magicLinkToken})
+		assert.Equal(t, http.StatusUnauthorized, failure.GetCode(err))
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		s := newTestUserService(t)
+		s.userRepo.On("ResolveUserByEmail", ctx, "nobody@example.com", mock.Anything).Return(model.User{}, failure.NotFound("user"))
+
+		assert.NoError(t, s.RequestMagicLink(ctx, dto.UserMagicLinkRequest{Email: "nobody@example.com"}))
+	})
+
+	t.Run("Failures do not tell registered emails apart", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
+		s.Notifications = stalledNotifications(s)
+
+		assert.NoError(t, s.RequestMagicLink(ctx, dto.UserMagicLinkRequest{Email: user.Email}))
+	})
+
+	t.Run("Requests are limited", func(t *testing.T) {
+		s := newTestUserService(t)
+		s.userRepo.On("ResolveUserByEmail", ctx, "nobody@example.com", mock.Anything).Return(model.User{}, failure.NotFound("user"))
+
+		for i := 0; i < s.cfg.Internal.MaxLoginAttempt; i++ {
+			assert.NoError(t, s.RequestMagicLink(ctx, dto.UserMagicLinkRequest{Email: "nobody@example.com"}))
+		}
+		err := s.RequestMagicLink(ctx, dto.UserMagicLinkRequest{Email: "nobody@example.com"})
+		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
+	})
+}
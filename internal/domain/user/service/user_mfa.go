@@ -0,0 +1,318 @@
+package service
+
+import (
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/crypt"
+	"github.com/IlhamRobyana/user/shared/failure"
+)
+
+const (
+	mfaTokenSize       = 32
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	totpCodeLength     = 6
+	// a TOTP code is accepted during its own period and the ones around it
+	totpCodeReuseTTL = 90 * time.Second
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EnrollTOTP generates a new TOTP secret for the calling user. The secret takes
+// no part in logins until it is confirmed with a code.
+func (s *UserServiceImpl) EnrollTOTP(ctx context.Context, primaryID uuid.UUID) (dto.UserMFAEnrollResponse, error) {
+	user, err := s.resolveCallingUser(ctx, primaryID)
+	if err != nil {
+		return dto.UserMFAEnrollResponse{}, err
+	}
+	mfa, err := s.MFARepository.ResolveMFAByUserID(ctx, user.Id)
+	if err != nil && failure.GetCode(err) != http.StatusNotFound {
+		log.Error().Err(err).Msg("[EnrollTOTP] failed get mfa")
+		return dto.UserMFAEnrollResponse{}, err
+	}
+	if err == nil && mfa.Enabled {
+		return dto.UserMFAEnrollResponse{}, failure.Conflict("enroll", "mfa", "multi-factor authentication is already enabled")
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.cfg.App.Name,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("[EnrollTOTP] failed generate totp secret")
+		return dto.UserMFAEnrollResponse{}, failure.InternalError(err)
+	}
+	encrypted, err := crypt.Encrypt(key.Secret(), s.cfg.Auth.MFA.EncryptionKey)
+	if err != nil {
+		log.Error().Err(err).Msg("[EnrollTOTP] failed encrypt totp secret")
+		return dto.UserMFAEnrollResponse{}, failure.InternalError(err)
+	}
+	now := time.Now()
+	err = s.MFARepository.UpsertMFA(ctx, &model.MFA{
+		UserId:    user.Id,
+		Secret:    encrypted,
+		Enabled:   false,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("[EnrollTOTP] failed store mfa")
+		return dto.UserMFAEnrollResponse{}, err
+	}
+	return dto.UserMFAEnrollResponse{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+	}, nil
+}
+
+// ConfirmTOTP enables the enrolled TOTP secret of the calling user with a code
+// generated from it and returns a fresh set of recovery codes.
+func (s *UserServiceImpl) ConfirmTOTP(ctx context.Context, primaryID uuid.UUID, confirmRequest dto.UserMFAConfirmRequest) (dto.UserMFARecoveryCodesResponse, error) {
+	user, err := s.resolveCallingUser(ctx, primaryID)
+	if err != nil {
+		return dto.UserMFARecoveryCodesResponse{}, err
+	}
+	mfa, err := s.MFARepository.ResolveMFAByUserID(ctx, user.Id)
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			return dto.UserMFARecoveryCodesResponse{}, failure.BadRequestFromString("Multi-factor authentication is not enrolled")
+		}
+		log.Error().Err(err).Msg("[ConfirmTOTP] failed get mfa")
+		return dto.UserMFARecoveryCodesResponse{}, err
+	}
+	if mfa.Enabled {
+		return dto.UserMFARecoveryCodesResponse{}, failure.Conflict("confirm", "mfa", "multi-factor authentication is already enabled")
+	}
+	valid, err := s.validateTOTPCode(ctx, mfa, confirmRequest.Code)
+	if err != nil {
+		return dto.UserMFARecoveryCodesResponse{}, err
+	}
+	if !valid {
+		return dto.UserMFARecoveryCodesResponse{}, failure.BadRequestFromString("Invalid code")
+	}
+
+	codes, recoveryCodes, err := generateRecoveryCodes(user.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("[ConfirmTOTP] failed generate recovery codes")
+		return dto.UserMFARecoveryCodesResponse{}, failure.InternalError(err)
+	}
+	if err = s.MFARepository.EnableMFA(ctx, user.Id, recoveryCodes); err != nil {
+		log.Error().Err(err).Msg("[ConfirmTOTP] failed enable mfa")
+		return dto.UserMFARecoveryCodesResponse{}, err
+	}
+	return dto.UserMFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP removes the second factor of the calling user after checking both
+// the password and a TOTP or recovery code.
+func (s *UserServiceImpl) DisableTOTP(ctx context.Context, primaryID uuid.UUID, disableRequest dto.UserMFADisableRequest) error {
+	user, err := s.resolveCallingUser(ctx, primaryID)
+	if err != nil {
+		return err
+	}
+	match, err := user.ComparePassword(disableRequest.Password)
+	if err != nil {
+		log.Error().Err(err).Msg("[DisableTOTP] failed compare password")
+		return failure.InternalError(err)
+	}
+	if !match {
+		return failure.BadRequestFromString("Password is incorrect")
+	}
+
+	mfa, err := s.MFARepository.ResolveMFAByUserID(ctx, user.Id)
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			return failure.BadRequestFromString("Multi-factor authentication is not enabled")
+		}
+		log.Error().Err(err).Msg("[DisableTOTP] failed get mfa")
+		return err
+	}
+	if mfa.Enabled {
+		valid, err := s.verifyMFACode(ctx, mfa, disableRequest.Code)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return failure.BadRequestFromString("Invalid code")
+		}
+	}
+
+	if err = s.MFARepository.DeleteMFA(ctx, user.Id); err != nil {
+		log.Error().Err(err).Msg("[DisableTOTP] failed delete mfa")
+		return err
+	}
+	return nil
+}
+
+// LoginMFA completes a login challenged for a second factor and starts the session.
+func (s *UserServiceImpl) LoginMFA(ctx context.Context, loginRequest dto.UserLoginMFARequest) (dto.UserLoginResponse, error) {
+	tokenHash := crypt.HashSHA256(loginRequest.MfaToken)
+	userID, err := s.GetMFAChallenge(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return dto.UserLoginResponse{}, failure.Unauthorized("Invalid or expired MFA token")
+		}
+		log.Error().Err(err).Msg("[LoginMFA] failed get mfa challenge")
+		return dto.UserLoginResponse{}, failure.InternalError(err)
+	}
+
+	attempt, err := s.IncrMFAChallengeAttempt(ctx, tokenHash, s.cfg.Auth.MFA.ChallengeTTL)
+	if err != nil {
+		log.Error().Err(err).Msg("[LoginMFA] failed count mfa challenge attempt")
+		return dto.UserLoginResponse{}, failure.InternalError(err)
+	}
+	if attempt > int64(s.cfg.Auth.MFA.MaxChallengeAttempts) {
+		// the challenge is dropped, the user has to log in with the password again
+		if err = s.DeleteMFAChallenge(ctx, tokenHash); err != nil {
+			log.Warn().Err(err).Msg("[LoginMFA] failed delete mfa challenge")
+		}
+		return dto.UserLoginResponse{}, failure.Unauthorized("Invalid or expired MFA token")
+	}
+
+	mfa, err := s.MFARepository.ResolveMFAByUserID(ctx, userID)
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			return dto.UserLoginResponse{}, failure.Unauthorized("Invalid or expired MFA token")
+		}
+		log.Error().Err(err).Msg("[LoginMFA] failed get mfa")
+		return dto.UserLoginResponse{}, err
+	}
+	// a secret enrolled after the password step takes no part until confirmed
+	if !mfa.Enabled {
+		return dto.UserLoginResponse{}, failure.Unauthorized("Invalid code")
+	}
+	valid, err := s.verifyMFACode(ctx, mfa, loginRequest.Code)
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+	if !valid {
+		return dto.UserLoginResponse{}, failure.Unauthorized("Invalid code")
+	}
+	if err = s.DeleteMFAChallenge(ctx, tokenHash); err != nil {
+		log.Error().Err(err).Msg("[LoginMFA] failed delete mfa challenge")
+		return dto.UserLoginResponse{}, failure.InternalError(err)
+	}
+
+	user, err := s.UserRepository.ResolveUserByID(ctx, userID)
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			return dto.UserLoginResponse{}, failure.Unauthorized("Invalid or expired MFA token")
+		}
+		log.Error().Err(err).Msg("[LoginMFA] failed get user by id")
+		return dto.UserLoginResponse{}, err
+	}
+	if !user.Status.CanLogin() {
+		return dto.UserLoginResponse{}, failure.Forbidden("User is not active")
+	}
+	return s.startSession(ctx, user, loginRequest.Client)
+}
+
+// isMFAEnabled reports whether logins of the user require a second factor.
+func (s *UserServiceImpl) isMFAEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	mfa, err := s.MFARepository.ResolveMFAByUserID(ctx, userID)
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return mfa.Enabled, nil
+}
+
+// startMFAChallenge issues a short-lived token the login of the user has to be
+// completed with, in place of a session.
+func (s *UserServiceImpl) startMFAChallenge(ctx context.Context, user model.User) (dto.UserLoginResponse, error) {
+	mfaToken, err := crypt.GenerateToken(mfaTokenSize)
+	if err != nil {
+		log.Error().Err(err).Msg("[startMFAChallenge] failed generate mfa token")
+		return dto.UserLoginResponse{}, failure.InternalError(err)
+	}
+	ttl := s.cfg.Auth.MFA.ChallengeTTL
+	if err = s.SetMFAChallenge(ctx, crypt.HashSHA256(mfaToken), user.Id, ttl); err != nil {
+		log.Error().Err(err).Msg("[startMFAChallenge] failed store mfa challenge")
+		return dto.UserLoginResponse{}, failure.InternalError(err)
+	}
+	expiresAt := time.Now().Add(ttl)
+	return dto.UserLoginResponse{
+		Challenge:         dto.ChallengeMFARequired,
+		MfaToken:          mfaToken,
+		MfaTokenExpiresAt: &expiresAt,
+	}, nil
+}
+
+// verifyMFACode reports whether code is a valid TOTP code or an unused recovery
+// code of the enabled second factor. A matching recovery code is used up.
+func (s *UserServiceImpl) verifyMFACode(ctx context.Context, mfa model.MFA, code string) (bool, error) {
+	code = normalizeMFACode(code)
+	if len(code) == totpCodeLength {
+		return s.validateTOTPCode(ctx, mfa, code)
+	}
+	used, err := s.MFARepository.UseRecoveryCode(ctx, mfa.UserId, crypt.HashSHA256(code))
+	if err != nil {
+		log.Error().Err(err).Msg("[verifyMFACode] failed use recovery code")
+		return false, err
+	}
+	return used, nil
+}
+
+// validateTOTPCode reports whether code is valid for the secret of mfa. Each code
+// is accepted only once.
+func (s *UserServiceImpl) validateTOTPCode(ctx context.Context, mfa model.MFA, code string) (bool, error) {
+	secret, err := crypt.Decrypt(mfa.Secret, s.cfg.Auth.MFA.EncryptionKey)
+	if err != nil {
+		log.Error().Err(err).Msg("[validateTOTPCode] failed decrypt totp secret")
+		return false, failure.InternalError(err)
+	}
+	code = normalizeMFACode(code)
+	if !totp.Validate(code, secret) {
+		return false, nil
+	}
+	first, err := s.SetTOTPCodeUsed(ctx, mfa.UserId, code, totpCodeReuseTTL)
+	if err != nil {
+		log.Error().Err(err).Msg("[validateTOTPCode] failed mark totp code as used")
+		return false, failure.InternalError(err)
+	}
+	return first, nil
+}
+
+// generateRecoveryCodes returns new recovery codes in plain text, for the user to
+// keep, and hashed, for storage.
+func generateRecoveryCodes(userID uuid.UUID) ([]string, model.RecoveryCodeList, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	recoveryCodes := make(model.RecoveryCodeList, 0, recoveryCodeCount)
+	now := time.Now()
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:recoveryCodeLength]
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		recoveryCodes = append(recoveryCodes, &model.RecoveryCode{
+			Id:        uuid.New(),
+			UserId:    userID,
+			CodeHash:  crypt.HashSHA256(code),
+			CreatedAt: now,
+		})
+	}
+	return codes, recoveryCodes, nil
+}
+
+// normalizeMFACode drops the separators users may type along with a code.
+func normalizeMFACode(code string) string {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(code)
+	return strings.ToLower(code)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/crypt"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newTestMFA returns an enabled second factor of the user and the secret of its
+// TOTP codes.
+func newTestMFA(t *testing.T, s testUserService, user model.User) (model.MFA, string) {
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: "user-test", AccountName: user.Email})
+	assert.NoError(t, err)
+	encrypted, err := crypt.Encrypt(key.Secret(), s.cfg.Auth.MFA.EncryptionKey)
+	assert.NoError(t, err)
+	return model.MFA{UserId: user.Id, Secret: encrypted, Enabled: true}, key.Secret()
+}
+
+func TestLoginMFA(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Password login is challenged", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		mfa, _ := newTestMFA(t, s, user)
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
+		s.mfaRepo.On("ResolveMFAByUserID", ctx, user.Id).Return(mfa, nil)
+
+		loginResponse, err := s.LoginUser(ctx, dto.UserLoginRequest{Email: user.Email, Password: "Str0ngPassword"})
+		assert.NoError(t, err)
+		assert.Equal(t, dto.ChallengeMFARequired, loginResponse.Challenge)
+		assert.NotEmpty(t, loginResponse.MfaToken)
+		assert.Empty(t, loginResponse.AccessToken)
+		s.sessionRepo.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+	})
+
+	t.Run("TOTP code completes the login once", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		mfa, secret := newTestMFA(t, s, user)
+		s.mfaRepo.On("ResolveMFAByUserID", ctx, user.Id).Return(mfa, nil)
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.sessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
+		code, err := totp.GenerateCode(secret, time.Now())
+		assert.NoError(t, err)
+
+		challenge, err := s.startMFAChallenge(ctx, user)
+		assert.NoError(t, err)
+		loginResponse, err := s.LoginMFA(ctx, dto.UserLoginMFARequest{MfaToken: challenge.MfaToken, Code: code})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, loginResponse.AccessToken)
+
+		// the challenge is used up and the code cannot be replayed
+		_, err = s.LoginMFA(ctx, dto.UserLoginMFARequest{MfaToken: challenge.MfaToken, Code: code})
+		assert.Equal(t, http.StatusUnauthorized, failure.GetCode(err))
+		challenge, err = s.startMFAChallenge(ctx, user)
+		assert.NoError(t, err)
+		_, err = s.LoginMFA(ctx, dto.UserLoginMFARequest{MfaToken: challenge.MfaToken, Code: code})
+		assert.Equal(t, http.StatusUnauthorized, failure.GetCode(err))
+		s.sessionRepo.AssertNumberOfCalls(t, "CreateSession", 1)
+	})
+
+	t.Run("Unconfirmed secret", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		mfa, secret := newTestMFA(t, s, user)
+		// re-enrolled between the password step and the second factor
+		mfa.Enabled = false
+		s.mfaRepo.On("ResolveMFAByUserID", ctx, user.Id).Return(mfa, nil)
+		code, err := totp.GenerateCode(secret, time.Now())
+		assert.NoError(t, err)
+
+		challenge, err := s.startMFAChallenge(ctx, user)
+		assert.NoError(t, err)
+		_, err = s.LoginMFA(ctx, dto.UserLoginMFARequest{MfaToken: challenge.MfaToken, Code: code})
+		assert.Equal(t, http.StatusUnauthorized, failure.GetCode(err))
+		s.mfaRepo.AssertNotCalled(t, "UseRecoveryCode", mock.Anything, mock.Anything, mock.Anything)
+		s.sessionRepo.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Recovery code", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		mfa, _ := newTestMFA(t, s, user)
+		s.mfaRepo.On("ResolveMFAByUserID", ctx, user.Id).Return(mfa, nil)
+		s.mfaRepo.On("UseRecoveryCode", ctx, user.Id, crypt.HashSHA256("abcdefghij")).Return(true, nil)
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.sessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
+
+		challenge, err := s.startMFAChallenge(ctx, user)
+		assert.NoError(t, err)
+		loginResponse, err := s.LoginMFA(ctx, dto.UserLoginMFARequest{MfaToken: challenge.MfaToken, Code: "ABCDE-FGHIJ"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, loginResponse.AccessToken)
+		s.mfaRepo.AssertExpectations(t)
+	})
+
+	t.Run("Challenge is dropped after too many attempts", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		mfa, secret := newTestMFA(t, s, user)
+		s.mfaRepo.On("ResolveMFAByUserID", ctx, user.Id).Return(mfa, nil)
+		s.mfaRepo.On("UseRecoveryCode", ctx, user.Id, mock.Anything).Return(false, nil)
+
+		challenge, err := s.startMFAChallenge(ctx, user)
+		assert.NoError(t, err)
+		for i := 0; i < s.cfg.Auth.MFA.MaxChallengeAttempts; i++ {
+			_, err = s.LoginMFA(ctx, dto.UserLoginMFARequest{MfaToken: challenge.MfaToken, Code: "wrong-code"})
+			assert.Equal(t, http.StatusUnauthorized, failure.GetCode(err))
+		}
+
+		code, err := totp.GenerateCode(secret, time.Now())
+		assert.NoError(t, err)
+		_, err = s.LoginMFA(ctx, dto.UserLoginMFARequest{MfaToken: challenge.MfaToken, Code: code})
+		assert.Equal(t, http.StatusUnauthorized, failure.GetCode(err))
+		_, err = s.GetMFAChallenge(ctx, crypt.HashSHA256(challenge.MfaToken))
+		assert.Error(t, err)
+		s.userRepo.AssertNotCalled(t, "ResolveUserByID", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
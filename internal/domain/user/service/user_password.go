@@ -0,0 +1,196 @@
+package service
+
+import (
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/internal/domain/user/repository"
+	"github.com/IlhamRobyana/user/shared"
+	"github.com/IlhamRobyana/user/shared/crypt"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/notifier"
+)
+
+const passwordResetTokenSize = 32
+
+// ForgotPassword emails a password reset link to the user. It succeeds whether
+// or not the email is registered so it cannot be used to discover accounts:
+// failures past the lookup are only logged, as reporting them would tell a
+// registered email apart.
+func (s *UserServiceImpl) ForgotPassword(ctx context.Context, forgotRequest dto.UserForgotPasswordRequest) error {
+	user, err := s.UserRepository.ResolveUserByEmail(ctx, forgotRequest.Email)
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			return nil
+		}
+		log.Error().Err(err).Msg("[ForgotPassword] failed get user by email")
+		return err
+	}
+
+	resetToken, err := crypt.GenerateToken(passwordResetTokenSize)
+	if err != nil {
+		log.Error().Err(err).Msg("[ForgotPassword] failed generate reset token")
+		return nil
+	}
+	err = s.SetPasswordResetToken(ctx, crypt.HashSHA256(resetToken), user.Id, s.cfg.Auth.PasswordReset.TTL)
+	if err != nil {
+		log.Error().Err(err).Msg("[ForgotPassword] failed store reset token")
+		return nil
+	}
+
+	resetURL := fmt.Sprintf("%s?token=%s", s.cfg.Auth.PasswordReset.URL, url.QueryEscape(resetToken))
+	err = s.Notifications.Dispatch(ctx, notifier.Notification{
+		Template: notifier.TemplateResetPassword,
+		To:       user.Email,
+		Data: map[string]interface{}{
+			"Name":      user.Fullname,
+			"URL":       resetURL,
+			"ExpiresIn": s.cfg.Auth.PasswordReset.TTL.String(),
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("[ForgotPassword] failed send reset email")
+	}
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token, then clears the login
+// lockout counters and revokes every session of the user.
+func (s *UserServiceImpl) ResetPassword(ctx context.Context, resetRequest dto.UserResetPasswordRequest) error {
+	tokenHash := crypt.HashSHA256(resetRequest.Token)
+	userID, err := s.GetPasswordResetToken(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return failure.BadRequestFromString("Invalid or expired reset token")
+		}
+		log.Error().Err(err).Msg("[ResetPassword] failed get reset token")
+		return failure.InternalError(err)
+	}
+
+	user, err := s.UserRepository.ResolveUserByID(ctx, userID)
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			return failure.BadRequestFromString("Invalid or expired reset token")
+		}
+		log.Error().Err(err).Msg("[ResetPassword] failed get user by id")
+		return err
+	}
+	// the policy is checked before the token is used up so the user can retry
+	if reasons := shared.GetPasswordPolicy().Violations(resetRequest.Password, user.Email, user.Fullname); len(reasons) > 0 {
+		return failure.ValidationFailed(map[string][]string{"password": reasons})
+	}
+
+	consumedUserID, err := s.ConsumePasswordResetToken(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return failure.BadRequestFromString("Invalid or expired reset token")
+		}
+		log.Error().Err(err).Msg("[ResetPassword] failed consume reset token")
+		return failure.InternalError(err)
+	}
+	if consumedUserID != user.Id {
+		return failure.BadRequestFromString("Invalid or expired reset token")
+	}
+
+	hashed, err := crypt.HashPassword(resetRequest.Password)
+	if err != nil {
+		log.Error().Err(err).Msg("[ResetPassword] failed hash password")
+		return failure.InternalError(err)
+	}
+	if err = s.updatePassword(ctx, user.Id, hashed, user.Id); err != nil {
+		log.Error().Err(err).Msg("[ResetPassword] failed update user password")
+		return err
+	}
+
+	if err = s.DeleteLoginAttempt(ctx, user.Email); err != nil {
+		log.Error().Err(err).Msg("[ResetPassword] failed clear login attempt")
+		return failure.InternalError(err)
+	}
+	if err = s.DeleteSuspendAmount(ctx, user.Email); err != nil {
+		log.Error().Err(err).Msg("[ResetPassword] failed clear suspend amount")
+		return failure.InternalError(err)
+	}
+	return s.LogoutAll(ctx, user.Id)
+}
+
+// ChangePassword replaces the password of the calling user after checking the
+// current one. Other sessions of the user are revoked when requested. Wrong current
+// passwords count as failed logins, so a stolen access token cannot be used to
+// guess the password without limit.
+func (s *UserServiceImpl) ChangePassword(ctx context.Context, primaryID uuid.UUID, changeRequest dto.UserChangePasswordRequest) error {
+	caller, ok := model.PrincipalFromContext(ctx)
+	if !ok {
+		return failure.Unauthorized("Missing authenticated user")
+	}
+	if caller.UserID != primaryID {
+		return failure.Forbidden("Not allowed to change the password of this user")
+	}
+
+	user, err := s.UserRepository.ResolveUserByID(ctx, primaryID)
+	if err != nil {
+		if failure.GetCode(err) != http.StatusNotFound {
+			log.Error().Err(err).Msg("[ChangePassword] failed get user by id")
+		}
+		return err
+	}
+	attempt, err := s.GetLoginAttempt(ctx, user.Email)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Error().Err(err).Msg("[ChangePassword] failed get login attempt")
+		return failure.InternalError(err)
+	}
+	if attempt >= s.cfg.Internal.MaxLoginAttempt {
+		return failure.Forbidden("Max password attempts exceeded, please wait before trying again")
+	}
+	match, err := user.ComparePassword(changeRequest.CurrentPassword)
+	if err != nil {
+		log.Error().Err(err).Msg("[ChangePassword] failed compare password")
+		return failure.InternalError(err)
+	}
+	if !match {
+		if err = s.SetLoginAttempt(ctx, user.Email, attempt+1, s.cfg.Internal.LoginAttemptTTL); err != nil {
+			log.Error().Err(err).Msg("[ChangePassword] failed count password attempt")
+			return failure.InternalError(err)
+		}
+		return failure.BadRequestFromString("Current password is incorrect")
+	}
+	if reasons := shared.GetPasswordPolicy().Violations(changeRequest.NewPassword, user.Email, user.Fullname); len(reasons) > 0 {
+		return failure.ValidationFailed(map[string][]string{"newPassword": reasons})
+	}
+
+	hashed, err := crypt.HashPassword(changeRequest.NewPassword)
+	if err != nil {
+		log.Error().Err(err).Msg("[ChangePassword] failed hash password")
+		return failure.InternalError(err)
+	}
+	if err = s.updatePassword(ctx, user.Id, hashed, caller.UserID); err != nil {
+		log.Error().Err(err).Msg("[ChangePassword] failed update user password")
+		return err
+	}
+
+	if changeRequest.RevokeOtherSessions {
+		if err = s.revokeOtherSessions(ctx, user.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updatePassword stores a new password hash on behalf of updatedBy.
+func (s *UserServiceImpl) updatePassword(ctx context.Context, primaryID uuid.UUID, hashed string, updatedBy uuid.UUID) error {
+	selectField := repository.NewUserSelectFields()
+	return s.UserRepository.UpdateUser(ctx, primaryID, repository.UserUpdateFieldList{
+		repository.NewUserUpdateField(selectField.Password(), hashed),
+		repository.NewUserUpdateField(selectField.UpdatedAt(), time.Now()),
+		repository.NewUserUpdateField(selectField.UpdatedBy(), updatedBy.String()),
+	})
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/internal/domain/user/repository"
+	"github.com/IlhamRobyana/user/shared/crypt"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/notifier"
+	"github.com/IlhamRobyana/user/shared/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var linkTokenPattern = regexp.MustCompile(`token=([^\s"&]+)`)
+
+// linkToken returns the token of the link in a message.
+func linkToken(t *testing.T, message notifier.Message) string {
+	match := linkTokenPattern.FindStringSubmatch(message.Text)
+	if !assert.Len(t, match, 2) {
+		return ""
+	}
+	linkToken, err := url.QueryUnescape(match[1])
+	assert.NoError(t, err)
+	return linkToken
+}
+
+func TestForgotPassword(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Registered email", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
+
+		assert.NoError(t, s.ForgotPassword(ctx, dto.UserForgotPasswordRequest{Email: user.Email}))
+		message := waitForMessage(t, s.messages, user.Email)
+		userID, err := s.GetPasswordResetToken(ctx, crypt.HashSHA256(linkToken(t, message)))
+		assert.NoError(t, err)
+		assert.Equal(t, user.Id, userID)
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		s := newTestUserService(t)
+		s.userRepo.On("ResolveUserByEmail", ctx, "nobody@example.com", mock.Anything).Return(model.User{}, failure.NotFound("user"))
+
+		assert.NoError(t, s.ForgotPassword(ctx, dto.UserForgotPasswordRequest{Email: "nobody@example.com"}))
+	})
+
+	t.Run("Failures do not tell registered emails apart", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
+		s.Notifications = stalledNotifications(s)
+		assert.NoError(t, s.ForgotPassword(ctx, dto.UserForgotPasswordRequest{Email: user.Email}))
+
+		s.redis.SetError("connection refused")
+		assert.NoError(t, s.ForgotPassword(ctx, dto.UserForgotPasswordRequest{Email: user.Email}))
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		assert.NoError(t, s.SetPasswordResetToken(ctx, crypt.HashSHA256("reset-token"), user.Id, time.Minute))
+		assert.NoError(t, s.SetLoginAttempt(ctx, user.Email, 2, time.Minute))
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.userRepo.On("UpdateUser", ctx, user.Id, mock.AnythingOfType("repository.UserUpdateFieldList")).Return(nil)
+		sessionID := uuid.New()
+		s.expectSessions(ctx, user.Id, sessionID)
+
+		err := s.ResetPassword(ctx, dto.UserResetPasswordRequest{Token: "reset-token", Password: "N3wStr0ngPassword"})
+		assert.NoError(t, err)
+
+		s.userRepo.AssertCalled(t, "UpdateUser", ctx, user.Id, mock.MatchedBy(func(updateFields repository.UserUpdateFieldList) bool {
+			return len(updateFields) == 3
+		}))
+		assert.False(t, s.redis.Exists("user:login:attempt:"+user.Email))
+		revoked, err := s.IsRevokedSession(ctx, sessionID)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+		s.sessionRepo.AssertExpectations(t)
+
+		// the token is used up
+		err = s.ResetPassword(ctx, dto.UserResetPasswordRequest{Token: "reset-token", Password: "N3wStr0ngPassword"})
+		assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+	})
+
+	t.Run("Weak password keeps the token", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		assert.NoError(t, s.SetPasswordResetToken(ctx, crypt.HashSHA256("reset-token"), user.Id, time.Minute))
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+
+		err := s.ResetPassword(ctx, dto.UserResetPasswordRequest{Token: "reset-token", Password: "short"})
+		assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+		_, err = s.GetPasswordResetToken(ctx, crypt.HashSHA256("reset-token"))
+		assert.NoError(t, err)
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	// callerContext returns the context of a request of the user from the session
+	callerContext := func(user model.User, sessionID uuid.UUID) context.Context {
+		ctx := model.NewPrincipalContext(context.Background(), model.Principal{UserID: user.Id, Role: user.Role, SessionID: sessionID})
+		return token.NewContext(ctx, token.NewClaims("user-test", user.Id, string(user.Status), sessionID, time.Minute))
+	}
+
+	t.Run("Revokes other sessions", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		currentSessionID, otherSessionID := uuid.New(), uuid.New()
+		ctx := callerContext(user, currentSessionID)
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.userRepo.On("UpdateUser", ctx, user.Id, mock.AnythingOfType("repository.UserUpdateFieldList")).Return(nil)
+		s.sessionRepo.On("ResolveSessionsByUserID", ctx, user.Id).Return(model.SessionList{
+			{Id: currentSessionID, UserId: user.Id},
+			{Id: otherSessionID, UserId: user.Id},
+		}, nil)
+		s.sessionRepo.On("DeleteSessionByID", ctx, otherSessionID).Return(nil)
+
+		err := s.ChangePassword(ctx, user.Id, dto.UserChangePasswordRequest{
+			CurrentPassword:     "Str0ngPassword",
+			NewPassword:         "N3wStr0ngPassword",
+			RevokeOtherSessions: true,
+		})
+		assert.NoError(t, err)
+
+		revoked, err := s.IsRevokedSession(ctx, otherSessionID)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+		revoked, err = s.IsRevokedSession(ctx, currentSessionID)
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+		s.sessionRepo.AssertNotCalled(t, "DeleteSessionByID", ctx, currentSessionID)
+		s.sessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Keeps other sessions", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		ctx := callerContext(user, uuid.New())
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.userRepo.On("UpdateUser", ctx, user.Id, mock.AnythingOfType("repository.UserUpdateFieldList")).Return(nil)
+
+		err := s.ChangePassword(ctx, user.Id, dto.UserChangePasswordRequest{CurrentPassword: "Str0ngPassword", NewPassword: "N3wStr0ngPassword"})
+		assert.NoError(t, err)
+		s.sessionRepo.AssertNotCalled(t, "ResolveSessionsByUserID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Wrong current password", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		ctx := callerContext(user, uuid.New())
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+
+		err := s.ChangePassword(ctx, user.Id, dto.UserChangePasswordRequest{CurrentPassword: "WrongPassword1", NewPassword: "N3wStr0ngPassword"})
+		assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+		s.userRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything)
+
+		attempt, err := s.GetLoginAttempt(ctx, user.Email)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempt)
+	})
+
+	t.Run("Guessing is limited", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		ctx := callerContext(user, uuid.New())
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+
+		for i := 0; i < s.cfg.Internal.MaxLoginAttempt; i++ {
+			err := s.ChangePassword(ctx, user.Id, dto.UserChangePasswordRequest{CurrentPassword: "WrongPassword1", NewPassword: "N3wStr0ngPassword"})
+			assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+		}
+
+		// even the right password is refused until the counter expires
+		err := s.ChangePassword(ctx, user.Id, dto.UserChangePasswordRequest{CurrentPassword: "Str0ngPassword", NewPassword: "N3wStr0ngPassword"})
+		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
+		s.userRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything)
+
+		// and so are logins
+		_, err = s.LoginUser(context.Background(), dto.UserLoginRequest{Email: user.Email, Password: "Str0ngPassword"})
+		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
+	})
+
+	t.Run("Other users", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		ctx := callerContext(user, uuid.New())
+
+		err := s.ChangePassword(ctx, uuid.New(), dto.UserChangePasswordRequest{CurrentPassword: "Str0ngPassword", NewPassword: "N3wStr0ngPassword"})
+		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
+	})
+}
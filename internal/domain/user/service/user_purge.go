@@ -0,0 +1,72 @@
+package service
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"time"
+
+	"github.com/IlhamRobyana/user/shared/scheduler"
+)
+
+const (
+	// jobPurgeDeletedUsers is the name of the job erasing soft deleted users.
+	jobPurgeDeletedUsers = "user.purge_deleted"
+	// defaultPurgeBatchSize is the number of users erased per batch when none is
+	// configured.
+	defaultPurgeBatchSize = 500
+)
+
+// registerJobs registers the periodic jobs of the user domain. The purge of soft
+// deleted users only runs once both its interval and retention are configured.
+func (s *UserServiceImpl) registerJobs() {
+	purge := s.cfg.Scheduler.PurgeDeletedUsers
+	if purge.Interval <= 0 || purge.Retention <= 0 {
+		log.Warn().Msg("[registerJobs] purge of deleted users is not configured, skipping")
+		return
+	}
+	s.jobs.Register(scheduler.Job{
+		Name:     jobPurgeDeletedUsers,
+		Interval: purge.Interval,
+		Run:      s.purgeDeletedUsers,
+	})
+}
+
+// purgeDeletedUsers permanently erases users soft deleted longer than the
+// retention window ago, batch by batch until none are left.
+func (s *UserServiceImpl) purgeDeletedUsers(ctx context.Context) error {
+	var (
+		started       = time.Now()
+		deletedBefore = started.Add(-s.cfg.Scheduler.PurgeDeletedUsers.Retention)
+		batchSize     = s.cfg.Scheduler.PurgeDeletedUsers.BatchSize
+		purged        int64
+		batches       int
+	)
+	if batchSize <= 0 {
+		batchSize = defaultPurgeBatchSize
+	}
+
+	for ctx.Err() == nil {
+		count, err := s.UserRepository.PurgeDeletedUsers(ctx, deletedBefore, batchSize)
+		if err != nil {
+			log.Error().Err(err).Int64("purged", purged).Int("batches", batches).Msg("[purgeDeletedUsers] failed purge deleted users")
+			return err
+		}
+		if count > 0 {
+			purged += count
+			batches++
+		}
+		if count < int64(batchSize) {
+			break
+		}
+	}
+
+	log.Info().
+		Int64("purged", purged).
+		Int("batches", batches).
+		Time("deletedBefore", deletedBefore).
+		Dur("took", time.Since(started)).
+		Bool("interrupted", ctx.Err() != nil).
+		Msg("[purgeDeletedUsers] purged deleted users")
+	return ctx.Err()
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	ctx := context.Background()
+
+	// deletedBeforeRetention matches a cutoff one retention window before now
+	deletedBeforeRetention := func(s testUserService) interface{} {
+		return mock.MatchedBy(func(deletedBefore time.Time) bool {
+			return time.Since(deletedBefore.Add(s.cfg.Scheduler.PurgeDeletedUsers.Retention)) < time.Minute
+		})
+	}
+
+	t.Run("Purges batch by batch", func(t *testing.T) {
+		s := newTestUserService(t)
+		s.cfg.Scheduler.PurgeDeletedUsers.Retention = 30 * 24 * time.Hour
+		s.cfg.Scheduler.PurgeDeletedUsers.BatchSize = 2
+		s.userRepo.On("PurgeDeletedUsers", ctx, deletedBeforeRetention(s), 2).Return(int64(2), nil).Twice()
+		s.userRepo.On("PurgeDeletedUsers", ctx, deletedBeforeRetention(s), 2).Return(int64(1), nil).Once()
+
+		assert.NoError(t, s.purgeDeletedUsers(ctx))
+		s.userRepo.AssertExpectations(t)
+	})
+
+	t.Run("Default batch size", func(t *testing.T) {
+		s := newTestUserService(t)
+		s.cfg.Scheduler.PurgeDeletedUsers.Retention = 30 * 24 * time.Hour
+		s.userRepo.On("PurgeDeletedUsers", ctx, deletedBeforeRetention(s), defaultPurgeBatchSize).Return(int64(0), nil).Once()
+
+		assert.NoError(t, s.purgeDeletedUsers(ctx))
+		s.userRepo.AssertExpectations(t)
+	})
+
+	t.Run("Stops at the first failure", func(t *testing.T) {
+		s := newTestUserService(t)
+		s.cfg.Scheduler.PurgeDeletedUsers.Retention = 30 * 24 * time.Hour
+		s.cfg.Scheduler.PurgeDeletedUsers.BatchSize = 2
+		s.userRepo.On("PurgeDeletedUsers", ctx, mock.Anything, 2).Return(int64(0), errors.New("database error")).Once()
+
+		assert.Error(t, s.purgeDeletedUsers(ctx))
+		s.userRepo.AssertNumberOfCalls(t, "PurgeDeletedUsers", 1)
+	})
+
+	t.Run("Stops when cancelled", func(t *testing.T) {
+		s := newTestUserService(t)
+		s.cfg.Scheduler.PurgeDeletedUsers.Retention = 30 * 24 * time.Hour
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		assert.ErrorIs(t, s.purgeDeletedUsers(cancelled), context.Canceled)
+		s.userRepo.AssertNotCalled(t, "PurgeDeletedUsers", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"context"
+
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/token"
+)
+
+// Logout revokes the session of the access token described by claims, along with
+// every access and refresh token issued for it.
+func (s *UserServiceImpl) Logout(ctx context.Context, claims token.Claims) error {
+	return s.revokeSession(ctx, claims.SessionID)
+}
+
+// LogoutAll revokes every session of the user along with the access and refresh
+// tokens issued for them. Sessions started afterwards, such as a login right
+// after, are not affected.
+func (s *UserServiceImpl) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	sessions, err := s.SessionRepository.ResolveSessionsByUserID(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("[LogoutAll] failed get sessions")
+		return err
+	}
+	for _, session := range sessions {
+		if err = s.revokeSession(ctx, session.Id); err != nil {
+			return err
+		}
+	}
+	if err = s.DeleteUserRefreshTokens(ctx, userID); err != nil {
+		log.Error().Err(err).Msg("[LogoutAll] failed delete refresh tokens")
+		return failure.InternalError(err)
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether the session of the token was revoked.
+func (s *UserServiceImpl) IsAccessTokenRevoked(ctx context.Context, claims token.Claims) (bool, error) {
+	revokedSession, err := s.IsRevokedSession(ctx, claims.SessionID)
+	if err != nil {
+		log.Error().Err(err).Msg("[IsAccessTokenRevoked] failed check revoked session")
+		return false, failure.InternalError(err)
+	}
+	return revokedSession, nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/crypt"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLogoutAll(t *testing.T) {
+	ctx := context.Background()
+	s := newTestUserService(t)
+	user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+	s.sessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
+
+	loginResponse, err := s.startSession(ctx, user, dto.ClientInfo{})
+	assert.NoError(t, err)
+	claims, err := token.Parse(loginResponse.AccessToken, s.cfg.Auth.AccessToken.Secret)
+	assert.NoError(t, err)
+	s.expectSessions(ctx, user.Id, claims.SessionID)
+
+	assert.NoError(t, s.LogoutAll(ctx, user.Id))
+	revoked, err := s.IsAccessTokenRevoked(ctx, claims)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	_, err = s.GetRefreshToken(ctx, crypt.HashSHA256(loginResponse.RefreshToken))
+	assert.Error(t, err)
+
+	// logging in again within the same second is not affected
+	loginResponse, err = s.startSession(ctx, user, dto.ClientInfo{})
+	assert.NoError(t, err)
+	claims, err = token.Parse(loginResponse.AccessToken, s.cfg.Auth.AccessToken.Secret)
+	assert.NoError(t, err)
+	revoked, err = s.IsAccessTokenRevoked(ctx, claims)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	s := newTestUserService(t)
+	user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+	s.sessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
+
+	loginResponse, err := s.startSession(ctx, user, dto.ClientInfo{})
+	assert.NoError(t, err)
+	claims, err := token.Parse(loginResponse.AccessToken, s.cfg.Auth.AccessToken.Secret)
+	assert.NoError(t, err)
+	// an access token issued earlier in the session, before a refresh
+	earlierAccessToken, _, err := s.issueAccessToken(user, claims.SessionID)
+	assert.NoError(t, err)
+	earlierClaims, err := token.Parse(earlierAccessToken, s.cfg.Auth.AccessToken.Secret)
+	assert.NoError(t, err)
+	s.sessionRepo.On("DeleteSessionByID", ctx, claims.SessionID).Return(nil)
+
+	assert.NoError(t, s.Logout(ctx, claims))
+	for _, claims := range []token.Claims{claims, earlierClaims} {
+		revoked, err := s.IsAccessTokenRevoked(ctx, claims)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	}
+	_, err = s.RefreshToken(ctx, dto.UserRefreshTokenRequest{RefreshToken: loginResponse.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, failure.GetCode(err))
+	s.sessionRepo.AssertExpectations(t)
+}
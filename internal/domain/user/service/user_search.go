@@ -0,0 +1,38 @@
+package service
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"context"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/search"
+)
+
+// SearchUsers returns the users best matching a partial name or email, with the
+// matching fragments highlighted. Emails are matched regardless of case.
+func (s *UserServiceImpl) SearchUsers(ctx context.Context, searchRequest dto.UserSearchRequest) ([]dto.UserSearchResult, error) {
+	terms := search.Terms(searchRequest.Query)
+	results, err := s.UserRepository.SearchUsers(ctx, terms, searchRequest.Limit)
+	if err != nil {
+		log.Error().Err(err).Msg("[SearchUsers] failed search users")
+		return nil, err
+	}
+
+	searchResults := make([]dto.UserSearchResult, 0, len(results))
+	for _, result := range results {
+		highlights := make(map[dto.UserDTOFieldNameType]string)
+		if email, ok := search.Highlight(result.Email, terms); ok {
+			highlights[dto.UserDTOFieldName.Email] = email
+		}
+		if fullname, ok := search.Highlight(result.Fullname, terms); ok {
+			highlights[dto.UserDTOFieldName.Fullname] = fullname
+		}
+		searchResults = append(searchResults, dto.UserSearchResult{
+			UserResponse: dto.NewUserResponse(result.User),
+			Score:        result.Score,
+			Highlights:   highlights,
+		})
+	}
+	return searchResults, nil
+}
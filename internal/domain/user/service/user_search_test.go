@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/internal/domain/user/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchUsers(t *testing.T) {
+	ctx := context.Background()
+	s := newTestUserService(t)
+	users := model.UserList{
+		{Id: uuid.New(), Email: "jane.doe@example.com", Fullname: "Jane Doe"},
+		{Id: uuid.New(), Email: "janet@example.com", Fullname: "Janet <Smith>"},
+		{Id: uuid.New(), Email: "john@example.com", Fullname: "John Doe"},
+	}
+	terms := []string{"jane"}
+	s.userRepo.On("SearchUsers", ctx, terms, 10).Return(repository.SearchUserList(users, terms, 10), nil)
+
+	results, err := s.SearchUsers(ctx, dto.UserSearchRequest{Query: "  JANE ", Limit: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, users[0].Id, results[0].Id)
+		assert.Equal(t, "<mark>jane</mark>.doe@example.com", results[0].Highlights[dto.UserDTOFieldName.Email])
+		assert.Equal(t, "<mark>Jane</mark> Doe", results[0].Highlights[dto.UserDTOFieldName.Fullname])
+		assert.Equal(t, users[1].Id, results[1].Id)
+		assert.Equal(t, "<mark>Jane</mark>t &lt;Smith&gt;", results[1].Highlights[dto.UserDTOFieldName.Fullname])
+		assert.Greater(t, results[0].Score, results[1].Score)
+	}
+	s.userRepo.AssertExpectations(t)
+}
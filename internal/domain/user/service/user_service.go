@@ -8,10 +8,14 @@ import (
 
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/IlhamRobyana/user/internal/domain/user/model"
 	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/internal/domain/user/repository"
+	"github.com/IlhamRobyana/user/shared/crypt"
 	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/token"
 )
 
 func (s *UserServiceImpl) CreateUser(ctx context.Context, userRequest dto.UserCreateRequest) (dto.UserResponse, error) {
@@ -25,11 +29,20 @@ func (s *UserServiceImpl) CreateUser(ctx context.Context, userRequest dto.UserCr
 		log.Error().Err(err).Msg("[CreateUser] failed create user")
 		return dto.UserResponse{}, err
 	}
+	// the user is created either way, a lost email can be requested again
+	if err = s.sendVerificationEmail(ctx, user); err != nil {
+		log.Warn().Err(err).Msg("[CreateUser] failed send verification email")
+	}
 	return dto.NewUserResponse(user), nil
 }
 
-func (s *UserServiceImpl) ResolveUserByID(ctx context.Context, primaryID uuid.UUID) (dto.UserResponse, error) {
-	user, err := s.UserRepository.ResolveUserByID(ctx, primaryID)
+// ResolveUserByID returns a user to the user themself or a staff member. Given a
+// sparse fieldset, only those fields are fetched and the others are left empty.
+func (s *UserServiceImpl) ResolveUserByID(ctx context.Context, primaryID uuid.UUID, fields ...dto.UserDTOFieldNameType) (dto.UserResponse, error) {
+	if err := s.authorizeUserAccess(ctx, primaryID); err != nil {
+		return dto.UserResponse{}, err
+	}
+	user, err := s.UserRepository.ResolveUserByID(ctx, primaryID, userSelectFields(fields)...)
 	if err != nil {
 		if failure.GetCode(err) != http.StatusNotFound {
 			log.Error().Err(err).Msg("[ResolveUserByID] failed get user by id")
@@ -39,12 +52,80 @@ func (s *UserServiceImpl) ResolveUserByID(ctx context.Context, primaryID uuid.UU
 	return dto.NewUserResponse(user), nil
 }
 
-func (s *UserServiceImpl) LoginUser(ctx context.Context, userRequest dto.UserLoginRequest) (bool, error) {
+// userSelectFields returns the columns of a sparse fieldset along with the
+// required ones, or every column but the password when the fieldset is empty.
+func userSelectFields(fields []dto.UserDTOFieldNameType, required ...repository.UserField) []repository.UserField {
+	if len(fields) == 0 {
+		return repository.NewUserSelectFields().Public()
+	}
+	var selectFields []repository.UserField
+	seen := make(map[repository.UserField]bool)
+	for _, column := range dto.UserDBFieldNames(fields) {
+		required = append(required, repository.UserField(column))
+	}
+	for _, field := range required {
+		if !seen[field] {
+			seen[field] = true
+			selectFields = append(selectFields, field)
+		}
+	}
+	return selectFields
+}
+
+// UpdateUser applies a merge patch to a user. Users may patch their own profile
+// and staff members any profile, while roles may only be changed by admins.
+func (s *UserServiceImpl) UpdateUser(ctx context.Context, primaryID uuid.UUID, patchRequest dto.UserPatchRequest) (dto.UserResponse, error) {
+	if err := s.authorizeUserAccess(ctx, primaryID); err != nil {
+		return dto.UserResponse{}, err
+	}
+	caller, _ := model.PrincipalFromContext(ctx)
+	if patchRequest.Role != nil && caller.Role != model.RoleAdmin {
+		return dto.UserResponse{}, failure.Forbidden("Not allowed to change the role of a user")
+	}
+
+	user, err := s.UserRepository.ResolveUserByID(ctx, primaryID)
+	if err != nil {
+		if failure.GetCode(err) != http.StatusNotFound {
+			log.Error().Err(err).Msg("[UpdateUser] failed get user by id")
+		}
+		return dto.UserResponse{}, err
+	}
+
+	selectField := repository.NewUserSelectFields()
+	var updateFields repository.UserUpdateFieldList
+	if patchRequest.Fullname != nil && *patchRequest.Fullname != user.Fullname {
+		user.Fullname = *patchRequest.Fullname
+		updateFields = append(updateFields, repository.NewUserUpdateField(selectField.Fullname(), user.Fullname))
+	}
+	if patchRequest.Role != nil && *patchRequest.Role != user.Role {
+		user.Role = *patchRequest.Role
+		updateFields = append(updateFields, repository.NewUserUpdateField(selectField.Role(), user.Role))
+	}
+	// a patch that changes nothing leaves the user untouched
+	if len(updateFields) == 0 {
+		return dto.NewUserResponse(user), nil
+	}
+
+	user.UpdatedAt = time.Now()
+	user.UpdatedBy = caller.UserID.String()
+	updateFields = append(updateFields,
+		repository.NewUserUpdateField(selectField.UpdatedAt(), user.UpdatedAt),
+		repository.NewUserUpdateField(selectField.UpdatedBy(), user.UpdatedBy),
+	)
+	err = s.UserRepository.UpdateUser(ctx, user.Id, updateFields)
+	if err != nil {
+		log.Error().Err(err).Msg("[UpdateUser] failed update user")
+		return dto.UserResponse{}, err
+	}
+	return dto.NewUserResponse(user), nil
+}
+
+func (s *UserServiceImpl) LoginUser(ctx context.Context, userRequest dto.UserLoginRequest) (dto.UserLoginResponse, error) {
 	attempt, err := s.GetLoginAttempt(ctx, userRequest.Email)
 	if err != nil {
 		if err.Error() != redis.Nil.Error() {
 			log.Error().Err(err).Msg("[LoginUser] failed get login attempt")
-			return false, err
+			return dto.UserLoginResponse{}, err
 		}
 		s.SetLoginAttempt(ctx, userRequest.Email, 0, s.cfg.Internal.LoginAttemptTTL)
 	}
@@ -52,7 +133,7 @@ func (s *UserServiceImpl) LoginUser(ctx context.Context, userRequest dto.UserLog
 	if attempt >= s.cfg.Internal.MaxLoginAttempt {
 		err = failure.Forbidden("Max login attempts exceeded, please wait for 2 minutes for re-login")
 		log.Error().Err(err).Msg("[LoginUser] failed login user")
-		return false, err
+		return dto.UserLoginResponse{}, err
 	}
 
 	var (
@@ -74,14 +155,28 @@ func (s *UserServiceImpl) LoginUser(ctx context.Context, userRequest dto.UserLog
 					s.SetSuspendAmount(ctx, userRequest.Email, 0, s.cfg.Internal.SuspendAmountTTL)
 				}
 				s.SetSuspendAmount(ctx, userRequest.Email, suspendAmount+1, s.cfg.Internal.SuspendAmountTTL)
+				// only active accounts are locked and told about it, unknown
+				// emails and unverified addresses have no one to notify
+				if user.Id == uuid.Nil || !user.Status.CanTransitionTo(model.StatusLocked, model.ActorKindSystem) {
+					return
+				}
 				if suspendAmount+1 >= s.cfg.Internal.MaxSuspendAmount {
-					err = s.UserRepository.UpdateUserStatus(ctx, user.Id, model.Inactive)
+					err = s.transitionUserStatus(ctx, user, model.StatusLocked, model.SystemActor(), model.ReasonRepeatedLockouts)
 					if err != nil {
-						log.Error().Err(err).Msg("[LoginUser] failed update user status")
+						log.Error().Err(err).Msg("[LoginUser] failed lock user")
 						return
 					}
 					return
 				}
+				now := time.Now()
+				s.publishEvent(model.TopicUserLockedOut, model.UserLockedOutEvent{
+					UserId:      user.Id,
+					Email:       user.Email,
+					Fullname:    user.Fullname,
+					Reason:      model.ReasonFailedLoginAttempts,
+					LockedUntil: now.Add(s.cfg.Internal.LoginAttemptTTL),
+					OccurredAt:  now,
+				})
 				return
 			}
 		}
@@ -91,21 +186,101 @@ func (s *UserServiceImpl) LoginUser(ctx context.Context, userRequest dto.UserLog
 		if failure.GetCode(err) != http.StatusNotFound {
 			log.Error().Err(err).Msg("[LoginUser] failed login user")
 		}
-		return false, err
+		return dto.UserLoginResponse{}, err
 	}
 	isPasswordMatch, err = user.ComparePassword(userRequest.Password)
 	if err != nil {
 		log.Error().Err(err).Msg("[LoginUser] failed compare password")
 		err = failure.Unauthorized("Invalid email or password")
-		return false, err
+		return dto.UserLoginResponse{}, err
+	}
+	if !isPasswordMatch {
+		return dto.UserLoginResponse{}, failure.Unauthorized("Invalid email or password")
+	}
+	if user.Status == model.StatusPendingVerification {
+		return dto.UserLoginResponse{}, failure.WithErrorCode(failure.Forbidden("Email is not verified"), ErrCodeEmailNotVerified)
+	}
+	if !user.Status.CanLogin() {
+		return dto.UserLoginResponse{}, failure.Forbidden("User is not active")
+	}
+	if user.PasswordNeedsRehash() {
+		s.rehashPassword(ctx, user, userRequest.Password)
+	}
+
+	mfaEnabled, errMFA := s.isMFAEnabled(ctx, user.Id)
+	if errMFA != nil {
+		log.Error().Err(errMFA).Msg("[LoginUser] failed check mfa")
+		return dto.UserLoginResponse{}, errMFA
+	}
+	if mfaEnabled {
+		return s.startMFAChallenge(ctx, user)
 	}
 
-	return isPasswordMatch, nil
+	loginResponse, errSession := s.startSession(ctx, user, userRequest.Client)
+	if errSession != nil {
+		return dto.UserLoginResponse{}, errSession
+	}
+	return loginResponse, nil
+}
+
+// rehashPassword replaces an outdated password hash. Failing to do so does not
+// affect the login, the old hash stays valid.
+func (s *UserServiceImpl) rehashPassword(ctx context.Context, user model.User, password string) {
+	hashed, err := crypt.HashPassword(password)
+	if err != nil {
+		log.Warn().Err(err).Msg("[rehashPassword] failed hash password")
+		return
+	}
+	selectField := repository.NewUserSelectFields()
+	err = s.UserRepository.UpdateUser(ctx, user.Id, repository.UserUpdateFieldList{
+		repository.NewUserUpdateField(selectField.Password(), hashed),
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("[rehashPassword] failed update user password")
+	}
 }
 
 type UserService interface {
 	CreateUser(ctx context.Context, userRequest dto.UserCreateRequest) (dto.UserResponse, error)
-	ResolveUserByID(ctx context.Context, primaryID uuid.UUID) (dto.UserResponse, error)
+	ResolveUserByID(ctx context.Context, primaryID uuid.UUID, fields ...dto.UserDTOFieldNameType) (dto.UserResponse, error)
+	ResolveUsers(ctx context.Context, listRequest dto.UserListRequest) ([]dto.UserResponse, dto.UserListMetadata, error)
+	SearchUsers(ctx context.Context, searchRequest dto.UserSearchRequest) ([]dto.UserSearchResult, error)
+	ExportUsers(ctx context.Context, exportRequest dto.UserExportRequest, fn func(userResponse dto.UserResponse) error) error
+	ImportUsers(ctx context.Context, rows []dto.UserImportRow, dryRun bool) (dto.UserImportReport, error)
+	UpdateUser(ctx context.Context, primaryID uuid.UUID, patchRequest dto.UserPatchRequest) (dto.UserResponse, error)
+
+	LoginUser(ctx context.Context, userRequest dto.UserLoginRequest) (dto.UserLoginResponse, error)
+	RequestMagicLink(ctx context.Context, magicLinkRequest dto.UserMagicLinkRequest) error
+	LoginMagicLink(ctx context.Context, loginRequest dto.UserMagicLinkLoginRequest) (dto.UserLoginResponse, error)
+	RefreshToken(ctx context.Context, refreshRequest dto.UserRefreshTokenRequest) (dto.UserLoginResponse, error)
+	Logout(ctx context.Context, claims token.Claims) error
+	LogoutAll(ctx context.Context, userID uuid.UUID) error
+	IsAccessTokenRevoked(ctx context.Context, claims token.Claims) (bool, error)
+	ActivateUser(ctx context.Context, primaryID uuid.UUID, statusRequest dto.UserStatusChangeRequest) error
+	SuspendUser(ctx context.Context, primaryID uuid.UUID, statusRequest dto.UserStatusChangeRequest) error
+	DeactivateUser(ctx context.Context, primaryID uuid.UUID, statusRequest dto.UserStatusChangeRequest) error
+	DeleteUser(ctx context.Context, primaryID uuid.UUID) error
+	RestoreUser(ctx context.Context, primaryID uuid.UUID, statusRequest dto.UserStatusChangeRequest) error
+
+	ForgotPassword(ctx context.Context, forgotRequest dto.UserForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, resetRequest dto.UserResetPasswordRequest) error
+	ChangePassword(ctx context.Context, primaryID uuid.UUID, changeRequest dto.UserChangePasswordRequest) error
+
+	EnrollTOTP(ctx context.Context, primaryID uuid.UUID) (dto.UserMFAEnrollResponse, error)
+	ConfirmTOTP(ctx context.Context, primaryID uuid.UUID, confirmRequest dto.UserMFAConfirmRequest) (dto.UserMFARecoveryCodesResponse, error)
+	DisableTOTP(ctx context.Context, primaryID uuid.UUID, disableRequest dto.UserMFADisableRequest) error
+	LoginMFA(ctx context.Context, loginRequest dto.UserLoginMFARequest) (dto.UserLoginResponse, error)
+
+	BeginWebAuthnRegistration(ctx context.Context, primaryID uuid.UUID) (dto.WebAuthnCeremonyResponse, error)
+	FinishWebAuthnRegistration(ctx context.Context, primaryID uuid.UUID, registrationRequest dto.WebAuthnRegistrationRequest) (dto.WebAuthnCredentialResponse, error)
+	BeginWebAuthnLogin(ctx context.Context) (dto.WebAuthnCeremonyResponse, error)
+	FinishWebAuthnLogin(ctx context.Context, loginRequest dto.WebAuthnLoginRequest) (dto.UserLoginResponse, error)
+	ResolveWebAuthnCredentialsByUserID(ctx context.Context, userID uuid.UUID) ([]dto.WebAuthnCredentialResponse, error)
+	DeleteWebAuthnCredential(ctx context.Context, userID uuid.UUID, credentialID uuid.UUID) error
+
+	VerifyEmail(ctx context.Context, verifyRequest dto.UserVerifyEmailRequest) error
+	ResendVerificationEmail(ctx context.Context, resendRequest dto.UserResendVerificationRequest) error
 
-	LoginUser(ctx context.Context, userRequest dto.UserLoginRequest) (bool, error)
+	ResolveSessionsByUserID(ctx context.Context, userID uuid.UUID) ([]dto.SessionResponse, error)
+	DeleteSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error
 }
@@ -1,4 +1,4 @@
-package service_test
+package service
 
 import (
 	"context"
@@ -11,8 +11,11 @@ import (
 	"github.com/IlhamRobyana/user/internal/domain/user/model"
 	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
 	"github.com/IlhamRobyana/user/internal/domain/user/repository"
-	"github.com/IlhamRobyana/user/internal/domain/user/service"
+	"github.com/IlhamRobyana/user/shared"
+	"github.com/IlhamRobyana/user/shared/crypt"
 	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/notifier"
+	"github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -29,6 +32,21 @@ func (m *MockUserRepository) CreateUser(ctx context.Context, user *model.User, f
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) CreateUsers(ctx context.Context, users []model.User) error {
+	args := m.Called(ctx, users)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ResolveUsedEmails(ctx context.Context, emails []string) ([]string, error) {
+	args := m.Called(ctx, emails)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockUserRepository) StreamUsers(ctx context.Context, filter repository.UserFilter, sort repository.UserSort, fn func(user model.User) error, selectFields ...repository.UserField) error {
+	args := m.Called(ctx, filter, sort, fn, selectFields)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) ResolveUserByID(ctx context.Context, userID uuid.UUID, selectFields ...repository.UserField) (model.User, error) {
 	args := m.Called(ctx, userID, selectFields)
 	return args.Get(0).(model.User), args.Error(1)
@@ -44,320 +62,547 @@ func (m *MockUserRepository) IsExistUserByID(ctx context.Context, userID uuid.UU
 	return args.Bool(0), args.Error(1)
 }
 
-type MockRedisClient struct {
+func (m *MockUserRepository) ResolveUserByIDIncludingDeleted(ctx context.Context, userID uuid.UUID, selectFields ...repository.UserField) (model.User, error) {
+	args := m.Called(ctx, userID, selectFields)
+	return args.Get(0).(model.User), args.Error(1)
+}
+
+func (m *MockUserRepository) ResolveUserByEmailIncludingDeleted(ctx context.Context, email string, selectFields ...repository.UserField) (model.User, error) {
+	args := m.Called(ctx, email, selectFields)
+	return args.Get(0).(model.User), args.Error(1)
+}
+
+func (m *MockUserRepository) IsExistUserByIDIncludingDeleted(ctx context.Context, userID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) ResolveUsers(ctx context.Context, listQuery repository.UserListQuery, selectFields ...repository.UserField) (model.UserList, *repository.UserCursor, error) {
+	args := m.Called(ctx, listQuery, selectFields)
+	return args.Get(0).(model.UserList), args.Get(1).(*repository.UserCursor), args.Error(2)
+}
+
+func (m *MockUserRepository) CountUsers(ctx context.Context, filter repository.UserFilter) (int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRepository) SearchUsers(ctx context.Context, terms []string, limit int) ([]repository.UserSearchResult, error) {
+	args := m.Called(ctx, terms, limit)
+	return args.Get(0).([]repository.UserSearchResult), args.Error(1)
+}
+
+func (m *MockUserRepository) ChangeUserStatus(ctx context.Context, primaryID uuid.UUID, from model.UserStatus, to model.UserStatus, auditLog model.AuditLog) error {
+	args := m.Called(ctx, primaryID, from, to, auditLog)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateUser(ctx context.Context, primaryID uuid.UUID, updateFields repository.UserUpdateFieldList) error {
+	args := m.Called(ctx, primaryID, updateFields)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, limit int) (int64, error) {
+	args := m.Called(ctx, deletedBefore, limit)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockSessionRepository struct {
 	mock.Mock
 }
 
-func (m *MockRedisClient) Get(ctx context.Context, key string) *redis.StringCmd {
-	args := m.Called(ctx, key)
-	return args.Get(0).(*redis.StringCmd)
+func (m *MockSessionRepository) CreateSession(ctx context.Context, session *model.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
 }
 
-func (m *MockRedisClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
-	args := m.Called(ctx, key, value, expiration)
-	return args.Get(0).(*redis.StatusCmd)
+func (m *MockSessionRepository) ResolveSessionByID(ctx context.Context, sessionID uuid.UUID) (model.Session, error) {
+	args := m.Called(ctx, sessionID)
+	return args.Get(0).(model.Session), args.Error(1)
 }
 
-func TestCreateUser(t *testing.T) {
-	// Setup
-	mockRepo := new(MockUserRepository)
-	mockRedis := new(MockRedisClient)
-
-	cfg := &configs.Config{
-		Internal: configs.Internal{
-			MaxLoginAttempt: 3,
-			LoginAttemptTTL: time.Minute * 2,
-		},
+func (m *MockSessionRepository) ResolveSessionsByUserID(ctx context.Context, userID uuid.UUID) (model.SessionList, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(model.SessionList), args.Error(1)
+}
+
+func (m *MockSessionRepository) UpdateSessionLastSeen(ctx context.Context, sessionID uuid.UUID, lastSeenAt time.Time) error {
+	args := m.Called(ctx, sessionID, lastSeenAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) DeleteSessionByID(ctx context.Context, sessionID uuid.UUID) error {
+	args := m.Called(ctx, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) DeleteSessionsByUserID(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+type MockMFARepository struct {
+	mock.Mock
+}
+
+func (m *MockMFARepository) ResolveMFAByUserID(ctx context.Context, userID uuid.UUID) (model.MFA, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(model.MFA), args.Error(1)
+}
+
+func (m *MockMFARepository) UpsertMFA(ctx context.Context, mfa *model.MFA) error {
+	args := m.Called(ctx, mfa)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) EnableMFA(ctx context.Context, userID uuid.UUID, recoveryCodes model.RecoveryCodeList) error {
+	args := m.Called(ctx, userID, recoveryCodes)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) DeleteMFA(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+type MockWebAuthnRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebAuthnRepository) CreateWebAuthnCredential(ctx context.Context, credential *model.WebAuthnCredential) error {
+	args := m.Called(ctx, credential)
+	return args.Error(0)
+}
+
+func (m *MockWebAuthnRepository) ResolveWebAuthnCredentialsByUserID(ctx context.Context, userID uuid.UUID) (model.WebAuthnCredentialList, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(model.WebAuthnCredentialList), args.Error(1)
+}
+
+func (m *MockWebAuthnRepository) UpdateWebAuthnCredentialUsage(ctx context.Context, id uuid.UUID, signCount uint32, lastUsedAt time.Time) error {
+	args := m.Called(ctx, id, signCount, lastUsedAt)
+	return args.Error(0)
+}
+
+func (m *MockWebAuthnRepository) DeleteWebAuthnCredential(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+// testUserService is a UserServiceImpl backed by mock repositories, an in-memory
+// Redis and a notifier keeping the messages it sends.
+type testUserService struct {
+	*UserServiceImpl
+	userRepo     *MockUserRepository
+	sessionRepo  *MockSessionRepository
+	mfaRepo      *MockMFARepository
+	webAuthnRepo *MockWebAuthnRepository
+	redis        *miniredis.Miniredis
+	messages     *notifier.MemoryNotifier
+}
+
+func newTestUserService(t *testing.T) testUserService {
+	crypt.SetPasswordHasher(crypt.NewPasswordHasher(
+		crypt.Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		crypt.Bcrypt{Cost: 4},
+	))
+	t.Cleanup(func() {
+		crypt.SetPasswordHasher(crypt.NewPasswordHasher(crypt.DefaultArgon2id(), crypt.DefaultBcrypt()))
+	})
+
+	cfg := &configs.Config{}
+	cfg.Internal.MaxLoginAttempt = 3
+	cfg.Internal.LoginAttemptTTL = 2 * time.Minute
+	cfg.Internal.MaxSuspendAmount = 3
+	cfg.Internal.SuspendAmountTTL = time.Hour
+	cfg.Auth.Issuer = "user-test"
+	cfg.Auth.AccessToken.Secret = "access-token-secret"
+	cfg.Auth.AccessToken.TTL = 15 * time.Minute
+	cfg.Auth.RefreshToken.TTL = 24 * time.Hour
+	cfg.Auth.PasswordReset.TTL = 15 * time.Minute
+	cfg.Auth.PasswordReset.URL = "http://localhost/reset-password"
+	cfg.Auth.EmailVerification.Secret = "email-verification-secret"
+	cfg.Auth.EmailVerification.TTL = time.Hour
+	cfg.Auth.EmailVerification.URL = "http://localhost/verify-email"
+	cfg.Auth.MagicLink.Secret = "magic-link-secret"
+	cfg.Auth.MagicLink.TTL = 15 * time.Minute
+	cfg.Auth.MagicLink.URL = "http://localhost/magic-link"
+	cfg.Auth.MFA.ChallengeTTL = 5 * time.Minute
+	cfg.Auth.MFA.MaxChallengeAttempts = 3
+	cfg.Auth.MFA.EncryptionKey = "mfa-encryption-key"
+	cfg.Auth.WebAuthn.RPID = "localhost"
+	cfg.Auth.WebAuthn.RPDisplayName = "User Test"
+	cfg.Auth.WebAuthn.RPOrigins = []string{"http://localhost"}
+	cfg.Auth.WebAuthn.CeremonyTTL = 5 * time.Minute
+	cfg.Notifier.DefaultLocale = "en"
+
+	redisServer := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	t.Cleanup(func() { cache.Close() })
+
+	events := shared.New(1, shared.SetMessageBuffer(100))
+	messages := notifier.NewMemoryNotifier()
+	notifications := notifier.ProvideDispatcher(cfg, events, notifier.ProvideTemplates(cfg), messages)
+
+	s := testUserService{
+		userRepo:     new(MockUserRepository),
+		sessionRepo:  new(MockSessionRepository),
+		mfaRepo:      new(MockMFARepository),
+		webAuthnRepo: new(MockWebAuthnRepository),
+		redis:        redisServer,
+		messages:     messages,
 	}
+	s.UserServiceImpl = &UserServiceImpl{
+		UserRepository:     s.userRepo,
+		SessionRepository:  s.sessionRepo,
+		MFARepository:      s.mfaRepo,
+		WebAuthnRepository: s.webAuthnRepo,
+		Notifications:      notifications,
+		events:             events,
+		cfg:                cfg,
+		cache:              cache,
+	}
+	s.subscribeEvents()
+	events.Start()
+	return s
+}
 
-	userService := &service.UserServiceImpl{
-		UserRepository: mockRepo,
-		cfg:            cfg,
-		cache:          mockRedis,
+// stalledNotifications returns a dispatcher whose queue is always full, as when
+// the notifier stopped sending.
+func stalledNotifications(s testUserService) *notifier.Dispatcher {
+	return notifier.ProvideDispatcher(s.cfg, shared.New(1), notifier.ProvideTemplates(s.cfg), s.messages)
+}
+
+// expectSessions makes the session repository hold sessions of the user with the
+// given ids, which may be revoked.
+func (s testUserService) expectSessions(ctx context.Context, userID uuid.UUID, sessionIDs ...uuid.UUID) {
+	sessions := model.SessionList{}
+	for _, sessionID := range sessionIDs {
+		sessions = append(sessions, &model.Session{Id: sessionID, UserId: userID})
+		s.sessionRepo.On("DeleteSessionByID", ctx, sessionID).Return(nil)
 	}
+	s.sessionRepo.On("ResolveSessionsByUserID", ctx, userID).Return(sessions, nil)
+}
+
+// newTestUser returns an active user with the given password.
+func newTestUser(t *testing.T, email, password string) model.User {
+	hashed, err := crypt.HashPassword(password)
+	assert.NoError(t, err)
+	return model.User{
+		Id:       uuid.New(),
+		Email:    email,
+		Password: hashed,
+		Fullname: "Test User",
+		Status:   model.StatusActive,
+		Role:     model.RoleUser,
+	}
+}
 
+// waitForMessage waits for the notifier to send a message to the address.
+func waitForMessage(t *testing.T, messages *notifier.MemoryNotifier, to string) notifier.Message {
+	assert.Eventually(t, func() bool {
+		_, ok := messages.Last(to)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	message, _ := messages.Last(to)
+	return message
+}
+
+func TestCreateUser(t *testing.T) {
 	ctx := context.Background()
-	testID := uuid.New()
 
-	// Test case 1: Successful user creation
 	t.Run("Success", func(t *testing.T) {
-		// Input
+		s := newTestUserService(t)
 		createRequest := dto.UserCreateRequest{
 			Email:    "test@example.com",
-			Password: "password123",
+			Password: "Str0ngPassword",
 			Fullname: "Test User",
 		}
+		s.userRepo.On("CreateUser", ctx, mock.AnythingOfType("*model.User"), mock.Anything).Return(nil)
 
-		// Expected model after conversion
-		expectedUser := model.User{
-			Id:        testID,
-			Email:     "test@example.com",
-			Password:  "hashed_password", // This would be the hashed password
-			Fullname:  "Test User",
-			CreatedBy: testID.String(),
-			UpdatedBy: testID.String(),
-		}
-
-		// Mock behavior
-		mockRepo.On("CreateUser", ctx, mock.AnythingOfType("*model.User")).Return(nil)
-
-		// Execute
-		response, err := userService.CreateUser(ctx, createRequest)
+		response, err := s.CreateUser(ctx, createRequest)
+		assert.NoError(t, err)
+		assert.Equal(t, "test@example.com", response.Email)
+		assert.Equal(t, string(model.StatusPendingVerification), response.Status)
 
-		// Assert
+		created := s.userRepo.Calls[0].Arguments.Get(1).(*model.User)
+		match, err := created.ComparePassword("Str0ngPassword")
 		assert.NoError(t, err)
-		assert.NotEmpty(t, response)
-		mockRepo.AssertExpectations(t)
+		assert.True(t, match)
+
+		message := waitForMessage(t, s.messages, "test@example.com")
+		assert.Contains(t, message.Text, "http://localhost/verify-email?token=")
+		s.userRepo.AssertExpectations(t)
 	})
 
-	// Test case 2: Repository error
 	t.Run("RepositoryError", func(t *testing.T) {
-		// Input
+		s := newTestUserService(t)
 		createRequest := dto.UserCreateRequest{
 			Email:    "test@example.com",
-			Password: "password123",
+			Password: "Str0ngPassword",
 			Fullname: "Test User",
 		}
+		s.userRepo.On("CreateUser", ctx, mock.AnythingOfType("*model.User"), mock.Anything).Return(errors.New("database error"))
 
-		// Mock behavior
-		mockRepo.On("CreateUser", ctx, mock.AnythingOfType("*model.User")).Return(errors.New("database error"))
-
-		// Execute
-		response, err := userService.CreateUser(ctx, createRequest)
-
-		// Assert
+		response, err := s.CreateUser(ctx, createRequest)
 		assert.Error(t, err)
 		assert.Equal(t, dto.UserResponse{}, response)
-		mockRepo.AssertExpectations(t)
+		s.userRepo.AssertExpectations(t)
 	})
 }
 
 func TestResolveUserByID(t *testing.T) {
-	// Setup
-	mockRepo := new(MockUserRepository)
-	mockRedis := new(MockRedisClient)
-
-	cfg := &configs.Config{
-		Internal: configs.Internal{
-			MaxLoginAttempt: 3,
-			LoginAttemptTTL: time.Minute * 2,
-		},
-	}
-
-	userService := &service.UserServiceImpl{
-		UserRepository: mockRepo,
-		cfg:            cfg,
-		cache:          mockRedis,
-	}
-
-	ctx := context.Background()
+	s := newTestUserService(t)
 	testID := uuid.New()
+	ctx := model.NewPrincipalContext(context.Background(), model.Principal{UserID: testID, Role: model.RoleUser})
 
-	// Test case 1: User found
 	t.Run("UserFound", func(t *testing.T) {
-		// Expected user
 		expectedUser := model.User{
 			Id:       testID,
 			Email:    "test@example.com",
-			Password: "hashed_password",
 			Fullname: "Test User",
 		}
+		s.userRepo.On("ResolveUserByID", ctx, testID, mock.Anything).Return(expectedUser, nil).Once()
 
-		// Mock behavior
-		mockRepo.On("ResolveUserByID", ctx, testID, mock.Anything).Return(expectedUser, nil)
-
-		// Execute
-		response, err := userService.ResolveUserByID(ctx, testID)
-
-		// Assert
+		response, err := s.ResolveUserByID(ctx, testID)
 		assert.NoError(t, err)
 		assert.Equal(t, testID, response.Id)
 		assert.Equal(t, "test@example.com", response.Email)
 		assert.Equal(t, "Test User", response.Fullname)
-		mockRepo.AssertExpectations(t)
+		s.userRepo.AssertExpectations(t)
 	})
 
-	// Test case 2: User not found
 	t.Run("UserNotFound", func(t *testing.T) {
-		// Mock behavior
-		notFoundErr := failure.NotFound("user with id not found")
-		mockRepo.On("ResolveUserByID", ctx, testID, mock.Anything).Return(model.User{}, notFoundErr)
-
-		// Execute
-		response, err := userService.ResolveUserByID(ctx, testID)
+		s.userRepo.On("ResolveUserByID", ctx, testID, mock.Anything).Return(model.User{}, failure.NotFound("user")).Once()
 
-		// Assert
-		assert.Error(t, err)
+		response, err := s.ResolveUserByID(ctx, testID)
+		assert.Equal(t, http.StatusNotFound, failure.GetCode(err))
 		assert.Equal(t, dto.UserResponse{}, response)
-		mockRepo.AssertExpectations(t)
+		s.userRepo.AssertExpectations(t)
 	})
-}
 
-func TestLoginUser(t *testing.T) {
-	// Setup
-	mockRepo := new(MockUserRepository)
-	mockRedis := new(MockRedisClient)
-
-	cfg := &configs.Config{
-		Internal: configs.Internal{
-			MaxLoginAttempt: 3,
-			LoginAttemptTTL: time.Minute * 2,
-		},
-	}
+	t.Run("SparseFieldset", func(t *testing.T) {
+		s := newTestUserService(t)
+		selectField := repository.NewUserSelectFields()
+		s.userRepo.On("ResolveUserByID", ctx, testID, []repository.UserField{selectField.Email(), selectField.Fullname()}).Return(model.User{
+			Email:    "test@example.com",
+			Fullname: "Test User",
+		}, nil).Once()
 
-	userService := &service.UserServiceImpl{
-		UserRepository: mockRepo,
-		cfg:            cfg,
-		cache:          mockRedis,
-	}
+		response, err := s.ResolveUserByID(ctx, testID, dto.UserDTOFieldName.Email, dto.UserDTOFieldName.Fullname, dto.UserDTOFieldName.Email)
+		assert.NoError(t, err)
+		assert.Equal(t, "test@example.com", response.Email)
+		s.userRepo.AssertExpectations(t)
+	})
 
-	ctx := context.Background()
+	t.Run("EmptyFieldsetNeverReadsThePassword", func(t *testing.T) {
+		s := newTestUserService(t)
+		s.userRepo.On("ResolveUserByID", ctx, testID, []repository.UserField(repository.NewUserSelectFields().Public())).Return(model.User{Id: testID}, nil).Once()
 
-	// Test case 1: Successful login
-	t.Run("SuccessfulLogin", func(t *testing.T) {
-		// Input
-		loginRequest := dto.UserLoginRequest{
-			Email:    "test@example.com",
-			Password: "password123",
-		}
+		_, err := s.ResolveUserByID(ctx, testID)
+		assert.NoError(t, err)
+		assert.NotContains(t, repository.NewUserSelectFields().Public(), repository.NewUserSelectFields().Password())
+		s.userRepo.AssertExpectations(t)
+	})
 
-		// Mock user from repository
-		user := model.User{
-			Id:       uuid.New(),
-			Email:    "test@example.com",
-			Password: "hashed_password", // This would be the hashed password
-			Fullname: "Test User",
-		}
+	t.Run("StaffResolveOtherUsers", func(t *testing.T) {
+		s := newTestUserService(t)
+		staff := model.NewPrincipalContext(context.Background(), model.Principal{UserID: uuid.New(), Role: model.RoleSupport})
+		s.userRepo.On("ResolveUserByID", staff, testID, mock.Anything).Return(model.User{Id: testID}, nil).Once()
 
-		// Mock GetLoginAttempt behavior - no previous attempts
-		nilRedisErr := redis.NewStringResult("", redis.Nil)
-		mockRedis.On("Get", ctx, "login_attempt:test@example.com").Return(nilRedisErr)
+		_, err := s.ResolveUserByID(staff, testID)
+		assert.NoError(t, err)
+		s.userRepo.AssertExpectations(t)
+	})
 
-		// Mock SetLoginAttempt behavior - initial setup with 0 attempts
-		statusCmd := redis.NewStatusResult("OK", nil)
-		mockRedis.On("Set", ctx, "login_attempt:test@example.com", "0", cfg.Internal.LoginAttemptTTL).Return(statusCmd)
+	t.Run("UsersCannotResolveOthers", func(t *testing.T) {
+		s := newTestUserService(t)
+		other := model.NewPrincipalContext(context.Background(), model.Principal{UserID: uuid.New(), Role: model.RoleUser})
 
-		// Mock ResolveUserByEmail behavior
-		mockRepo.On("ResolveUserByEmail", ctx, "test@example.com").Return(user, nil)
+		_, err := s.ResolveUserByID(other, testID)
+		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
+		s.userRepo.AssertNotCalled(t, "ResolveUserByID", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
 
-		// We need to mock the password comparison
-		// Since we can't mock the ComparePassword method directly (it's on model.User),
-		// we'll need to use a custom implementation or mock strategy
+func TestLoginUser(t *testing.T) {
+	ctx := context.Background()
 
-		// Execute with mock password comparison
-		isPasswordMatch, err := userService.LoginUser(ctx, loginRequest)
+	t.Run("SuccessfulLogin", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "test@example.com", "password123")
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
+		s.mfaRepo.On("ResolveMFAByUserID", ctx, user.Id).Return(model.MFA{}, failure.NotFound("mfa"))
+		s.sessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
 
-		// Assert
+		loginResponse, err := s.LoginUser(ctx, dto.UserLoginRequest{Email: user.Email, Password: "password123"})
 		assert.NoError(t, err)
-		assert.True(t, isPasswordMatch)
-		mockRepo.AssertExpectations(t)
-		mockRedis.AssertExpectations(t)
-	})
-
-	// Test case 2: User not found
-	t.Run("UserNotFound", func(t *testing.T) {
-		// Input
-		loginRequest := dto.UserLoginRequest{
-			Email:    "nonexistent@example.com",
-			Password: "password123",
-		}
+		assert.NotEmpty(t, loginResponse.AccessToken)
+		assert.NotEmpty(t, loginResponse.RefreshToken)
 
-		// Mock GetLoginAttempt behavior - no previous attempts
-		nilRedisErr := redis.NewStringResult("", redis.Nil)
-		mockRedis.On("Get", ctx, "login_attempt:nonexistent@example.com").Return(nilRedisErr)
+		attempt, err := s.GetLoginAttempt(ctx, user.Email)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, attempt)
+		s.userRepo.AssertExpectations(t)
+		s.sessionRepo.AssertExpectations(t)
+	})
 
-		// Mock SetLoginAttempt behavior - initial setup with 0 attempts
-		statusCmd := redis.NewStatusResult("OK", nil)
-		mockRedis.On("Set", ctx, "login_attempt:nonexistent@example.com", "0", cfg.Internal.LoginAttemptTTL).Return(statusCmd)
+	t.Run("RehashesOutdatedPassword", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "test@example.com", "password123")
+		hashed, err := crypt.Bcrypt{Cost: 4}.Hash("password123")
+		assert.NoError(t, err)
+		user.Password = hashed
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
+		s.userRepo.On("UpdateUser", ctx, user.Id, mock.MatchedBy(func(updateFields repository.UserUpdateFieldList) bool {
+			return len(updateFields) == 1
+		})).Return(nil)
+		s.mfaRepo.On("ResolveMFAByUserID", ctx, user.Id).Return(model.MFA{}, failure.NotFound("mfa"))
+		s.sessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
+
+		loginResponse, err := s.LoginUser(ctx, dto.UserLoginRequest{Email: user.Email, Password: "password123"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, loginResponse.AccessToken)
+		s.userRepo.AssertExpectations(t)
+	})
 
-		// Mock ResolveUserByEmail behavior - user not found
-		notFoundErr := failure.NotFound("user not found")
-		mockRepo.On("ResolveUserByEmail", ctx, "nonexistent@example.com").Return(model.User{}, notFoundErr)
+	t.Run("CurrentHashIsKept", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "test@example.com", "password123")
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
+		s.mfaRepo.On("ResolveMFAByUserID", ctx, user.Id).Return(model.MFA{}, failure.NotFound("mfa"))
+		s.sessionRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
 
-		// Mock SetLoginAttempt behavior - increment after failed login
-		mockRedis.On("Set", ctx, "login_attempt:nonexistent@example.com", "1", cfg.Internal.LoginAttemptTTL).Return(statusCmd)
+		_, err := s.LoginUser(ctx, dto.UserLoginRequest{Email: user.Email, Password: "password123"})
+		assert.NoError(t, err)
+		s.userRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything)
+	})
 
-		// Execute
-		isPasswordMatch, err := userService.LoginUser(ctx, loginRequest)
+	t.Run("UserNotFound", func(t *testing.T) {
+		s := newTestUserService(t)
+		s.userRepo.On("ResolveUserByEmail", ctx, "nonexistent@example.com", mock.Anything).Return(model.User{}, failure.NotFound("user"))
 
-		// Assert
+		loginResponse, err := s.LoginUser(ctx, dto.UserLoginRequest{Email: "nonexistent@example.com", Password: "password123"})
 		assert.Error(t, err)
-		assert.False(t, isPasswordMatch)
-		mockRepo.AssertExpectations(t)
-		mockRedis.AssertExpectations(t)
+		assert.Empty(t, loginResponse.AccessToken)
+
+		attempt, err := s.GetLoginAttempt(ctx, "nonexistent@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempt)
 	})
 
-	// Test case 3: Max login attempts exceeded
 	t.Run("MaxLoginAttemptsExceeded", func(t *testing.T) {
-		// Input
-		loginRequest := dto.UserLoginRequest{
-			Email:    "test@example.com",
-			Password: "password123",
-		}
+		s := newTestUserService(t)
+		assert.NoError(t, s.SetLoginAttempt(ctx, "test@example.com", 3, time.Minute))
+
+		loginResponse, err := s.LoginUser(ctx, dto.UserLoginRequest{Email: "test@example.com", Password: "password123"})
+		assert.Empty(t, loginResponse.AccessToken)
+		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
+		s.userRepo.AssertNotCalled(t, "ResolveUserByEmail", mock.Anything, mock.Anything, mock.Anything)
+	})
 
-		// Mock GetLoginAttempt behavior - max attempts reached
-		stringCmd := redis.NewStringResult("3", nil) // 3 attempts already made
-		mockRedis.On("Get", ctx, "login_attempt:test@example.com").Return(stringCmd)
+	t.Run("InvalidPassword", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "test@example.com", "password123")
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
 
-		// Execute
-		isPasswordMatch, err := userService.LoginUser(ctx, loginRequest)
+		loginResponse, err := s.LoginUser(ctx, dto.UserLoginRequest{Email: user.Email, Password: "wrong_password"})
+		assert.Empty(t, loginResponse.AccessToken)
+		assert.Equal(t, http.StatusUnauthorized, failure.GetCode(err))
 
-		// Assert
+		attempt, err := s.GetLoginAttempt(ctx, user.Email)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempt)
+	})
+	t.Run("RepeatedLockoutsLockTheAccount", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "test@example.com", "password123")
+		sessionID := uuid.New()
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
+		s.userRepo.On("ChangeUserStatus", ctx, user.Id, model.StatusActive, model.StatusLocked, mock.MatchedBy(func(auditLog model.AuditLog) bool {
+			return auditLog.Action == model.AuditActionLock && auditLog.Reason == model.ReasonRepeatedLockouts && auditLog.Actor == model.ActorSystem
+		})).Return(nil)
+		s.expectSessions(ctx, user.Id, sessionID)
+		assert.NoError(t, s.SetLoginAttempt(ctx, user.Email, s.cfg.Internal.MaxLoginAttempt-1, time.Minute))
+		assert.NoError(t, s.SetSuspendAmount(ctx, user.Email, s.cfg.Internal.MaxSuspendAmount-1, time.Minute))
+
+		_, err := s.LoginUser(ctx, dto.UserLoginRequest{Email: user.Email, Password: "wrong_password"})
 		assert.Error(t, err)
-		assert.False(t, isPasswordMatch)
+		s.userRepo.AssertExpectations(t)
+		revoked, err := s.IsRevokedSession(ctx, sessionID)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("LockedAccount", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "test@example.com", "password123")
+		user.Status = model.StatusLocked
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
+
+		loginResponse, err := s.LoginUser(ctx, dto.UserLoginRequest{Email: user.Email, Password: "password123"})
+		assert.Empty(t, loginResponse.AccessToken)
 		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
-		mockRedis.AssertExpectations(t)
+		s.sessionRepo.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
 	})
+}
 
-	// Test case 4: Invalid password
-	t.Run("InvalidPassword", func(t *testing.T) {
-		// Input
-		loginRequest := dto.UserLoginRequest{
-			Email:    "test@example.com",
-			Password: "wrong_password",
-		}
+func TestUpdateUser(t *testing.T) {
+	fullname, role := "Jane Roe", model.RoleSupport
 
-		// Mock user from repository
-		user := model.User{
-			Id:       uuid.New(),
-			Email:    "test@example.com",
-			Password: "hashed_password", // This would be the hashed password
-			Fullname: "Test User",
-		}
+	t.Run("Owner patches their fullname", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		ctx := model.NewPrincipalContext(context.Background(), model.Principal{UserID: user.Id, Role: user.Role})
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.userRepo.On("UpdateUser", ctx, user.Id, mock.MatchedBy(func(updateFields repository.UserUpdateFieldList) bool {
+			return len(updateFields) == 3
+		})).Return(nil)
 
-		// Mock GetLoginAttempt behavior - no previous attempts
-		nilRedisErr := redis.NewStringResult("", redis.Nil)
-		mockRedis.On("Get", ctx, "login_attempt:test@example.com").Return(nilRedisErr)
+		response, err := s.UpdateUser(ctx, user.Id, dto.UserPatchRequest{Fullname: &fullname})
+		assert.NoError(t, err)
+		assert.Equal(t, fullname, response.Fullname)
+		s.userRepo.AssertExpectations(t)
+	})
 
-		// Mock SetLoginAttempt behavior - initial setup with 0 attempts
-		statusCmd := redis.NewStatusResult("OK", nil)
-		mockRedis.On("Set", ctx, "login_attempt:test@example.com", "0", cfg.Internal.LoginAttemptTTL).Return(statusCmd)
+	t.Run("Patch changing nothing", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		ctx := model.NewPrincipalContext(context.Background(), model.Principal{UserID: user.Id, Role: user.Role})
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
 
-		// Mock ResolveUserByEmail behavior
-		mockRepo.On("ResolveUserByEmail", ctx, "test@example.com").Return(user, nil)
+		_, err := s.UpdateUser(ctx, user.Id, dto.UserPatchRequest{Fullname: &user.Fullname})
+		assert.NoError(t, err)
+		s.userRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything)
+	})
 
-		// Mock SetLoginAttempt behavior - increment after failed login
-		mockRedis.On("Set", ctx, "login_attempt:test@example.com", "1", cfg.Internal.LoginAttemptTTL).Return(statusCmd)
+	t.Run("Only admins change roles", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		ctx := model.NewPrincipalContext(context.Background(), model.Principal{UserID: uuid.New(), Role: model.RoleSupport})
 
-		// Execute with mock password comparison (returning false)
-		isPasswordMatch, err := userService.LoginUser(ctx, loginRequest)
+		_, err := s.UpdateUser(ctx, user.Id, dto.UserPatchRequest{Role: &role})
+		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
 
-		// Assert
-		assert.Error(t, err)
-		assert.False(t, isPasswordMatch)
-		assert.Equal(t, http.StatusUnauthorized, failure.GetCode(err))
-		mockRepo.AssertExpectations(t)
-		mockRedis.AssertExpectations(t)
+		admin := model.NewPrincipalContext(context.Background(), model.Principal{UserID: uuid.New(), Role: model.RoleAdmin})
+		s.userRepo.On("ResolveUserByID", admin, user.Id, mock.Anything).Return(user, nil)
+		s.userRepo.On("UpdateUser", admin, user.Id, mock.AnythingOfType("repository.UserUpdateFieldList")).Return(nil)
+		response, err := s.UpdateUser(admin, user.Id, dto.UserPatchRequest{Role: &role})
+		assert.NoError(t, err)
+		assert.Equal(t, role, response.Role)
 	})
-}
 
-// Mock implementations for the cache methods
-func (s *MockUserServiceImpl) GetLoginAttempt(ctx context.Context, email string) (int, error) {
-	args := s.Called(ctx, email)
-	return args.Int(0), args.Error(1)
-}
+	t.Run("Users cannot patch others", func(t *testing.T) {
+		s := newTestUserService(t)
+		ctx := model.NewPrincipalContext(context.Background(), model.Principal{UserID: uuid.New(), Role: model.RoleUser})
 
-func (s *MockUserServiceImpl) SetLoginAttempt(ctx context.Context, email string, attempt int, ttl time.Duration) error {
-	args := s.Called(ctx, email, attempt, ttl)
-	return args.Error(0)
+		_, err := s.UpdateUser(ctx, uuid.New(), dto.UserPatchRequest{Fullname: &fullname})
+		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
+		s.userRepo.AssertNotCalled(t, "ResolveUserByID", mock.Anything, mock.Anything, mock.Anything)
+	})
 }
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/token"
+)
+
+func (s *UserServiceImpl) ResolveSessionsByUserID(ctx context.Context, userID uuid.UUID) ([]dto.SessionResponse, error) {
+	if err := s.authorizeUserAccess(ctx, userID); err != nil {
+		return nil, err
+	}
+	sessions, err := s.SessionRepository.ResolveSessionsByUserID(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("[ResolveSessionsByUserID] failed get sessions")
+		return nil, err
+	}
+	claims, _ := token.FromContext(ctx)
+	return dto.NewSessionListResponse(sessions, claims.SessionID), nil
+}
+
+func (s *UserServiceImpl) DeleteSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error {
+	if err := s.authorizeUserAccess(ctx, userID); err != nil {
+		return err
+	}
+	session, err := s.SessionRepository.ResolveSessionByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserId != userID {
+		return failure.NotFound("session not found")
+	}
+	return s.revokeSession(ctx, sessionID)
+}
+
+// startSession records a new session for the user and issues its tokens.
+func (s *UserServiceImpl) startSession(ctx context.Context, user model.User, client dto.ClientInfo) (dto.UserLoginResponse, error) {
+	now := time.Now()
+	session := model.Session{
+		Id:         uuid.New(),
+		UserId:     user.Id,
+		IpAddress:  client.IpAddress,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := s.SessionRepository.CreateSession(ctx, &session); err != nil {
+		log.Error().Err(err).Msg("[startSession] failed create session")
+		return dto.UserLoginResponse{}, err
+	}
+	return s.issueTokens(ctx, user, session.Id)
+}
+
+// revokeSession removes the session and rejects the tokens that were issued for it.
+func (s *UserServiceImpl) revokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	if err := s.DeleteRefreshTokenFamily(ctx, sessionID); err != nil {
+		log.Error().Err(err).Msg("[revokeSession] failed delete refresh tokens")
+		return failure.InternalError(err)
+	}
+	if err := s.SetRevokedSession(ctx, sessionID, s.cfg.Auth.AccessToken.TTL); err != nil {
+		log.Error().Err(err).Msg("[revokeSession] failed revoke access tokens")
+		return failure.InternalError(err)
+	}
+	if err := s.SessionRepository.DeleteSessionByID(ctx, sessionID); err != nil {
+		log.Error().Err(err).Msg("[revokeSession] failed delete session")
+		return err
+	}
+	return nil
+}
+
+// revokeOtherSessions revokes every session of the user but the one of the caller.
+func (s *UserServiceImpl) revokeOtherSessions(ctx context.Context, userID uuid.UUID) error {
+	claims, _ := token.FromContext(ctx)
+	sessions, err := s.SessionRepository.ResolveSessionsByUserID(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("[revokeOtherSessions] failed get sessions")
+		return err
+	}
+	for _, session := range sessions {
+		if session.Id == claims.SessionID {
+			continue
+		}
+		if err = s.revokeSession(ctx, session.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authorizeUserAccess allows the user themself and staff members to act on the
+// account identified by userID.
+func (s *UserServiceImpl) authorizeUserAccess(ctx context.Context, userID uuid.UUID) error {
+	caller, ok := model.PrincipalFromContext(ctx)
+	if !ok {
+		return failure.Unauthorized("Missing authenticated user")
+	}
+	if caller.UserID != userID && !caller.IsStaff() {
+		return failure.Forbidden("Not allowed to access this user")
+	}
+	return nil
+}
+
+// resolveCallingUser returns the calling user, for actions users may only take on
+// their own account.
+func (s *UserServiceImpl) resolveCallingUser(ctx context.Context, primaryID uuid.UUID) (model.User, error) {
+	caller, ok := model.PrincipalFromContext(ctx)
+	if !ok {
+		return model.User{}, failure.Unauthorized("Missing authenticated user")
+	}
+	if caller.UserID != primaryID {
+		return model.User{}, failure.Forbidden("Not allowed to manage this user")
+	}
+	user, err := s.UserRepository.ResolveUserByID(ctx, primaryID)
+	if err != nil {
+		if failure.GetCode(err) != http.StatusNotFound {
+			log.Error().Err(err).Msg("[resolveCallingUser] failed get user by id")
+		}
+		return model.User{}, err
+	}
+	return user, nil
+}
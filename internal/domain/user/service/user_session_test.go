@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSessionsByUserID(t *testing.T) {
+	s := newTestUserService(t)
+	userID, currentSessionID, otherSessionID := uuid.New(), uuid.New(), uuid.New()
+	ctx := model.NewPrincipalContext(context.Background(), model.Principal{UserID: userID, Role: model.RoleUser, SessionID: currentSessionID})
+	ctx = token.NewContext(ctx, token.NewClaims("user-test", userID, string(model.StatusActive), currentSessionID, time.Minute))
+	s.sessionRepo.On("ResolveSessionsByUserID", ctx, userID).Return(model.SessionList{
+		{Id: currentSessionID, UserId: userID},
+		{Id: otherSessionID, UserId: userID},
+	}, nil)
+
+	sessions, err := s.ResolveSessionsByUserID(ctx, userID)
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 2) {
+		assert.True(t, sessions[0].Current)
+		assert.False(t, sessions[1].Current)
+	}
+
+	other := model.NewPrincipalContext(context.Background(), model.Principal{UserID: uuid.New(), Role: model.RoleUser})
+	_, err = s.ResolveSessionsByUserID(other, userID)
+	assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
+}
+
+func TestDeleteSession(t *testing.T) {
+	userID := uuid.New()
+	ctx := model.NewPrincipalContext(context.Background(), model.Principal{UserID: userID, Role: model.RoleUser})
+
+	t.Run("Revokes the session", func(t *testing.T) {
+		s := newTestUserService(t)
+		sessionID := uuid.New()
+		s.sessionRepo.On("ResolveSessionByID", ctx, sessionID).Return(model.Session{Id: sessionID, UserId: userID}, nil)
+		s.sessionRepo.On("DeleteSessionByID", ctx, sessionID).Return(nil)
+
+		assert.NoError(t, s.DeleteSession(ctx, userID, sessionID))
+		revoked, err := s.IsRevokedSession(ctx, sessionID)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+		s.sessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Session of another user", func(t *testing.T) {
+		s := newTestUserService(t)
+		sessionID := uuid.New()
+		s.sessionRepo.On("ResolveSessionByID", ctx, sessionID).Return(model.Session{Id: sessionID, UserId: uuid.New()}, nil)
+
+		err := s.DeleteSession(ctx, userID, sessionID)
+		assert.Equal(t, http.StatusNotFound, failure.GetCode(err))
+		revoked, err := s.IsRevokedSession(ctx, sessionID)
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+	})
+}
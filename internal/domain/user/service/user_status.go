@@ -0,0 +1,148 @@
+package service
+
+import (
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+)
+
+// ActivateUser lets a staff member reactivate an account, lifting any login
+// lockout along with it.
+func (s *UserServiceImpl) ActivateUser(ctx context.Context, primaryID uuid.UUID, statusRequest dto.UserStatusChangeRequest) error {
+	return s.changeUserStatusByStaff(ctx, primaryID, model.StatusActive, statusRequest.Reason)
+}
+
+// SuspendUser lets a staff member suspend an account until it is reactivated.
+// Staff members cannot suspend themselves.
+func (s *UserServiceImpl) SuspendUser(ctx context.Context, primaryID uuid.UUID, statusRequest dto.UserStatusChangeRequest) error {
+	return s.changeUserStatusByStaff(ctx, primaryID, model.StatusSuspended, statusRequest.Reason)
+}
+
+// DeactivateUser lets a staff member deactivate an account. Staff members cannot
+// deactivate themselves.
+func (s *UserServiceImpl) DeactivateUser(ctx context.Context, primaryID uuid.UUID, statusRequest dto.UserStatusChangeRequest) error {
+	return s.changeUserStatusByStaff(ctx, primaryID, model.StatusDeactivated, statusRequest.Reason)
+}
+
+// DeleteUser soft deletes a user and logs it out of every session. Users may
+// delete their own account and staff members the accounts of lower roles.
+func (s *UserServiceImpl) DeleteUser(ctx context.Context, primaryID uuid.UUID) error {
+	if err := s.authorizeUserAccess(ctx, primaryID); err != nil {
+		return err
+	}
+	caller, _ := model.PrincipalFromContext(ctx)
+	actor, reason := model.OwnerActor(caller.UserID), model.ReasonDeletedByOwner
+	if caller.UserID != primaryID {
+		actor, reason = model.StaffActor(caller.UserID), model.ReasonDeletedByStaff
+	}
+
+	user, err := s.UserRepository.ResolveUserByID(ctx, primaryID)
+	if err != nil {
+		if failure.GetCode(err) != http.StatusNotFound {
+			log.Error().Err(err).Msg("[DeleteUser] failed get user by id")
+		}
+		return err
+	}
+	if caller.UserID != primaryID && !model.RoleOutranks(caller.Role, user.Role) {
+		return failure.Forbidden("Not allowed to delete this user")
+	}
+	return s.transitionUserStatus(ctx, user, model.StatusDeleted, actor, reason)
+}
+
+// RestoreUser lets a staff member bring back a soft deleted user in the status it
+// had before it was deleted.
+func (s *UserServiceImpl) RestoreUser(ctx context.Context, primaryID uuid.UUID, statusRequest dto.UserStatusChangeRequest) error {
+	caller, ok := model.PrincipalFromContext(ctx)
+	if !ok {
+		return failure.Unauthorized("Missing authenticated user")
+	}
+	user, err := s.UserRepository.ResolveUserByIDIncludingDeleted(ctx, primaryID)
+	if err != nil {
+		if failure.GetCode(err) != http.StatusNotFound {
+			log.Error().Err(err).Msg("[RestoreUser] failed get user by id")
+		}
+		return err
+	}
+	if user.Status != model.StatusDeleted {
+		return failure.Conflict("restore", "user", fmt.Sprintf("user with id '%s' is not deleted", primaryID))
+	}
+	if err = s.transitionUserStatus(ctx, user, user.RestoreStatus(), model.StaffActor(caller.UserID), statusRequest.Reason); err != nil {
+		return err
+	}
+	s.clearLoginLockout(ctx, user.Email)
+	return nil
+}
+
+// changeUserStatusByStaff moves another user to a status on behalf of the calling
+// staff member and drops the failed login counters of the user.
+func (s *UserServiceImpl) changeUserStatusByStaff(ctx context.Context, primaryID uuid.UUID, to model.UserStatus, reason string) error {
+	caller, ok := model.PrincipalFromContext(ctx)
+	if !ok {
+		return failure.Unauthorized("Missing authenticated user")
+	}
+	if caller.UserID == primaryID {
+		return failure.Forbidden("Not allowed to change the status of yourself")
+	}
+	user, err := s.UserRepository.ResolveUserByID(ctx, primaryID)
+	if err != nil {
+		return err
+	}
+	if err = s.transitionUserStatus(ctx, user, to, model.StaffActor(caller.UserID), reason); err != nil {
+		return err
+	}
+	s.clearLoginLockout(ctx, user.Email)
+	return nil
+}
+
+// transitionUserStatus moves a user to another status when the lifecycle allows
+// the actor to. Users who can no longer log in afterwards are logged out of every
+// session.
+func (s *UserServiceImpl) transitionUserStatus(ctx context.Context, user model.User, to model.UserStatus, actor model.Actor, reason string) error {
+	from := user.Status
+	if !from.CanTransitionTo(to, actor.Kind) {
+		return failure.Conflict("change status", "user", fmt.Sprintf("cannot change status from %s to %s", from, to))
+	}
+
+	auditLog := model.NewAuditLog(user.Id, model.AuditActionForTransition(from, to), reason, actor.Id)
+	err := s.UserRepository.ChangeUserStatus(ctx, user.Id, from, to, auditLog)
+	if err != nil {
+		log.Error().Err(err).Msg("[transitionUserStatus] failed change user status")
+		return err
+	}
+	if !to.CanLogin() {
+		if err = s.LogoutAll(ctx, user.Id); err != nil {
+			log.Error().Err(err).Msg("[transitionUserStatus] failed logout user")
+			return err
+		}
+	}
+
+	s.publishEvent(model.TopicUserStatusChanged, model.UserStatusChangedEvent{
+		UserId:     user.Id,
+		Email:      user.Email,
+		Fullname:   user.Fullname,
+		From:       from,
+		To:         to,
+		Actor:      actor,
+		Reason:     reason,
+		OccurredAt: auditLog.CreatedAt,
+	})
+	return nil
+}
+
+// clearLoginLockout drops the failed login counters of an email. The counters
+// expire on their own, so failing to drop them is only logged.
+func (s *UserServiceImpl) clearLoginLockout(ctx context.Context, email string) {
+	if err := s.DeleteLoginAttempt(ctx, email); err != nil {
+		log.Warn().Err(err).Msg("[clearLoginLockout] failed delete login attempt")
+	}
+	if err := s.DeleteSuspendAmount(ctx, email); err != nil {
+		log.Warn().Err(err).Msg("[clearLoginLockout] failed delete suspend amount")
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/google/uuid"
+	"github.com/guregu/null/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDeleteUser(t *testing.T) {
+	support := model.Principal{UserID: uuid.New(), Role: model.RoleSupport}
+
+	t.Run("Staff deletes a lower role", func(t *testing.T) {
+		s := newTestUserService(t)
+		ctx := model.NewPrincipalContext(context.Background(), support)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.userRepo.On("ChangeUserStatus", ctx, user.Id, model.StatusActive, model.StatusDeleted, mock.MatchedBy(func(auditLog model.AuditLog) bool {
+			return auditLog.Action == model.AuditActionDelete && auditLog.Reason == model.ReasonDeletedByStaff && auditLog.Actor == support.UserID.String()
+		})).Return(nil)
+		s.expectSessions(ctx, user.Id)
+
+		assert.NoError(t, s.DeleteUser(ctx, user.Id))
+		s.userRepo.AssertExpectations(t)
+		s.sessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Staff cannot delete an equal or higher role", func(t *testing.T) {
+		for _, role := range []string{model.RoleSupport, model.RoleAdmin} {
+			s := newTestUserService(t)
+			ctx := model.NewPrincipalContext(context.Background(), support)
+			user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+			user.Role = role
+			s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+
+			err := s.DeleteUser(ctx, user.Id)
+			assert.Equal(t, http.StatusForbidden, failure.GetCode(err), role)
+			s.userRepo.AssertNotCalled(t, "ChangeUserStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("Owner deletes their account", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		user.Role = model.RoleAdmin
+		ctx := model.NewPrincipalContext(context.Background(), model.Principal{UserID: user.Id, Role: user.Role})
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.userRepo.On("ChangeUserStatus", ctx, user.Id, model.StatusActive, model.StatusDeleted, mock.AnythingOfType("model.AuditLog")).Return(nil)
+		s.expectSessions(ctx, user.Id)
+
+		assert.NoError(t, s.DeleteUser(ctx, user.Id))
+		s.userRepo.AssertExpectations(t)
+	})
+
+	t.Run("Users cannot delete others", func(t *testing.T) {
+		s := newTestUserService(t)
+		ctx := model.NewPrincipalContext(context.Background(), model.Principal{UserID: uuid.New(), Role: model.RoleUser})
+
+		err := s.DeleteUser(ctx, uuid.New())
+		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
+	})
+}
+
+func TestRestoreUser(t *testing.T) {
+	admin := model.Principal{UserID: uuid.New(), Role: model.RoleAdmin}
+	ctx := model.NewPrincipalContext(context.Background(), admin)
+
+	t.Run("Restores the status before deletion", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		user.Status = model.StatusDeleted
+		user.StatusBeforeDelete = null.StringFrom(string(model.StatusPendingVerification))
+		s.userRepo.On("ResolveUserByIDIncludingDeleted", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.userRepo.On("ChangeUserStatus", ctx, user.Id, model.StatusDeleted, model.StatusPendingVerification, mock.MatchedBy(func(auditLog model.AuditLog) bool {
+			return auditLog.Action == model.AuditActionRestore && auditLog.Reason == "mistake" && auditLog.Actor == admin.UserID.String()
+		})).Return(nil)
+		// a user that cannot log in afterwards is logged out everywhere
+		s.expectSessions(ctx, user.Id)
+
+		assert.NoError(t, s.RestoreUser(ctx, user.Id, dto.UserStatusChangeRequest{Reason: "mistake"}))
+		s.userRepo.AssertExpectations(t)
+	})
+
+	t.Run("Not deleted", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		s.userRepo.On("ResolveUserByIDIncludingDeleted", ctx, user.Id, mock.Anything).Return(user, nil)
+
+		err := s.RestoreUser(ctx, user.Id, dto.UserStatusChangeRequest{Reason: "mistake"})
+		assert.Equal(t, http.StatusConflict, failure.GetCode(err))
+	})
+}
+
+func TestChangeUserStatusByStaff(t *testing.T) {
+	support := model.Principal{UserID: uuid.New(), Role: model.RoleSupport}
+	ctx := model.NewPrincipalContext(context.Background(), support)
+
+	t.Run("Activate lifts the login lockout", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		user.Status = model.StatusLocked
+		assert.NoError(t, s.SetLoginAttempt(ctx, user.Email, s.cfg.Internal.MaxLoginAttempt, time.Minute))
+		assert.NoError(t, s.SetSuspendAmount(ctx, user.Email, s.cfg.Internal.MaxSuspendAmount, time.Minute))
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.userRepo.On("ChangeUserStatus", ctx, user.Id, model.StatusLocked, model.StatusActive, mock.MatchedBy(func(auditLog model.AuditLog) bool {
+			return auditLog.Action == model.AuditActionActivate && auditLog.Reason == "verified identity" && auditLog.Actor == support.UserID.String()
+		})).Return(nil)
+
+		assert.NoError(t, s.ActivateUser(ctx, user.Id, dto.UserStatusChangeRequest{Reason: "verified identity"}))
+		s.userRepo.AssertExpectations(t)
+		assert.False(t, s.redis.Exists("user:login:attempt:"+user.Email))
+		assert.False(t, s.redis.Exists("user:suspend:amount:"+user.Email))
+		s.sessionRepo.AssertNotCalled(t, "ResolveSessionsByUserID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Deactivate logs the user out", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		sessionID := uuid.New()
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.userRepo.On("ChangeUserStatus", ctx, user.Id, model.StatusActive, model.StatusDeactivated, mock.MatchedBy(func(auditLog model.AuditLog) bool {
+			return auditLog.Action == model.AuditActionDeactivate && auditLog.Reason == "left the company"
+		})).Return(nil)
+		s.expectSessions(ctx, user.Id, sessionID)
+
+		assert.NoError(t, s.DeactivateUser(ctx, user.Id, dto.UserStatusChangeRequest{Reason: "left the company"}))
+		revoked, err := s.IsRevokedSession(ctx, sessionID)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+		s.userRepo.AssertExpectations(t)
+		s.sessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Staff cannot change their own status", func(t *testing.T) {
+		s := newTestUserService(t)
+
+		err := s.SuspendUser(ctx, support.UserID, dto.UserStatusChangeRequest{Reason: "holiday"})
+		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
+		s.userRepo.AssertNotCalled(t, "ResolveUserByID", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Transition not allowed", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		user.Status = model.StatusDeactivated
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+
+		err := s.SuspendUser(ctx, user.Id, dto.UserStatusChangeRequest{Reason: "abuse"})
+		assert.Equal(t, http.StatusConflict, failure.GetCode(err))
+		s.userRepo.AssertNotCalled(t, "ChangeUserStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
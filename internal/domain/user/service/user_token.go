@@ -0,0 +1,109 @@
+package service
+
+import (
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/crypt"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/token"
+)
+
+const refreshTokenSize = 32
+
+func (s *UserServiceImpl) RefreshToken(ctx context.Context, refreshRequest dto.UserRefreshTokenRequest) (dto.UserLoginResponse, error) {
+	tokenHash := crypt.HashSHA256(refreshRequest.RefreshToken)
+	refreshToken, err := s.GetRefreshToken(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return dto.UserLoginResponse{}, failure.Unauthorized("Invalid refresh token")
+		}
+		log.Error().Err(err).Msg("[RefreshToken] failed get refresh token")
+		return dto.UserLoginResponse{}, failure.InternalError(err)
+	}
+
+	rotated, err := s.SetRefreshTokenRotated(ctx, tokenHash, s.cfg.Auth.RefreshToken.TTL)
+	if err != nil {
+		log.Error().Err(err).Msg("[RefreshToken] failed mark refresh token as rotated")
+		return dto.UserLoginResponse{}, failure.InternalError(err)
+	}
+	if !rotated {
+		// the token was already exchanged once, so either the client or an attacker
+		// holds a stolen copy: revoke the session, along with every access and
+		// refresh token issued from the same login
+		log.Warn().Str("familyId", refreshToken.FamilyId.String()).Msg("[RefreshToken] refresh token reuse detected")
+		if err = s.revokeSession(ctx, refreshToken.FamilyId); err != nil {
+			return dto.UserLoginResponse{}, err
+		}
+		return dto.UserLoginResponse{}, failure.Unauthorized("Invalid refresh token")
+	}
+
+	user, err := s.UserRepository.ResolveUserByID(ctx, refreshToken.UserId)
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			return dto.UserLoginResponse{}, failure.Unauthorized("Invalid refresh token")
+		}
+		log.Error().Err(err).Msg("[RefreshToken] failed get user by id")
+		return dto.UserLoginResponse{}, err
+	}
+	if !user.Status.CanLogin() {
+		return dto.UserLoginResponse{}, failure.Forbidden("User is not active")
+	}
+
+	if err = s.SessionRepository.UpdateSessionLastSeen(ctx, refreshToken.FamilyId, time.Now()); err != nil {
+		log.Warn().Err(err).Msg("[RefreshToken] failed update session last seen")
+	}
+	return s.issueTokens(ctx, user, refreshToken.FamilyId)
+}
+
+// issueTokens issues an access token and a refresh token belonging to familyID. The
+// family id doubles as the session id of the access token.
+func (s *UserServiceImpl) issueTokens(ctx context.Context, user model.User, familyID uuid.UUID) (dto.UserLoginResponse, error) {
+	accessToken, accessTokenExpiresAt, err := s.issueAccessToken(user, familyID)
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+	refreshToken, refreshTokenExpiresAt, err := s.issueRefreshToken(ctx, user.Id, familyID)
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+	return dto.UserLoginResponse{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  &accessTokenExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: &refreshTokenExpiresAt,
+	}, nil
+}
+
+func (s *UserServiceImpl) issueAccessToken(user model.User, sessionID uuid.UUID) (string, time.Time, error) {
+	claims := token.NewClaims(s.cfg.Auth.Issuer, user.Id, string(user.Status), sessionID, s.cfg.Auth.AccessToken.TTL)
+	accessToken, err := token.Sign(claims, s.cfg.Auth.AccessToken.Secret)
+	if err != nil {
+		log.Error().Err(err).Msg("[issueAccessToken] failed sign access token")
+		return "", time.Time{}, failure.InternalError(err)
+	}
+	return accessToken, claims.ExpiresAt.Time, nil
+}
+
+func (s *UserServiceImpl) issueRefreshToken(ctx context.Context, userID uuid.UUID, familyID uuid.UUID) (string, time.Time, error) {
+	refreshToken, err := crypt.GenerateToken(refreshTokenSize)
+	if err != nil {
+		log.Error().Err(err).Msg("[issueRefreshToken] failed generate refresh token")
+		return "", time.Time{}, failure.InternalError(err)
+	}
+	ttl := s.cfg.Auth.RefreshToken.TTL
+	err = s.SetRefreshToken(ctx, crypt.HashSHA256(refreshToken), model.RefreshToken{UserId: userID, FamilyId: familyID}, ttl)
+	if err != nil {
+		log.Error().Err(err).Msg("[issueRefreshToken] failed store refresh token")
+		return "", time.Time{}, failure.InternalError(err)
+	}
+	return refreshToken, time.Now().Add(ttl), nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRefreshToken(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Rotates the refresh token", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		familyID := uuid.New()
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.sessionRepo.On("UpdateSessionLastSeen", ctx, familyID, mock.Anything).Return(nil)
+		issued, err := s.issueTokens(ctx, user, familyID)
+		assert.NoError(t, err)
+
+		rotated, err := s.RefreshToken(ctx, dto.UserRefreshTokenRequest{RefreshToken: issued.RefreshToken})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, rotated.AccessToken)
+		assert.NotEqual(t, issued.RefreshToken, rotated.RefreshToken)
+
+		_, err = s.RefreshToken(ctx, dto.UserRefreshTokenRequest{RefreshToken: rotated.RefreshToken})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Reuse revokes the whole family", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		familyID := uuid.New()
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.sessionRepo.On("UpdateSessionLastSeen", ctx, familyID, mock.Anything).Return(nil)
+		s.sessionRepo.On("DeleteSessionByID", ctx, familyID).Return(nil)
+		issued, err := s.issueTokens(ctx, user, familyID)
+		assert.NoError(t, err)
+		rotated, err := s.RefreshToken(ctx, dto.UserRefreshTokenRequest{RefreshToken: issued.RefreshToken})
+		assert.NoError(t, err)
+
+		_, err = s.RefreshToken(ctx, dto.UserRefreshTokenRequest{RefreshToken: issued.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, failure.GetCode(err))
+
+		_, err = s.RefreshToken(ctx, dto.UserRefreshTokenRequest{RefreshToken: rotated.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, failure.GetCode(err))
+
+		// access tokens already issued to the family are cut off too
+		for _, accessToken := range []string{issued.AccessToken, rotated.AccessToken} {
+			claims, err := token.Parse(accessToken, s.cfg.Auth.AccessToken.Secret)
+			assert.NoError(t, err)
+			revoked, err := s.IsAccessTokenRevoked(ctx, claims)
+			assert.NoError(t, err)
+			assert.True(t, revoked)
+		}
+		s.sessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unknown token", func(t *testing.T) {
+		s := newTestUserService(t)
+
+		_, err := s.RefreshToken(ctx, dto.UserRefreshTokenRequest{RefreshToken: "unknown"})
+		assert.Equal(t, http.StatusUnauthorized, failure.GetCode(err))
+	})
+
+	t.Run("User can no longer log in", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		user.Status = model.StatusSuspended
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		issued, err := s.issueTokens(ctx, user, uuid.New())
+		assert.NoError(t, err)
+
+		_, err = s.RefreshToken(ctx, dto.UserRefreshTokenRequest{RefreshToken: issued.RefreshToken})
+		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
+		s.sessionRepo.AssertNotCalled(t, "UpdateSessionLastSeen", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/notifier"
+	"github.com/IlhamRobyana/user/shared/token"
+)
+
+// ErrCodeEmailNotVerified is the error code of logins refused because the email
+// of the user was not verified yet.
+const ErrCodeEmailNotVerified = "EMAIL_NOT_VERIFIED"
+
+// VerifyEmail activates the user a verification token was issued to. Verifying an
+// already verified email succeeds.
+func (s *UserServiceImpl) VerifyEmail(ctx context.Context, verifyRequest dto.UserVerifyEmailRequest) error {
+	claims, err := token.ParseEmail(verifyRequest.Token, s.cfg.Auth.EmailVerification.Secret, token.AudienceEmailVerification)
+	if err != nil {
+		if errors.Is(err, token.ErrExpired) {
+			return failure.BadRequestFromString("Verification token has expired")
+		}
+		return failure.BadRequestFromString("Invalid verification token")
+	}
+
+	user, err := s.UserRepository.ResolveUserByID(ctx, claims.UserID)
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			return failure.BadRequestFromString("Invalid verification token")
+		}
+		log.Error().Err(err).Msg("[VerifyEmail] failed get user by id")
+		return err
+	}
+	// a token sent to a previous address must not verify the current one
+	if user.Email != claims.Email {
+		return failure.BadRequestFromString("Invalid verification token")
+	}
+	switch user.Status {
+	case model.StatusActive:
+		return nil
+	case model.StatusPendingVerification:
+	default:
+		return failure.Forbidden("User is not active")
+	}
+
+	err = s.transitionUserStatus(ctx, user, model.StatusActive, model.OwnerActor(user.Id), "email verified")
+	if err != nil {
+		log.Error().Err(err).Msg("[VerifyEmail] failed activate user")
+		return err
+	}
+	return nil
+}
+
+// ResendVerificationEmail sends a new verification link to a user whose email is
+// not verified yet. Requests for an email are throttled, and it succeeds whether or
+// not the email is registered so it cannot be used to discover accounts: failures
+// past the lookup are only logged, as reporting them would tell a registered
+// email apart.
+func (s *UserServiceImpl) ResendVerificationEmail(ctx context.Context, resendRequest dto.UserResendVerificationRequest) error {
+	allowed, err := s.SetVerificationResent(ctx, resendRequest.Email, s.cfg.Auth.EmailVerification.ResendInterval)
+	if err != nil {
+		log.Error().Err(err).Msg("[ResendVerificationEmail] failed throttle verification email")
+		return failure.InternalError(err)
+	}
+	if !allowed {
+		return failure.TooManyRequests("Verification email was requested recently, please try again later")
+	}
+
+	user, err := s.UserRepository.ResolveUserByEmail(ctx, resendRequest.Email)
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			return nil
+		}
+		log.Error().Err(err).Msg("[ResendVerificationEmail] failed get user by email")
+		return err
+	}
+	if user.Status != model.StatusPendingVerification {
+		return nil
+	}
+	if err = s.sendVerificationEmail(ctx, user); err != nil {
+		log.Error().Err(err).Msg("[ResendVerificationEmail] failed send verification email")
+	}
+	return nil
+}
+
+// sendVerificationEmail emails a signed verification link to the user.
+func (s *UserServiceImpl) sendVerificationEmail(ctx context.Context, user model.User) error {
+	verificationConfig := s.cfg.Auth.EmailVerification
+	claims := token.NewEmailClaims(token.AudienceEmailVerification, s.cfg.Auth.Issuer, user.Id, user.Email, verificationConfig.TTL)
+	verificationToken, err := token.SignEmail(claims, verificationConfig.Secret)
+	if err != nil {
+		return err
+	}
+
+	verificationURL := fmt.Sprintf("%s?token=%s", verificationConfig.URL, url.QueryEscape(verificationToken))
+	return s.Notifications.Dispatch(ctx, notifier.Notification{
+		Template: notifier.TemplateVerifyEmail,
+		To:       user.Email,
+		Data: map[string]interface{}{
+			"Name":      user.Fullname,
+			"URL":       verificationURL,
+			"ExpiresIn": verificationConfig.TTL.String(),
+		},
+	})
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestResendVerificationEmail(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Verify with the resent link", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		user.Status = model.StatusPendingVerification
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.userRepo.On("ChangeUserStatus", ctx, user.Id, model.StatusPendingVerification, model.StatusActive, mock.AnythingOfType("model.AuditLog")).Return(nil)
+
+		assert.NoError(t, s.ResendVerificationEmail(ctx, dto.UserResendVerificationRequest{Email: user.Email}))
+		verificationToken := linkToken(t, waitForMessage(t, s.messages, user.Email))
+		assert.NoError(t, s.VerifyEmail(ctx, dto.UserVerifyEmailRequest{Token: verificationToken}))
+		s.userRepo.AssertExpectations(t)
+
+		// requests are throttled
+		err := s.ResendVerificationEmail(ctx, dto.UserResendVerificationRequest{Email: user.Email})
+		assert.Equal(t, http.StatusTooManyRequests, failure.GetCode(err))
+	})
+
+	t.Run("Failures do not tell registered emails apart", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		user.Status = model.StatusPendingVerification
+		s.userRepo.On("ResolveUserByEmail", ctx, user.Email, mock.Anything).Return(user, nil)
+		s.Notifications = stalledNotifications(s)
+
+		assert.NoError(t, s.ResendVerificationEmail(ctx, dto.UserResendVerificationRequest{Email: user.Email}))
+	})
+}
+
+func TestVerifyEmail(t *testing.T) {
+	ctx := context.Background()
+	// verificationToken signs a verification token of the user for the email
+	verificationToken := func(t *testing.T, s testUserService, user model.User, email string, ttl time.Duration) string {
+		claims := token.NewEmailClaims(token.AudienceEmailVerification, s.cfg.Auth.Issuer, user.Id, email, ttl)
+		signed, err := token.SignEmail(claims, s.cfg.Auth.EmailVerification.Secret)
+		assert.NoError(t, err)
+		return signed
+	}
+
+	t.Run("Already verified", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+
+		assert.NoError(t, s.VerifyEmail(ctx, dto.UserVerifyEmailRequest{Token: verificationToken(t, s, user, user.Email, time.Minute)}))
+		s.userRepo.AssertNotCalled(t, "ChangeUserStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Token of a previous address", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		user.Status = model.StatusPendingVerification
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+
+		err := s.VerifyEmail(ctx, dto.UserVerifyEmailRequest{Token: verificationToken(t, s, user, "jane.old@example.com", time.Minute)})
+		assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+		s.userRepo.AssertNotCalled(t, "ChangeUserStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+
+		err := s.VerifyEmail(ctx, dto.UserVerifyEmailRequest{Token: verificationToken(t, s, user, user.Email, -time.Minute)})
+		assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+		s.userRepo.AssertNotCalled(t, "ResolveUserByID", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Suspended user", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		user.Status = model.StatusSuspended
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+
+		err := s.VerifyEmail(ctx, dto.UserVerifyEmailRequest{Token: verificationToken(t, s, user, user.Email, time.Minute)})
+		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
+	})
+}
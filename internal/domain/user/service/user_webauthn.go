@@ -0,0 +1,278 @@
+package service
+
+import (
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/crypt"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/passkey"
+)
+
+const webAuthnCeremonyIDSize = 16
+
+// webAuthnCeremony is the state of a WebAuthn ceremony between its begin and finish
+// requests. UserId is only set for registrations.
+type webAuthnCeremony struct {
+	UserId  uuid.UUID       `json:"userId"`
+	Session passkey.Session `json:"session"`
+}
+
+// BeginWebAuthnRegistration starts registering a passkey for the calling user.
+func (s *UserServiceImpl) BeginWebAuthnRegistration(ctx context.Context, primaryID uuid.UUID) (dto.WebAuthnCeremonyResponse, error) {
+	user, err := s.resolveCallingUser(ctx, primaryID)
+	if err != nil {
+		return dto.WebAuthnCeremonyResponse{}, err
+	}
+	passkeyUser, _, err := s.resolvePasskeyUser(ctx, user)
+	if err != nil {
+		return dto.WebAuthnCeremonyResponse{}, err
+	}
+	rp, err := s.relyingParty()
+	if err != nil {
+		return dto.WebAuthnCeremonyResponse{}, err
+	}
+
+	creation, session, err := rp.BeginRegistration(passkeyUser)
+	if err != nil {
+		log.Error().Err(err).Msg("[BeginWebAuthnRegistration] failed begin registration")
+		return dto.WebAuthnCeremonyResponse{}, failure.InternalError(err)
+	}
+	ceremonyID, err := s.startWebAuthnCeremony(ctx, webAuthnCeremony{UserId: user.Id, Session: session})
+	if err != nil {
+		return dto.WebAuthnCeremonyResponse{}, err
+	}
+	return dto.WebAuthnCeremonyResponse{CeremonyId: ceremonyID, Options: creation}, nil
+}
+
+// FinishWebAuthnRegistration verifies the new passkey of the calling user and stores it.
+func (s *UserServiceImpl) FinishWebAuthnRegistration(ctx context.Context, primaryID uuid.UUID, registrationRequest dto.WebAuthnRegistrationRequest) (dto.WebAuthnCredentialResponse, error) {
+	user, err := s.resolveCallingUser(ctx, primaryID)
+	if err != nil {
+		return dto.WebAuthnCredentialResponse{}, err
+	}
+	ceremony, err := s.finishWebAuthnCeremony(ctx, registrationRequest.CeremonyId)
+	if err != nil {
+		return dto.WebAuthnCredentialResponse{}, err
+	}
+	if ceremony.UserId != user.Id {
+		return dto.WebAuthnCredentialResponse{}, failure.BadRequestFromString("Invalid or expired ceremony")
+	}
+	passkeyUser, _, err := s.resolvePasskeyUser(ctx, user)
+	if err != nil {
+		return dto.WebAuthnCredentialResponse{}, err
+	}
+	rp, err := s.relyingParty()
+	if err != nil {
+		return dto.WebAuthnCredentialResponse{}, err
+	}
+
+	credential, err := rp.FinishRegistration(passkeyUser, ceremony.Session, registrationRequest.Credential)
+	if err != nil {
+		log.Warn().Err(err).Msg("[FinishWebAuthnRegistration] failed verify credential")
+		return dto.WebAuthnCredentialResponse{}, failure.BadRequestFromString("Passkey could not be verified")
+	}
+	webAuthnCredential := model.WebAuthnCredential{
+		Id:              uuid.New(),
+		UserId:          user.Id,
+		CredentialId:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.AAGUID,
+		SignCount:       credential.SignCount,
+		Transports:      strings.Join(credential.Transports, ","),
+		Name:            registrationRequest.Name,
+		CreatedAt:       time.Now(),
+	}
+	if err = s.WebAuthnRepository.CreateWebAuthnCredential(ctx, &webAuthnCredential); err != nil {
+		log.Error().Err(err).Msg("[FinishWebAuthnRegistration] failed create credential")
+		return dto.WebAuthnCredentialResponse{}, err
+	}
+	return dto.NewWebAuthnCredentialResponse(webAuthnCredential), nil
+}
+
+// BeginWebAuthnLogin starts a passwordless login with any passkey.
+func (s *UserServiceImpl) BeginWebAuthnLogin(ctx context.Context) (dto.WebAuthnCeremonyResponse, error) {
+	rp, err := s.relyingParty()
+	if err != nil {
+		return dto.WebAuthnCeremonyResponse{}, err
+	}
+	assertion, session, err := rp.BeginLogin()
+	if err != nil {
+		log.Error().Err(err).Msg("[BeginWebAuthnLogin] failed begin login")
+		return dto.WebAuthnCeremonyResponse{}, failure.InternalError(err)
+	}
+	ceremonyID, err := s.startWebAuthnCeremony(ctx, webAuthnCeremony{Session: session})
+	if err != nil {
+		return dto.WebAuthnCeremonyResponse{}, err
+	}
+	return dto.WebAuthnCeremonyResponse{CeremonyId: ceremonyID, Options: assertion}, nil
+}
+
+// FinishWebAuthnLogin verifies the passkey assertion and starts a session for its
+// owner. The passkey replaces both the password and the second factor.
+func (s *UserServiceImpl) FinishWebAuthnLogin(ctx context.Context, loginRequest dto.WebAuthnLoginRequest) (dto.UserLoginResponse, error) {
+	ceremony, err := s.finishWebAuthnCeremony(ctx, loginRequest.CeremonyId)
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+	if ceremony.UserId != uuid.Nil {
+		return dto.UserLoginResponse{}, failure.BadRequestFromString("Invalid or expired ceremony")
+	}
+	rp, err := s.relyingParty()
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	var (
+		user        model.User
+		credentials model.WebAuthnCredentialList
+	)
+	_, credential, err := rp.FinishLogin(ceremony.Session, loginRequest.Credential, func(userHandle []byte) (passkey.User, error) {
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return passkey.User{}, err
+		}
+		user, err = s.UserRepository.ResolveUserByID(ctx, userID)
+		if err != nil {
+			return passkey.User{}, err
+		}
+		var passkeyUser passkey.User
+		passkeyUser, credentials, err = s.resolvePasskeyUser(ctx, user)
+		return passkeyUser, err
+	})
+	if err != nil {
+		if errors.Is(err, passkey.ErrCloned) {
+			log.Warn().Str("userId", user.Id.String()).Msg("[FinishWebAuthnLogin] possibly cloned authenticator")
+		} else {
+			log.Warn().Err(err).Msg("[FinishWebAuthnLogin] failed verify assertion")
+		}
+		return dto.UserLoginResponse{}, failure.Unauthorized("Passkey could not be verified")
+	}
+
+	if user.Status == model.StatusPendingVerification {
+		return dto.UserLoginResponse{}, failure.WithErrorCode(failure.Forbidden("Email is not verified"), ErrCodeEmailNotVerified)
+	}
+	if !user.Status.CanLogin() {
+		return dto.UserLoginResponse{}, failure.Forbidden("User is not active")
+	}
+	for _, stored := range credentials {
+		if bytes.Equal(stored.CredentialId, credential.ID) {
+			err = s.WebAuthnRepository.UpdateWebAuthnCredentialUsage(ctx, stored.Id, credential.SignCount, time.Now())
+			if err != nil {
+				log.Error().Err(err).Msg("[FinishWebAuthnLogin] failed update credential")
+				return dto.UserLoginResponse{}, err
+			}
+			break
+		}
+	}
+	return s.startSession(ctx, user, loginRequest.Client)
+}
+
+// ResolveWebAuthnCredentialsByUserID lists the passkeys of a user.
+func (s *UserServiceImpl) ResolveWebAuthnCredentialsByUserID(ctx context.Context, userID uuid.UUID) ([]dto.WebAuthnCredentialResponse, error) {
+	if err := s.authorizeUserAccess(ctx, userID); err != nil {
+		return nil, err
+	}
+	credentials, err := s.WebAuthnRepository.ResolveWebAuthnCredentialsByUserID(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("[ResolveWebAuthnCredentialsByUserID] failed get credentials")
+		return nil, err
+	}
+	return dto.NewWebAuthnCredentialListResponse(credentials), nil
+}
+
+// DeleteWebAuthnCredential removes a passkey of a user.
+func (s *UserServiceImpl) DeleteWebAuthnCredential(ctx context.Context, userID uuid.UUID, credentialID uuid.UUID) error {
+	if err := s.authorizeUserAccess(ctx, userID); err != nil {
+		return err
+	}
+	err := s.WebAuthnRepository.DeleteWebAuthnCredential(ctx, userID, credentialID)
+	if err != nil {
+		if failure.GetCode(err) != http.StatusNotFound {
+			log.Error().Err(err).Msg("[DeleteWebAuthnCredential] failed delete credential")
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *UserServiceImpl) relyingParty() (*passkey.RelyingParty, error) {
+	webAuthnConfig := s.cfg.Auth.WebAuthn
+	rp, err := passkey.NewRelyingParty(passkey.Config{
+		RPID:          webAuthnConfig.RPID,
+		RPDisplayName: webAuthnConfig.RPDisplayName,
+		RPOrigins:     webAuthnConfig.RPOrigins,
+		Timeout:       webAuthnConfig.CeremonyTTL,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("[relyingParty] failed configure webauthn relying party")
+		return nil, failure.InternalError(err)
+	}
+	return rp, nil
+}
+
+// resolvePasskeyUser returns the user as seen by the relying party together with
+// the stored passkeys.
+func (s *UserServiceImpl) resolvePasskeyUser(ctx context.Context, user model.User) (passkey.User, model.WebAuthnCredentialList, error) {
+	credentials, err := s.WebAuthnRepository.ResolveWebAuthnCredentialsByUserID(ctx, user.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("[resolvePasskeyUser] failed get credentials")
+		return passkey.User{}, nil, err
+	}
+	passkeyUser := passkey.User{
+		ID:          user.Id[:],
+		Name:        user.Email,
+		DisplayName: user.Fullname,
+	}
+	for _, credential := range credentials {
+		var transports []string
+		if credential.Transports != "" {
+			transports = strings.Split(credential.Transports, ",")
+		}
+		passkeyUser.Credentials = append(passkeyUser.Credentials, passkey.Credential{
+			ID:              credential.CredentialId,
+			PublicKey:       credential.PublicKey,
+			AttestationType: credential.AttestationType,
+			AAGUID:          credential.AAGUID,
+			SignCount:       credential.SignCount,
+			Transports:      transports,
+		})
+	}
+	return passkeyUser, credentials, nil
+}
+
+func (s *UserServiceImpl) startWebAuthnCeremony(ctx context.Context, ceremony webAuthnCeremony) (string, error) {
+	ceremonyID, err := crypt.GenerateToken(webAuthnCeremonyIDSize)
+	if err != nil {
+		log.Error().Err(err).Msg("[startWebAuthnCeremony] failed generate ceremony id")
+		return "", failure.InternalError(err)
+	}
+	if err = s.SetWebAuthnCeremony(ctx, ceremonyID, ceremony, s.cfg.Auth.WebAuthn.CeremonyTTL); err != nil {
+		log.Error().Err(err).Msg("[startWebAuthnCeremony] failed store ceremony")
+		return "", failure.InternalError(err)
+	}
+	return ceremonyID, nil
+}
+
+func (s *UserServiceImpl) finishWebAuthnCeremony(ctx context.Context, ceremonyID string) (webAuthnCeremony, error) {
+	ceremony, err := s.ConsumeWebAuthnCeremony(ctx, ceremonyID)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return webAuthnCeremony{}, failure.BadRequestFromString("Invalid or expired ceremony")
+		}
+		log.Error().Err(err).Msg("[finishWebAuthnCeremony] failed get ceremony")
+		return webAuthnCeremony{}, failure.InternalError(err)
+	}
+	return ceremony, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// emptyCredential stands in for a credential the ceremony is rejected before.
+var emptyCredential = json.RawMessage(`{}`)
+
+func TestWebAuthnCeremony(t *testing.T) {
+	// callerContext returns the context of a request of the user
+	callerContext := func(user model.User) context.Context {
+		return model.NewPrincipalContext(context.Background(), model.Principal{UserID: user.Id, Role: user.Role})
+	}
+
+	t.Run("Registration of another user", func(t *testing.T) {
+		s := newTestUserService(t)
+		jane := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		john := newTestUser(t, "john@example.com", "Str0ngPassword")
+		janeCtx, johnCtx := callerContext(jane), callerContext(john)
+		s.userRepo.On("ResolveUserByID", janeCtx, jane.Id, mock.Anything).Return(jane, nil)
+		s.userRepo.On("ResolveUserByID", johnCtx, john.Id, mock.Anything).Return(john, nil)
+		s.webAuthnRepo.On("ResolveWebAuthnCredentialsByUserID", janeCtx, jane.Id).Return(model.WebAuthnCredentialList{}, nil)
+
+		ceremony, err := s.BeginWebAuthnRegistration(janeCtx, jane.Id)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ceremony.CeremonyId)
+		assert.NotNil(t, ceremony.Options)
+
+		_, err = s.FinishWebAuthnRegistration(johnCtx, john.Id, dto.WebAuthnRegistrationRequest{CeremonyId: ceremony.CeremonyId, Credential: emptyCredential})
+		assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+
+		// ceremonies are single use
+		_, err = s.FinishWebAuthnRegistration(janeCtx, jane.Id, dto.WebAuthnRegistrationRequest{CeremonyId: ceremony.CeremonyId, Credential: emptyCredential})
+		assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+		s.webAuthnRepo.AssertNotCalled(t, "CreateWebAuthnCredential", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Ceremonies are not interchangeable", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+		ctx := callerContext(user)
+		s.userRepo.On("ResolveUserByID", ctx, user.Id, mock.Anything).Return(user, nil)
+		s.webAuthnRepo.On("ResolveWebAuthnCredentialsByUserID", ctx, user.Id).Return(model.WebAuthnCredentialList{}, nil)
+
+		login, err := s.BeginWebAuthnLogin(ctx)
+		assert.NoError(t, err)
+		_, err = s.FinishWebAuthnRegistration(ctx, user.Id, dto.WebAuthnRegistrationRequest{CeremonyId: login.CeremonyId, Credential: emptyCredential})
+		assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+
+		registration, err := s.BeginWebAuthnRegistration(ctx, user.Id)
+		assert.NoError(t, err)
+		_, err = s.FinishWebAuthnLogin(ctx, dto.WebAuthnLoginRequest{CeremonyId: registration.CeremonyId, Credential: emptyCredential})
+		assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+		s.sessionRepo.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown ceremony", func(t *testing.T) {
+		s := newTestUserService(t)
+
+		_, err := s.FinishWebAuthnLogin(context.Background(), dto.WebAuthnLoginRequest{CeremonyId: "unknown", Credential: emptyCredential})
+		assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+	})
+
+	t.Run("Passkeys of other users", func(t *testing.T) {
+		s := newTestUserService(t)
+		user := newTestUser(t, "jane@example.com", "Str0ngPassword")
+
+		err := s.DeleteWebAuthnCredential(callerContext(user), uuid.New(), uuid.New())
+		assert.Equal(t, http.StatusForbidden, failure.GetCode(err))
+		s.webAuthnRepo.AssertNotCalled(t, "DeleteWebAuthnCredential", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -4,18 +4,26 @@ package user
 import (
 	"github.com/go-chi/chi"
 
+	"net"
+	"net/http"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
 	"github.com/IlhamRobyana/user/internal/domain/user/service"
+	"github.com/IlhamRobyana/user/transport/http/middleware"
 )
 
 // UserHandler is the HTTP handler for User domain.
 type UserHandler struct {
-	UserService service.UserService
+	UserService    service.UserService
+	Authentication *middleware.Authentication
 }
 
 // ProvideUserHandler is the provider for this handler.
-func ProvideUserHandler(svc service.UserService) UserHandler {
+func ProvideUserHandler(svc service.UserService, auth *middleware.Authentication) UserHandler {
 	return UserHandler{
-		UserService: svc,
+		UserService:    svc,
+		Authentication: auth,
 	}
 }
 
@@ -24,9 +32,67 @@ func (h *UserHandler) Router(r chi.Router) {
 	r.Route("/user", func(r chi.Router) {
 		r.Group(func(r chi.Router) {
 			r.Post("/", h.CreateUser)
-			r.Get("/{id}", h.ResolveUserByID)
 			r.Post("/login", h.LoginUser)
+			r.Post("/login/mfa", h.LoginMFA)
+			r.Post("/login/magic-link", h.RequestMagicLink)
+			r.Post("/login/magic-link/consume", h.LoginMagicLink)
+			r.Post("/login/webauthn/begin", h.BeginWebAuthnLogin)
+			r.Post("/login/webauthn/finish", h.FinishWebAuthnLogin)
+			r.Post("/token/refresh", h.RefreshToken)
+			r.Post("/password/forgot", h.ForgotPassword)
+			r.Post("/password/reset", h.ResetPassword)
+			r.Post("/verify-email", h.VerifyEmail)
+			r.Post("/verify-email/resend", h.ResendVerificationEmail)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.Authentication.VerifyBearerToken)
+			r.Get("/{id}", h.ResolveUserByID)
+			r.Patch("/{id}", h.UpdateUser)
+			r.Delete("/{id}", h.DeleteUser)
+			r.Post("/logout", h.Logout)
+			r.Post("/logout-all", h.LogoutAll)
+			r.Put("/{id}/password", h.ChangePassword)
+			r.Post("/{id}/mfa/totp", h.EnrollTOTP)
+			r.Post("/{id}/mfa/totp/confirm", h.ConfirmTOTP)
+			r.Delete("/{id}/mfa/totp", h.DisableTOTP)
+			r.Post("/{id}/webauthn/credentials/begin", h.BeginWebAuthnRegistration)
+			r.Post("/{id}/webauthn/credentials", h.FinishWebAuthnRegistration)
+			r.Get("/{id}/webauthn/credentials", h.ResolveWebAuthnCredentialsByUserID)
+			r.Delete("/{id}/webauthn/credentials/{credentialId}", h.DeleteWebAuthnCredential)
+			r.Get("/{id}/sessions", h.ResolveSessionsByUserID)
+			r.Delete("/{id}/sessions/{sessionId}", h.DeleteSession)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.Authentication.VerifyBearerToken)
+			r.Use(middleware.RequireRole(model.RoleAdmin, model.RoleSupport))
+			r.Get("/search", h.SearchUsers)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.Authentication.VerifyBearerToken)
+			r.Use(middleware.RequireRole(model.RoleAdmin))
+			r.Get("/", h.ResolveUsers)
+			r.Get("/export", h.ExportUsers)
+			r.Post("/import", h.ImportUsers)
+			r.Post("/{id}/activate", h.ActivateUser)
+			r.Post("/{id}/suspend", h.SuspendUser)
+			r.Post("/{id}/deactivate", h.DeactivateUser)
+			r.Post("/{id}/restore", h.RestoreUser)
 		})
 
 	})
 }
+
+// clientInfo describes the client that sent r.
+func clientInfo(r *http.Request) dto.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return dto.ClientInfo{
+		IpAddress: ip,
+		UserAgent: r.UserAgent(),
+	}
+}
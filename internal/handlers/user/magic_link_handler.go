@@ -0,0 +1,85 @@
+package user
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"encoding/json"
+	"net/http"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/transport/http/response"
+)
+
+// RequestMagicLink sends a magic login link.
+// @Summary Request a magic login link.
+// @Description This endpoint emails a single-use login link. It responds the same way whether or not the email is registered.
+// @Tags user
+// @Param user body dto.UserMagicLinkRequest true "The email of the User."
+// @Produce json
+// @Success 202 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/login/magic-link [post]
+func (h *UserHandler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var magicLinkRequest dto.UserMagicLinkRequest
+	err := decoder.Decode(&magicLinkRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = magicLinkRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = h.UserService.RequestMagicLink(r.Context(), magicLinkRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[RequestMagicLink] failed request magic link")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusAccepted, "If the email is registered, a login link has been sent")
+}
+
+// LoginMagicLink logs in a User with a magic link.
+// @Summary Log in with a magic link.
+// @Description This endpoint logs in a User with the token of a magic login link. When the User has multi-factor authentication enabled, it responds with an "mfa_required" challenge instead.
+// @Tags user
+// @Param user body dto.UserMagicLinkLoginRequest true "The token of the magic link."
+// @Produce json
+// @Success 201 {object} response.Base{data=dto.UserLoginResponse}
+// @Success 202 {object} response.Base{data=dto.UserLoginResponse}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/login/magic-link/consume [post]
+func (h *UserHandler) LoginMagicLink(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var loginRequest dto.UserMagicLinkLoginRequest
+	err := decoder.Decode(&loginRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = loginRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	loginRequest.Client = clientInfo(r)
+
+	loginResponse, err := h.UserService.LoginMagicLink(r.Context(), loginRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[LoginMagicLink] failed login with magic link")
+		response.WithError(w, err)
+		return
+	}
+	if loginResponse.Challenge != "" {
+		response.WithJSON(w, http.StatusAccepted, loginResponse)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, loginResponse)
+}
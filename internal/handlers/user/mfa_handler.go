@@ -0,0 +1,164 @@
+package user
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"encoding/json"
+	"net/http"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/transport/http/response"
+)
+
+// LoginMFA completes a login challenged for a second factor.
+// @Summary Complete a login with a second factor.
+// @Description This endpoint completes a login answered with an "mfa_required" challenge using a TOTP code or a recovery code.
+// @Tags user
+// @Param user body dto.UserLoginMFARequest true "The MFA token of the challenge and the code."
+// @Produce json
+// @Success 201 {object} response.Base{data=dto.UserLoginResponse}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/login/mfa [post]
+func (h *UserHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var loginRequest dto.UserLoginMFARequest
+	err := decoder.Decode(&loginRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = loginRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	loginRequest.Client = clientInfo(r)
+
+	loginResponse, err := h.UserService.LoginMFA(r.Context(), loginRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[LoginMFA] failed complete login")
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, loginResponse)
+}
+
+// EnrollTOTP starts the TOTP enrollment of the calling User.
+// @Summary Enroll a TOTP second factor.
+// @Description This endpoint generates a TOTP secret for the calling User and returns it with an otpauth:// URI. The second factor is enabled once confirmed with a code.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Produce json
+// @Success 201 {object} response.Base{data=dto.UserMFAEnrollResponse}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/mfa/totp [post]
+func (h *UserHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	enrollResponse, err := h.UserService.EnrollTOTP(r.Context(), id)
+	if err != nil {
+		log.Warn().Err(err).Msg("[EnrollTOTP] failed enroll totp")
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, enrollResponse)
+}
+
+// ConfirmTOTP enables the enrolled TOTP second factor of the calling User.
+// @Summary Confirm a TOTP second factor.
+// @Description This endpoint enables the enrolled TOTP second factor with a code generated from it and returns ten one-time recovery codes. They are shown only once.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Param user body dto.UserMFAConfirmRequest true "A code generated from the enrolled secret."
+// @Produce json
+// @Success 200 {object} response.Base{data=dto.UserMFARecoveryCodesResponse}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/mfa/totp/confirm [post]
+func (h *UserHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var confirmRequest dto.UserMFAConfirmRequest
+	err = decoder.Decode(&confirmRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = confirmRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	recoveryCodes, err := h.UserService.ConfirmTOTP(r.Context(), id, confirmRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[ConfirmTOTP] failed confirm totp")
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, recoveryCodes)
+}
+
+// DisableTOTP removes the TOTP second factor of the calling User.
+// @Summary Disable the TOTP second factor.
+// @Description This endpoint removes the TOTP second factor and the recovery codes of the calling User after checking the password and a TOTP or recovery code.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Param user body dto.UserMFADisableRequest true "The password and a TOTP or recovery code."
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/mfa/totp [delete]
+func (h *UserHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var disableRequest dto.UserMFADisableRequest
+	err = decoder.Decode(&disableRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = disableRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = h.UserService.DisableTOTP(r.Context(), id, disableRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[DisableTOTP] failed disable totp")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "Multi-factor authentication disabled successfully")
+}
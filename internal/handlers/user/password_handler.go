@@ -0,0 +1,120 @@
+package user
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"encoding/json"
+	"net/http"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/transport/http/response"
+)
+
+// ForgotPassword sends a password reset link.
+// @Summary Request a password reset.
+// @Description This endpoint emails a single-use password reset link. It responds the same way whether or not the email is registered.
+// @Tags user
+// @Param user body dto.UserForgotPasswordRequest true "The email of the User."
+// @Produce json
+// @Success 202 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/password/forgot [post]
+func (h *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var forgotRequest dto.UserForgotPasswordRequest
+	err := decoder.Decode(&forgotRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = forgotRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = h.UserService.ForgotPassword(r.Context(), forgotRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[ForgotPassword] failed request password reset")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusAccepted, "If the email is registered, a password reset link has been sent")
+}
+
+// ResetPassword sets a new password using a reset token.
+// @Summary Reset the password.
+// @Description This endpoint sets a new password using a token from a password reset link and signs the User out everywhere.
+// @Tags user
+// @Param user body dto.UserResetPasswordRequest true "The reset token and the new password."
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/password/reset [post]
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var resetRequest dto.UserResetPasswordRequest
+	err := decoder.Decode(&resetRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = resetRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = h.UserService.ResetPassword(r.Context(), resetRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[ResetPassword] failed reset password")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "Password reset successfully")
+}
+
+// ChangePassword changes the password of the calling User.
+// @Summary Change the password.
+// @Description This endpoint changes the password of the calling User after checking the current one.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Param user body dto.UserChangePasswordRequest true "The current and the new password."
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/password [put]
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var changeRequest dto.UserChangePasswordRequest
+	err = decoder.Decode(&changeRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = changeRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = h.UserService.ChangePassword(r.Context(), id, changeRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[ChangePassword] failed change password")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "Password changed successfully")
+}
@@ -0,0 +1,77 @@
+package user
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"net/http"
+
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/transport/http/response"
+)
+
+// ResolveSessionsByUserID lists the sessions of a User.
+// @Summary List the sessions of a User.
+// @Description This endpoint lists where a User is signed in. Only the User and staff members may call it.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Produce json
+// @Success 200 {object} response.Base{data=[]dto.SessionResponse}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/sessions [get]
+func (h *UserHandler) ResolveSessionsByUserID(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	sessions, err := h.UserService.ResolveSessionsByUserID(r.Context(), id)
+	if err != nil {
+		log.Warn().Err(err).Msg("[ResolveSessionsByUserID] failed get sessions")
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, sessions)
+}
+
+// DeleteSession signs a User out of one session.
+// @Summary Delete a session of a User.
+// @Description This endpoint revokes one session of a User. Only the User and staff members may call it.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Param sessionId path string true "The session's identifier."
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/sessions/{sessionId} [delete]
+func (h *UserHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	sessionID, err := uuid.Parse(chi.URLParam(r, "sessionId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = h.UserService.DeleteSession(r.Context(), id, sessionID)
+	if err != nil {
+		log.Warn().Err(err).Msg("[DeleteSession] failed delete session")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "Session deleted successfully")
+}
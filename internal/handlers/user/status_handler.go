@@ -0,0 +1,158 @@
+package user
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"encoding/json"
+	"net/http"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/transport/http/response"
+)
+
+// ActivateUser reactivates a User.
+// @Summary Activate a User.
+// @Description This endpoint reactivates a User and lifts their login lockout. Only admins may call it.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Param user body dto.UserStatusChangeRequest true "The reason for the change."
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/activate [post]
+func (h *UserHandler) ActivateUser(w http.ResponseWriter, r *http.Request) {
+	id, statusRequest, err := decodeStatusChange(r)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	err = h.UserService.ActivateUser(r.Context(), id, statusRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[ActivateUser] failed activate user")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "User activated successfully")
+}
+
+// SuspendUser suspends a User.
+// @Summary Suspend a User.
+// @Description This endpoint suspends a User until they are reactivated and logs them out of every session. Only admins may call it.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Param user body dto.UserStatusChangeRequest true "The reason for the change."
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/suspend [post]
+func (h *UserHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	id, statusRequest, err := decodeStatusChange(r)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	err = h.UserService.SuspendUser(r.Context(), id, statusRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[SuspendUser] failed suspend user")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "User suspended successfully")
+}
+
+// DeactivateUser deactivates a User.
+// @Summary Deactivate a User.
+// @Description This endpoint deactivates a User and logs them out of every session. Only admins may call it.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Param user body dto.UserStatusChangeRequest true "The reason for the change."
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/deactivate [post]
+func (h *UserHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	id, statusRequest, err := decodeStatusChange(r)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	err = h.UserService.DeactivateUser(r.Context(), id, statusRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[DeactivateUser] failed deactivate user")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "User deactivated successfully")
+}
+
+// RestoreUser restores a deleted User.
+// @Summary Restore a User.
+// @Description This endpoint restores a soft deleted User in the status it had before it was deleted. Only admins may call it.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Param user body dto.UserStatusChangeRequest true "The reason for the change."
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/restore [post]
+func (h *UserHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	id, statusRequest, err := decodeStatusChange(r)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	err = h.UserService.RestoreUser(r.Context(), id, statusRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[RestoreUser] failed restore user")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "User restored successfully")
+}
+
+func decodeStatusChange(r *http.Request) (uuid.UUID, dto.UserStatusChangeRequest, error) {
+	var statusRequest dto.UserStatusChangeRequest
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return uuid.Nil, statusRequest, failure.BadRequest(err)
+	}
+	decoder := json.NewDecoder(r.Body)
+	if err = decoder.Decode(&statusRequest); err != nil {
+		return uuid.Nil, statusRequest, failure.BadRequest(err)
+	}
+	if err = statusRequest.Validate(); err != nil {
+		return uuid.Nil, statusRequest, failure.BadRequest(err)
+	}
+	return id, statusRequest, nil
+}
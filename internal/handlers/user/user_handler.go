@@ -7,10 +7,14 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
 	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/token"
 	"github.com/IlhamRobyana/user/transport/http/response"
 )
 
@@ -49,12 +53,16 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 // ResolveUserByID resolves a User by its ID.
 // @Summary Resolve User by ID
-// @Description This endpoint resolves a User by its ID.
+// @Description This endpoint resolves a User by its ID. Users may resolve themselves and staff members any user. A sparse fieldset limits the response to the given fields.
 // @Tags user
+// @Security EVMOauthToken
 // @Param id path string true "The User's identifier."
+// @Param fields query []string false "The fields to return, all of them by default." collectionFormat(csv)
 // @Produce json
 // @Success 200 {object} response.Base{data=dto.UserResponse}
 // @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
 // @Failure 404 {object} response.Base
 // @Failure 500 {object} response.Base
 // @Router /v1/user/{id} [get]
@@ -65,30 +73,332 @@ func (h *UserHandler) ResolveUserByID(w http.ResponseWriter, r *http.Request) {
 		id  uuid.UUID
 		err error
 	)
-	id, err = uuid.FromBytes([]byte(idStr))
+	id, err = uuid.Parse(idStr)
 	if err != nil {
 		response.WithError(w, failure.BadRequest(err))
 		return
 	}
 
-	userResponse, err := h.UserService.ResolveUserByID(r.Context(), id)
+	fields, err := dto.ParseUserFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	userResponse, err := h.UserService.ResolveUserByID(r.Context(), id, fields...)
 	if err != nil {
 		log.Warn().Err(err).Msg("[ResolveUserByID] failed get user by id")
 		response.WithError(w, err)
 		return
 	}
+	response.WithJSON(w, http.StatusOK, sparseUserResponse(userResponse, fields))
+}
+
+// sparseUserResponse returns the user response holding the fields of a sparse
+// fieldset only, or the whole response when the fieldset is empty.
+func sparseUserResponse(userResponse dto.UserResponse, fields []dto.UserDTOFieldNameType) interface{} {
+	if len(fields) == 0 {
+		return userResponse
+	}
+	return userResponse.Only(fields)
+}
+
+// ResolveUsers lists Users.
+// @Summary List Users.
+// @Description This endpoint lists Users, filtered and sorted. Pages are either numbered, which counts the Users, or follow on from the nextCursor of the previous page. A sparse fieldset limits the Users to the given fields. Only admins may call it.
+// @Tags user
+// @Security EVMOauthToken
+// @Param status query []string false "The statuses of the Users." collectionFormat(csv)
+// @Param emailPrefix query string false "The start of the email of the Users."
+// @Param createdFrom query string false "The earliest creation time, RFC 3339."
+// @Param createdTo query string false "The latest creation time, RFC 3339."
+// @Param deleted query string false "Whether deleted Users are listed." Enums(exclude, include, only) default(exclude)
+// @Param sortBy query string false "The field to sort by." default(createdAt)
+// @Param order query string false "The direction to sort in." Enums(asc, desc) default(desc)
+// @Param limit query int false "The number of Users per page." default(20)
+// @Param page query int false "The number of the page." default(1)
+// @Param cursor query string false "The cursor of the page, cannot be combined with page."
+// @Param fields query []string false "The fields to return, all of them by default." collectionFormat(csv)
+// @Produce json
+// @Success 200 {object} response.Base{data=[]dto.UserResponse,metadata=dto.UserListMetadata}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user [get]
+func (h *UserHandler) ResolveUsers(w http.ResponseWriter, r *http.Request) {
+	listRequest := dto.NewUserListRequest(r.URL.Query())
+	if err := listRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	userResponses, metadata, err := h.UserService.ResolveUsers(r.Context(), listRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[ResolveUsers] failed list users")
+		response.WithError(w, err)
+		return
+	}
+	sparseResponses := make([]interface{}, 0, len(userResponses))
+	for _, userResponse := range userResponses {
+		sparseResponses = append(sparseResponses, sparseUserResponse(userResponse, listRequest.Fields))
+	}
+	response.WithMetadata(w, http.StatusOK, sparseResponses, metadata)
+}
+
+// SearchUsers searches Users.
+// @Summary Search Users.
+// @Description This endpoint searches Users by partial name or email, best matches first, with the matching fragments highlighted. Only staff members may call it.
+// @Tags user
+// @Security EVMOauthToken
+// @Param q query string true "The partial name or email to search for."
+// @Param limit query int false "The number of Users to return." default(20)
+// @Produce json
+// @Success 200 {object} response.Base{data=[]dto.UserSearchResult}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/search [get]
+func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	searchRequest := dto.NewUserSearchRequest(r.URL.Query())
+	if err := searchRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	searchResults, err := h.UserService.SearchUsers(r.Context(), searchRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[SearchUsers] failed search users")
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, searchResults)
+}
+
+// userExportFlushInterval is the number of users written between two flushes of
+// an export to the client.
+const userExportFlushInterval = 1000
+
+// ExportUsers exports Users.
+// @Summary Export Users.
+// @Description This endpoint streams every User of a listing, filtered and sorted as listings are, as CSV, JSONL or columnar NDJSON. Columnar NDJSON holds every column on every line with a fixed type, timestamps being Unix milliseconds, and its schema is given in the X-Export-Schema header. The password is never exported. Only admins may call it.
+// @Tags user
+// @Security EVMOauthToken
+// @Param format query string false "The format of the export." Enums(csv, jsonl, columnar) default(csv)
+// @Param status query []string false "The statuses of the Users." collectionFormat(csv)
+// @Param emailPrefix query string false "The start of the email of the Users."
+// @Param createdFrom query string false "The earliest creation time, RFC 3339."
+// @Param createdTo query string false "The latest creation time, RFC 3339."
+// @Param deleted query string false "Whether deleted Users are exported." Enums(exclude, include, only) default(exclude)
+// @Param sortBy query string false "The field to sort by." default(createdAt)
+// @Param order query string false "The direction to sort in." Enums(asc, desc) default(desc)
+// @Param fields query []string false "The fields to export, all of them by default." collectionFormat(csv)
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Success 200 {string} string
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/export [get]
+func (h *UserHandler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	exportRequest := dto.NewUserExportRequest(r.URL.Query())
+	if err := exportRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	columns := exportRequest.Columns()
+	schema, _ := json.Marshal(dto.UserExportSchema(columns))
+	w.Header().Set("Content-Type", exportRequest.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportRequest.FileName(time.Now())))
+	w.Header().Set("X-Export-Schema", string(schema))
+
+	output := &exportOutput{ResponseWriter: w}
+	writer := dto.NewUserExportWriter(output, exportRequest.Format, columns)
+	flusher, _ := w.(http.Flusher)
+	var exported int
+	err := h.UserService.ExportUsers(r.Context(), exportRequest, func(userResponse dto.UserResponse) error {
+		if err := writer.Write(userResponse); err != nil {
+			return err
+		}
+		exported++
+		if exported%userExportFlushInterval == 0 && flusher != nil {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		if !output.started {
+			for _, header := range []string{"Content-Disposition", "X-Export-Schema"} {
+				w.Header().Del(header)
+			}
+			log.Warn().Err(err).Msg("[ExportUsers] failed export users")
+			response.WithError(w, err)
+			return
+		}
+		// the status is sent already, dropping the connection tells the client
+		// the export is incomplete
+		log.Warn().Err(err).Int("exported", exported).Msg("[ExportUsers] failed export users, aborting")
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportOutput is the body of an export, telling whether it started being sent.
+type exportOutput struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (o *exportOutput) Write(p []byte) (int, error) {
+	o.started = true
+	return o.ResponseWriter.Write(p)
+}
+
+// maxUserImportBytes is the largest import body accepted.
+const maxUserImportBytes = 16 << 20
+
+// ImportUsers imports Users in bulk.
+// @Summary Import Users.
+// @Description This endpoint creates Users in bulk from CSV, with a header row naming the columns, or from JSONL. Passwords are given either in plain text or already hashed with bcrypt or argon2id as passwordHash. Users are only created when every row is valid, otherwise the errors per row are reported and nothing is written. A dry run reports the errors without writing anything. Only admins may call it.
+// @Tags user
+// @Security EVMOauthToken
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Param dryRun query bool false "Whether to validate the rows only." default(false)
+// @Param users body string true "The Users to be imported, with the fields email, fullname, password or passwordHash, role and status."
+// @Produce json
+// @Success 200 {object} response.Base{data=dto.UserImportReport}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 422 {object} response.Base{data=dto.UserImportReport}
+// @Failure 500 {object} response.Base
+// @Router /v1/user/import [post]
+func (h *UserHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	format, err := dto.UserImportFormatFromContentType(r.Header.Get("Content-Type"))
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	var dryRun bool
+	if value := r.URL.Query().Get("dryRun"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			response.WithError(w, failure.ValidationFailed(map[string][]string{"dryRun": {"must be true or false"}}))
+			return
+		}
+	}
+	rows, err := dto.ParseUserImport(http.MaxBytesReader(w, r.Body, maxUserImportBytes), format)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	report, err := h.UserService.ImportUsers(r.Context(), rows, dryRun)
+	if err != nil {
+		log.Warn().Err(err).Msg("[ImportUsers] failed import users")
+		response.WithError(w, err)
+		return
+	}
+	status := http.StatusOK
+	if !report.DryRun && len(report.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	response.WithJSON(w, status, report)
+}
+
+// UpdateUser updates a User.
+// @Summary Update a User.
+// @Description This endpoint applies a JSON Merge Patch (RFC 7396) to the mutable fields of a User. Users may update their own profile and staff members any profile, while only admins may change roles.
+// @Tags user
+// @Security EVMOauthToken
+// @Accept json
+// @Param id path string true "The User's identifier."
+// @Param user body dto.UserPatchRequest true "The fields to be changed."
+// @Produce json
+// @Success 200 {object} response.Base{data=dto.UserResponse}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id} [patch]
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var patchRequest dto.UserPatchRequest
+	err = decoder.Decode(&patchRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = patchRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	userResponse, err := h.UserService.UpdateUser(r.Context(), id, patchRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[UpdateUser] failed update user")
+		response.WithError(w, err)
+		return
+	}
 	response.WithJSON(w, http.StatusOK, userResponse)
 }
 
+// DeleteUser soft deletes a User.
+// @Summary Delete a User.
+// @Description This endpoint soft deletes a User and logs them out of every session. Users may delete their own account and staff members the accounts of lower roles. Deleted Users can be restored by admins.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id} [delete]
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = h.UserService.DeleteUser(r.Context(), id)
+	if err != nil {
+		log.Warn().Err(err).Msg("[DeleteUser] failed delete user")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "User deleted successfully")
+}
+
 // LoginUser logs in a new User.
 // @Summary Logs in a new User.
-// @Description This endpoint logs in a new User.
+// @Description This endpoint logs in a new User. When the User has multi-factor authentication enabled, it responds with an "mfa_required" challenge and a token to complete the login with instead.
 // @Tags user
 // @Param user body dto.UserLoginRequest true "The User to be logged in."
 // @Produce json
-// @Success 201 {object} response.Base
+// @Success 201 {object} response.Base{data=dto.UserLoginResponse}
+// @Success 202 {object} response.Base{data=dto.UserLoginResponse}
 // @Failure 400 {object} response.Base
-// @Failure 409 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
 // @Failure 500 {object} response.Base
 // @Router /v1/user/login [post]
 func (h *UserHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
@@ -103,12 +413,93 @@ func (h *UserHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		response.WithError(w, failure.BadRequest(err))
 		return
 	}
+	userRequest.Client = clientInfo(r)
 
-	loggedIn, err := h.UserService.LoginUser(r.Context(), userRequest)
-	if err != nil || !loggedIn {
+	loginResponse, err := h.UserService.LoginUser(r.Context(), userRequest)
+	if err != nil {
 		log.Warn().Err(err).Msg("[LoginUser] failed login user")
 		response.WithError(w, err)
 		return
 	}
-	response.WithMessage(w, http.StatusCreated, "Successfully login")
+	if loginResponse.Challenge != "" {
+		response.WithJSON(w, http.StatusAccepted, loginResponse)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, loginResponse)
+}
+
+// RefreshToken exchanges a refresh token for a new pair of tokens.
+// @Summary Refresh the access token.
+// @Description This endpoint rotates a refresh token and issues a new access token.
+// @Tags user
+// @Param token body dto.UserRefreshTokenRequest true "The refresh token to be exchanged."
+// @Produce json
+// @Success 201 {object} response.Base{data=dto.UserLoginResponse}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/token/refresh [post]
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var refreshRequest dto.UserRefreshTokenRequest
+	err := decoder.Decode(&refreshRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = refreshRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	loginResponse, err := h.UserService.RefreshToken(r.Context(), refreshRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[RefreshToken] failed refresh token")
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, loginResponse)
+}
+
+// Logout ends the caller's current session.
+// @Summary Logs out the current session.
+// @Description This endpoint revokes the access token used for the call and the refresh tokens of its session.
+// @Tags user
+// @Security EVMOauthToken
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/logout [post]
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, _ := token.FromContext(r.Context())
+	err := h.UserService.Logout(r.Context(), claims)
+	if err != nil {
+		log.Warn().Err(err).Msg("[Logout] failed logout user")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "Successfully logout")
+}
+
+// LogoutAll ends every session of the caller.
+// @Summary Logs out every session.
+// @Description This endpoint revokes every access and refresh token issued to the caller.
+// @Tags user
+// @Security EVMOauthToken
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/logout-all [post]
+func (h *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	claims, _ := token.FromContext(r.Context())
+	err := h.UserService.LogoutAll(r.Context(), claims.UserID)
+	if err != nil {
+		log.Warn().Err(err).Msg("[LogoutAll] failed logout user")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "Successfully logout from all sessions")
 }
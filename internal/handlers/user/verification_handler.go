@@ -0,0 +1,78 @@
+package user
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"encoding/json"
+	"net/http"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/transport/http/response"
+)
+
+// VerifyEmail verifies the email of a User.
+// @Summary Verify the email.
+// @Description This endpoint verifies the email of a User using the token from a verification link and activates the User.
+// @Tags user
+// @Param user body dto.UserVerifyEmailRequest true "The verification token."
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/verify-email [post]
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var verifyRequest dto.UserVerifyEmailRequest
+	err := decoder.Decode(&verifyRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = verifyRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = h.UserService.VerifyEmail(r.Context(), verifyRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[VerifyEmail] failed verify email")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "Email verified successfully")
+}
+
+// ResendVerificationEmail sends a new email verification link.
+// @Summary Resend the verification email.
+// @Description This endpoint emails a new verification link to a User whose email is not verified yet. It responds the same way whether or not the email is registered.
+// @Tags user
+// @Param user body dto.UserResendVerificationRequest true "The email of the User."
+// @Produce json
+// @Success 202 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 429 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/verify-email/resend [post]
+func (h *UserHandler) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var resendRequest dto.UserResendVerificationRequest
+	err := decoder.Decode(&resendRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = resendRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = h.UserService.ResendVerificationEmail(r.Context(), resendRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[ResendVerificationEmail] failed resend verification email")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusAccepted, "If the email is registered and not verified yet, a verification link has been sent")
+}
@@ -0,0 +1,203 @@
+package user
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"encoding/json"
+	"net/http"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model/dto"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/transport/http/response"
+)
+
+// BeginWebAuthnLogin starts a passwordless login.
+// @Summary Begin a passkey login.
+// @Description This endpoint returns the options to pass to navigator.credentials.get() and the id of the ceremony.
+// @Tags user
+// @Produce json
+// @Success 200 {object} response.Base{data=dto.WebAuthnCeremonyResponse}
+// @Failure 500 {object} response.Base
+// @Router /v1/user/login/webauthn/begin [post]
+func (h *UserHandler) BeginWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
+	ceremony, err := h.UserService.BeginWebAuthnLogin(r.Context())
+	if err != nil {
+		log.Warn().Err(err).Msg("[BeginWebAuthnLogin] failed begin login")
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, ceremony)
+}
+
+// FinishWebAuthnLogin completes a passwordless login.
+// @Summary Finish a passkey login.
+// @Description This endpoint verifies the assertion of a passkey and logs its User in.
+// @Tags user
+// @Param user body dto.WebAuthnLoginRequest true "The ceremony id and the result of navigator.credentials.get()."
+// @Produce json
+// @Success 201 {object} response.Base{data=dto.UserLoginResponse}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/login/webauthn/finish [post]
+func (h *UserHandler) FinishWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var loginRequest dto.WebAuthnLoginRequest
+	err := decoder.Decode(&loginRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = loginRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	loginRequest.Client = clientInfo(r)
+
+	loginResponse, err := h.UserService.FinishWebAuthnLogin(r.Context(), loginRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[FinishWebAuthnLogin] failed finish login")
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, loginResponse)
+}
+
+// BeginWebAuthnRegistration starts registering a passkey.
+// @Summary Begin a passkey registration.
+// @Description This endpoint returns the options to pass to navigator.credentials.create() and the id of the ceremony.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Produce json
+// @Success 200 {object} response.Base{data=dto.WebAuthnCeremonyResponse}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/webauthn/credentials/begin [post]
+func (h *UserHandler) BeginWebAuthnRegistration(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	ceremony, err := h.UserService.BeginWebAuthnRegistration(r.Context(), id)
+	if err != nil {
+		log.Warn().Err(err).Msg("[BeginWebAuthnRegistration] failed begin registration")
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, ceremony)
+}
+
+// FinishWebAuthnRegistration stores a new passkey.
+// @Summary Finish a passkey registration.
+// @Description This endpoint verifies the result of navigator.credentials.create() and stores the passkey of the calling User.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Param user body dto.WebAuthnRegistrationRequest true "The ceremony id, a name and the new credential."
+// @Produce json
+// @Success 201 {object} response.Base{data=dto.WebAuthnCredentialResponse}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/webauthn/credentials [post]
+func (h *UserHandler) FinishWebAuthnRegistration(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var registrationRequest dto.WebAuthnRegistrationRequest
+	err = decoder.Decode(&registrationRequest)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	if err = registrationRequest.Validate(); err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	credential, err := h.UserService.FinishWebAuthnRegistration(r.Context(), id, registrationRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("[FinishWebAuthnRegistration] failed finish registration")
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, credential)
+}
+
+// ResolveWebAuthnCredentialsByUserID lists the passkeys of a User.
+// @Summary List the passkeys of a User.
+// @Description This endpoint lists the passkeys of a User. Only the User and staff members may call it.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Produce json
+// @Success 200 {object} response.Base{data=[]dto.WebAuthnCredentialResponse}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/webauthn/credentials [get]
+func (h *UserHandler) ResolveWebAuthnCredentialsByUserID(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	credentials, err := h.UserService.ResolveWebAuthnCredentialsByUserID(r.Context(), id)
+	if err != nil {
+		log.Warn().Err(err).Msg("[ResolveWebAuthnCredentialsByUserID] failed get credentials")
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, credentials)
+}
+
+// DeleteWebAuthnCredential removes a passkey of a User.
+// @Summary Delete a passkey of a User.
+// @Description This endpoint removes a passkey of a User. Only the User and staff members may call it.
+// @Tags user
+// @Security EVMOauthToken
+// @Param id path string true "The User's identifier."
+// @Param credentialId path string true "The passkey's identifier."
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/user/{id}/webauthn/credentials/{credentialId} [delete]
+func (h *UserHandler) DeleteWebAuthnCredential(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	credentialID, err := uuid.Parse(chi.URLParam(r, "credentialId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = h.UserService.DeleteWebAuthnCredential(r.Context(), id, credentialID)
+	if err != nil {
+		log.Warn().Err(err).Msg("[DeleteWebAuthnCredential] failed delete credential")
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "Passkey deleted successfully")
+}
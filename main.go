@@ -5,6 +5,8 @@ package main
 
 import (
 	"github.com/IlhamRobyana/user/configs"
+	"github.com/IlhamRobyana/user/shared"
+	"github.com/IlhamRobyana/user/shared/crypt"
 	"github.com/IlhamRobyana/user/shared/logger"
 )
 
@@ -23,6 +25,12 @@ func main() {
 	// Set desired log level
 	logger.SetLogLevel(configServiceGen)
 
+	// Set configured password hashing
+	crypt.InitPasswordHasher(configServiceGen)
+
+	// Set configured password policy
+	shared.InitPasswordPolicy(configServiceGen)
+
 	// Wire everything up
 	httpServiceGen := InitializeServiceServiceGen()
 
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS `user_session` (
+    `id` CHAR(36) NOT NULL,
+    `user_id` CHAR(36) NOT NULL,
+    `ip_address` VARCHAR(45) NOT NULL DEFAULT '',
+    `user_agent` VARCHAR(512) NOT NULL DEFAULT '',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `last_seen_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_user_session_user_id` (`user_id`)
+);
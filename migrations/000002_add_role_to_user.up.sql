@@ -0,0 +1 @@
+ALTER TABLE `user` ADD COLUMN `role` VARCHAR(32) NOT NULL DEFAULT 'user' AFTER `status`;
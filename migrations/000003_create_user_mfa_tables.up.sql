@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS `user_mfa` (
+    `user_id` CHAR(36) NOT NULL,
+    `secret` VARCHAR(255) NOT NULL,
+    `enabled` TINYINT(1) NOT NULL DEFAULT 0,
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`user_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `user_mfa_recovery_code` (
+    `id` CHAR(36) NOT NULL,
+    `user_id` CHAR(36) NOT NULL,
+    `code_hash` CHAR(64) NOT NULL,
+    `used_at` DATETIME NULL,
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uq_user_mfa_recovery_code_user_id_code_hash` (`user_id`, `code_hash`)
+);
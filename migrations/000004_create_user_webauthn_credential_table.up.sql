@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS `user_webauthn_credential` (
+    `id` CHAR(36) NOT NULL,
+    `user_id` CHAR(36) NOT NULL,
+    `credential_id` VARBINARY(1023) NOT NULL,
+    `public_key` BLOB NOT NULL,
+    `attestation_type` VARCHAR(32) NOT NULL DEFAULT '',
+    `aaguid` VARBINARY(16) NOT NULL,
+    `sign_count` INT UNSIGNED NOT NULL DEFAULT 0,
+    `transports` VARCHAR(255) NOT NULL DEFAULT '',
+    `name` VARCHAR(64) NOT NULL DEFAULT '',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `last_used_at` DATETIME NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uq_user_webauthn_credential_credential_id` (`credential_id`),
+    KEY `idx_user_webauthn_credential_user_id` (`user_id`)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS `user_audit_log` (
+    `id` CHAR(36) NOT NULL,
+    `user_id` CHAR(36) NOT NULL,
+    `action` VARCHAR(32) NOT NULL,
+    `reason` VARCHAR(255) NOT NULL,
+    `actor` VARCHAR(36) NOT NULL,
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_user_audit_log_user_id_created_at` (`user_id`, `created_at`)
+);
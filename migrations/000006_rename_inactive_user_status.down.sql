@@ -0,0 +1 @@
+UPDATE `user` SET `status` = 'inactive' WHERE `status` IN ('pending_verification', 'deactivated', 'locked', 'suspended', 'deleted');
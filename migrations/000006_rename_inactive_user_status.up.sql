@@ -0,0 +1 @@
+UPDATE `user` SET `status` = 'deactivated' WHERE `status` = 'inactive';
@@ -0,0 +1,3 @@
+ALTER TABLE `user`
+    DROP INDEX `ft_user_email_normalized_fullname`,
+    DROP COLUMN `email_normalized`;
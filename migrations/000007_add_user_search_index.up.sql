@@ -0,0 +1,3 @@
+ALTER TABLE `user`
+    ADD COLUMN `email_normalized` VARCHAR(255) GENERATED ALWAYS AS (LOWER(`email`)) STORED AFTER `email`,
+    ADD FULLTEXT INDEX `ft_user_email_normalized_fullname` (`email_normalized`, `fullname`);
@@ -0,0 +1 @@
+ALTER TABLE `user` DROP COLUMN `status_before_delete`;
@@ -0,0 +1 @@
+ALTER TABLE `user` ADD COLUMN `status_before_delete` VARCHAR(32) NULL AFTER `deleted_by`;
@@ -0,0 +1,127 @@
+package crypt
+
+import (
+	"golang.org/x/crypto/argon2"
+
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const argon2idId = "argon2id"
+
+var ErrInvalidArgon2idHash = errors.New("invalid argon2id hash")
+
+// Bounds of the parameters a stored hash may carry. Hashes are checked against
+// them before being computed, so a malformed or hostile hash can neither panic
+// nor make a single comparison exhaust memory or CPU. The configured parameters
+// must stay within them too.
+const (
+	maxArgon2idMemory     = 1024 * 1024 // KiB, 1 GiB
+	maxArgon2idIterations = 64
+	minArgon2idSaltLength = 8
+	maxArgon2idSaltLength = 128
+	minArgon2idKeyLength  = 16
+	maxArgon2idKeyLength  = 128
+)
+
+// Argon2id hashes passwords with Argon2id. Its hashes are PHC strings:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+type Argon2id struct {
+	// Memory is the memory cost in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2id returns Argon2id with the parameters recommended by RFC 9106
+// for memory constrained environments.
+func DefaultArgon2id() Argon2id {
+	return Argon2id{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+func (a Argon2id) Id() string {
+	return argon2idId
+}
+
+func (a Argon2id) Hash(plaintext string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(plaintext), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idId,
+		argon2.Version,
+		a.Memory,
+		a.Iterations,
+		a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a Argon2id) Compare(plaintext, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	otherKey := argon2.IDKey([]byte(plaintext), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+}
+
+func (a Argon2id) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$"+argon2idId+"$")
+}
+
+func (a Argon2id) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params != a
+}
+
+// decodeArgon2id parses a PHC string into its parameters, salt and key, and
+// checks the parameters are within bounds.
+func decodeArgon2id(encoded string) (params Argon2id, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != argon2idId {
+		return Argon2id{}, nil, nil, ErrInvalidArgon2idHash
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2id{}, nil, nil, ErrInvalidArgon2idHash
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Argon2id{}, nil, nil, ErrInvalidArgon2idHash
+	}
+	if params.Memory > maxArgon2idMemory || params.Iterations < 1 || params.Iterations > maxArgon2idIterations || params.Parallelism < 1 {
+		return Argon2id{}, nil, nil, ErrInvalidArgon2idHash
+	}
+	// the lengths are checked on the encoded strings so oversized ones are not
+	// decoded at all
+	if len(parts[4]) > base64.RawStdEncoding.EncodedLen(maxArgon2idSaltLength) || len(parts[5]) > base64.RawStdEncoding.EncodedLen(maxArgon2idKeyLength) {
+		return Argon2id{}, nil, nil, ErrInvalidArgon2idHash
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil || len(salt) < minArgon2idSaltLength {
+		return Argon2id{}, nil, nil, ErrInvalidArgon2idHash
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) < minArgon2idKeyLength {
+		return Argon2id{}, nil, nil, ErrInvalidArgon2idHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -1,16 +1,50 @@
 package crypt
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"golang.org/x/crypto/bcrypt"
 
-func HashByBcrypt(plaintext string) (hashed string, err error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(plaintext), bcrypt.MinCost)
+	"errors"
+	"strings"
+)
+
+const bcryptId = "bcrypt"
+
+// Bcrypt hashes passwords with bcrypt. Its hashes use the modular crypt format
+// ($2a$<cost>$<salt+hash>), which carries the cost like a PHC string does.
+type Bcrypt struct {
+	Cost int
+}
+
+// DefaultBcrypt returns bcrypt with the default cost.
+func DefaultBcrypt() Bcrypt {
+	return Bcrypt{Cost: 12}
+}
+
+func (b Bcrypt) Id() string {
+	return bcryptId
+}
+
+func (b Bcrypt) Hash(plaintext string) (string, error) {
+	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(plaintext), b.Cost)
 	return string(hashedBytes), err
 }
 
-func CompareBcrypt(plaintext, hash string) (match bool, err error) {
-	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(plaintext))
+func (b Bcrypt) Compare(plaintext, encoded string) (match bool, err error) {
+	err = bcrypt.CompareHashAndPassword([]byte(encoded), []byte(plaintext))
 	if err == nil {
-		match = true
+		return true, nil
+	}
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
 	}
-	return
+	return false, err
+}
+
+func (b Bcrypt) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (b Bcrypt) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.Cost
 }
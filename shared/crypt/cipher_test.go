@@ -0,0 +1,33 @@
+package crypt_test
+
+import (
+	"testing"
+
+	"github.com/IlhamRobyana/user/shared/crypt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCipher(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		encrypted, err := crypt.Encrypt("JBSWY3DPEHPK3PXP", "secret")
+		assert.NoError(t, err)
+		assert.NotContains(t, encrypted, "JBSWY3DPEHPK3PXP")
+
+		decrypted, err := crypt.Decrypt(encrypted, "secret")
+		assert.NoError(t, err)
+		assert.Equal(t, "JBSWY3DPEHPK3PXP", decrypted)
+	})
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		encrypted, err := crypt.Encrypt("JBSWY3DPEHPK3PXP", "secret")
+		assert.NoError(t, err)
+
+		_, err = crypt.Decrypt(encrypted, "other-secret")
+		assert.Error(t, err)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		_, err := crypt.Decrypt("not base64!", "secret")
+		assert.ErrorIs(t, err, crypt.ErrMalformedCiphertext)
+	})
+}
@@ -0,0 +1,156 @@
+package crypt
+
+import (
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
+
+	"errors"
+	"sync"
+
+	"github.com/IlhamRobyana/user/configs"
+)
+
+var ErrUnknownHashAlgorithm = errors.New("unknown password hash algorithm")
+
+// PasswordAlgorithm is a password hashing scheme producing self-describing
+// encoded hashes, so the scheme and its parameters can be read back from them.
+type PasswordAlgorithm interface {
+	// Id is the identifier the algorithm is configured by.
+	Id() string
+	// Hash returns the encoded hash of plaintext.
+	Hash(plaintext string) (string, error)
+	// Compare reports whether plaintext matches the encoded hash.
+	Compare(plaintext, encoded string) (bool, error)
+	// Identifies reports whether the encoded hash was produced by this algorithm.
+	Identifies(encoded string) bool
+	// NeedsRehash reports whether the encoded hash was produced with other
+	// parameters than the current ones.
+	NeedsRehash(encoded string) bool
+}
+
+// PasswordHasher hashes new passwords with its preferred algorithm and verifies
+// hashes of any algorithm it knows.
+type PasswordHasher struct {
+	preferred  PasswordAlgorithm
+	algorithms []PasswordAlgorithm
+}
+
+// NewPasswordHasher returns a hasher hashing with preferred. Hashes produced by
+// the other algorithms can still be verified and are reported as needing a rehash.
+func NewPasswordHasher(preferred PasswordAlgorithm, others ...PasswordAlgorithm) *PasswordHasher {
+	return &PasswordHasher{
+		preferred:  preferred,
+		algorithms: append([]PasswordAlgorithm{preferred}, others...),
+	}
+}
+
+func (h *PasswordHasher) Hash(plaintext string) (string, error) {
+	return h.preferred.Hash(plaintext)
+}
+
+func (h *PasswordHasher) Compare(plaintext, encoded string) (bool, error) {
+	algorithm := h.identify(encoded)
+	if algorithm == nil {
+		return false, ErrUnknownHashAlgorithm
+	}
+	return algorithm.Compare(plaintext, encoded)
+}
+
+func (h *PasswordHasher) NeedsRehash(encoded string) bool {
+	if !h.preferred.Identifies(encoded) {
+		return true
+	}
+	return h.preferred.NeedsRehash(encoded)
+}
+
+func (h *PasswordHasher) identify(encoded string) PasswordAlgorithm {
+	for _, algorithm := range h.algorithms {
+		if algorithm.Identifies(encoded) {
+			return algorithm
+		}
+	}
+	return nil
+}
+
+var (
+	passwordHasherMu sync.RWMutex
+	passwordHasher   = NewPasswordHasher(DefaultArgon2id(), DefaultBcrypt())
+)
+
+// SetPasswordHasher sets the hasher used by HashPassword, ComparePassword and
+// PasswordNeedsRehash.
+func SetPasswordHasher(hasher *PasswordHasher) {
+	passwordHasherMu.Lock()
+	defer passwordHasherMu.Unlock()
+	passwordHasher = hasher
+}
+
+// InitPasswordHasher sets the password hasher from the configuration.
+func InitPasswordHasher(config *configs.Config) {
+	hashConfig := config.Auth.PasswordHash
+	argon2id := DefaultArgon2id()
+	if hashConfig.Argon2id.Memory > 0 {
+		argon2id.Memory = hashConfig.Argon2id.Memory
+	}
+	if hashConfig.Argon2id.Iterations > 0 {
+		argon2id.Iterations = hashConfig.Argon2id.Iterations
+	}
+	if hashConfig.Argon2id.Parallelism > 0 {
+		argon2id.Parallelism = hashConfig.Argon2id.Parallelism
+	}
+	if hashConfig.Argon2id.SaltLength > 0 {
+		argon2id.SaltLength = hashConfig.Argon2id.SaltLength
+	}
+	if hashConfig.Argon2id.KeyLength > 0 {
+		argon2id.KeyLength = hashConfig.Argon2id.KeyLength
+	}
+	bcrypt := DefaultBcrypt()
+	if hashConfig.Bcrypt.Cost > 0 {
+		bcrypt.Cost = hashConfig.Bcrypt.Cost
+	}
+
+	hasher := NewPasswordHasher(argon2id, bcrypt)
+	if hashConfig.Algorithm == bcrypt.Id() {
+		hasher = NewPasswordHasher(bcrypt, argon2id)
+	} else if hashConfig.Algorithm != "" && hashConfig.Algorithm != argon2id.Id() {
+		log.Warn().Str("algorithm", hashConfig.Algorithm).Msg("Unknown password hash algorithm, using argon2id.")
+	}
+	SetPasswordHasher(hasher)
+}
+
+// HashPassword hashes plaintext with the configured password hasher.
+func HashPassword(plaintext string) (string, error) {
+	passwordHasherMu.RLock()
+	defer passwordHasherMu.RUnlock()
+	return passwordHasher.Hash(plaintext)
+}
+
+// ComparePassword reports whether plaintext matches the encoded hash.
+func ComparePassword(plaintext, encoded string) (bool, error) {
+	passwordHasherMu.RLock()
+	defer passwordHasherMu.RUnlock()
+	return passwordHasher.Compare(plaintext, encoded)
+}
+
+// PasswordNeedsRehash reports whether the encoded hash is outdated and should be
+// replaced by a hash of the configured algorithm and parameters.
+func PasswordNeedsRehash(encoded string) bool {
+	passwordHasherMu.RLock()
+	defer passwordHasherMu.RUnlock()
+	return passwordHasher.NeedsRehash(encoded)
+}
+
+// IsPasswordHash reports whether encoded is a well-formed hash of a known
+// algorithm, so it can be stored as is when users are imported with their
+// passwords already hashed.
+func IsPasswordHash(encoded string) bool {
+	switch {
+	case DefaultArgon2id().Identifies(encoded):
+		_, _, _, err := decodeArgon2id(encoded)
+		return err == nil
+	case DefaultBcrypt().Identifies(encoded):
+		_, err := bcrypt.Cost([]byte(encoded))
+		return err == nil
+	}
+	return false
+}
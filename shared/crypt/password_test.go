@@ -0,0 +1,116 @@
+package crypt_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/IlhamRobyana/user/shared/crypt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	argon2id := crypt.Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	bcrypt := crypt.Bcrypt{Cost: 4}
+
+	t.Run("Argon2id", func(t *testing.T) {
+		hasher := crypt.NewPasswordHasher(argon2id, bcrypt)
+		hashed, err := hasher.Hash("password123")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+		match, err := hasher.Compare("password123", hashed)
+		assert.NoError(t, err)
+		assert.True(t, match)
+
+		match, err = hasher.Compare("wrong_password", hashed)
+		assert.NoError(t, err)
+		assert.False(t, match)
+		assert.False(t, hasher.NeedsRehash(hashed))
+	})
+
+	t.Run("Bcrypt", func(t *testing.T) {
+		hasher := crypt.NewPasswordHasher(bcrypt, argon2id)
+		hashed, err := hasher.Hash("password123")
+		assert.NoError(t, err)
+
+		match, err := hasher.Compare("password123", hashed)
+		assert.NoError(t, err)
+		assert.True(t, match)
+
+		match, err = hasher.Compare("wrong_password", hashed)
+		assert.NoError(t, err)
+		assert.False(t, match)
+		assert.False(t, hasher.NeedsRehash(hashed))
+	})
+
+	t.Run("Rehash Outdated Parameters", func(t *testing.T) {
+		hashed, err := crypt.NewPasswordHasher(argon2id).Hash("password123")
+		assert.NoError(t, err)
+
+		stronger := argon2id
+		stronger.Iterations = 2
+		hasher := crypt.NewPasswordHasher(stronger)
+		match, err := hasher.Compare("password123", hashed)
+		assert.NoError(t, err)
+		assert.True(t, match)
+		assert.True(t, hasher.NeedsRehash(hashed))
+	})
+
+	t.Run("Rehash Other Algorithm", func(t *testing.T) {
+		hashed, err := bcrypt.Hash("password123")
+		assert.NoError(t, err)
+
+		hasher := crypt.NewPasswordHasher(argon2id, bcrypt)
+		match, err := hasher.Compare("password123", hashed)
+		assert.NoError(t, err)
+		assert.True(t, match)
+		assert.True(t, hasher.NeedsRehash(hashed))
+	})
+
+	t.Run("Unknown Algorithm", func(t *testing.T) {
+		hasher := crypt.NewPasswordHasher(argon2id)
+		_, err := hasher.Compare("password123", "$md5$abc")
+		assert.ErrorIs(t, err, crypt.ErrUnknownHashAlgorithm)
+	})
+}
+
+func TestIsPasswordHash(t *testing.T) {
+	argon2Hash, err := crypt.Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}.Hash("password123")
+	assert.NoError(t, err)
+	bcryptHash, err := crypt.Bcrypt{Cost: 4}.Hash("password123")
+	assert.NoError(t, err)
+
+	assert.True(t, crypt.IsPasswordHash(argon2Hash))
+	assert.True(t, crypt.IsPasswordHash(bcryptHash))
+	assert.False(t, crypt.IsPasswordHash("password123"))
+	assert.False(t, crypt.IsPasswordHash("$argon2id$v=19$m=65536"))
+	assert.False(t, crypt.IsPasswordHash("$2a$10$short"))
+	assert.False(t, crypt.IsPasswordHash("$md5$abc"))
+
+	salt := base64.RawStdEncoding.EncodeToString(make([]byte, 16))
+	key := base64.RawStdEncoding.EncodeToString(make([]byte, 32))
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"No iterations", "$argon2id$v=19$m=65536,t=0,p=2$" + salt + "$" + key},
+		{"Too many iterations", "$argon2id$v=19$m=65536,t=1000000,p=2$" + salt + "$" + key},
+		{"No parallelism", "$argon2id$v=19$m=65536,t=3,p=0$" + salt + "$" + key},
+		{"Too much memory", "$argon2id$v=19$m=4294967295,t=3,p=2$" + salt + "$" + key},
+		{"Short salt", "$argon2id$v=19$m=65536,t=3,p=2$" + base64.RawStdEncoding.EncodeToString(make([]byte, 4)) + "$" + key},
+		{"Long salt", "$argon2id$v=19$m=65536,t=3,p=2$" + base64.RawStdEncoding.EncodeToString(make([]byte, 4096)) + "$" + key},
+		{"Empty key", "$argon2id$v=19$m=65536,t=3,p=2$" + salt + "$"},
+		{"Long key", "$argon2id$v=19$m=65536,t=3,p=2$" + salt + "$" + base64.RawStdEncoding.EncodeToString(make([]byte, 1<<20))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.False(t, crypt.IsPasswordHash(tt.encoded))
+
+			// comparing must fail instead of panicking or allocating the memory
+			match, err := crypt.NewPasswordHasher(crypt.DefaultArgon2id()).Compare("password123", tt.encoded)
+			assert.ErrorIs(t, err, crypt.ErrInvalidArgon2idHash)
+			assert.False(t, match)
+		})
+	}
+}
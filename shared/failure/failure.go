@@ -21,9 +21,10 @@ var (
 
 // Failure is a wrapper for error messages and codes using standard HTTP response codes.
 type Failure struct {
-	Code      int    `json:"code"`
-	Message   string `json:"message"`
-	ErrorCode string `json:"errorCode"`
+	Code      int                 `json:"code"`
+	Message   string              `json:"message"`
+	ErrorCode string              `json:"errorCode"`
+	Fields    map[string][]string `json:"fields,omitempty"`
 }
 
 // Error returns the error code and message in a formatted string.
@@ -31,8 +32,13 @@ func (e *Failure) Error() string {
 	return fmt.Sprintf("%s: %s", http.StatusText(e.Code), e.Message)
 }
 
-// BadRequest returns a new Failure with code for bad requests.
+// BadRequest returns a new Failure with code for bad requests. Bad request
+// failures are returned as they are.
 func BadRequest(err error) error {
+	var f *Failure
+	if errors.As(err, &f) && f.Code == http.StatusBadRequest {
+		return f
+	}
 	if err != nil {
 		return &Failure{
 			Code:    http.StatusBadRequest,
@@ -50,6 +56,16 @@ func BadRequestFromString(msg string) error {
 	}
 }
 
+// ValidationFailed returns a new Failure with code for bad requests listing the
+// reasons per invalid field.
+func ValidationFailed(fields map[string][]string) error {
+	return &Failure{
+		Code:    http.StatusBadRequest,
+		Message: "validation failed",
+		Fields:  fields,
+	}
+}
+
 // Unauthorized returns a new Failure with code for unauthorized requests.
 func Unauthorized(msg string) error {
 	return &Failure{
@@ -101,6 +117,25 @@ func Forbidden(msg string) error {
 	}
 }
 
+// TooManyRequests returns a new Failure with code for rate limited requests.
+func TooManyRequests(msg string) error {
+	return &Failure{
+		Code:    http.StatusTooManyRequests,
+		Message: msg,
+	}
+}
+
+// WithErrorCode returns a copy of a Failure with an application specific error
+// code clients can match on. Other errors are returned as they are.
+func WithErrorCode(err error, errorCode string) error {
+	if f, ok := err.(*Failure); ok {
+		withCode := *f
+		withCode.ErrorCode = errorCode
+		return &withCode
+	}
+	return err
+}
+
 // GetCode returns the error code of an error interface.
 func GetCode(err error) int {
 	if f, ok := err.(*Failure); ok {
@@ -0,0 +1,116 @@
+package notifier
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/IlhamRobyana/user/configs"
+	"github.com/IlhamRobyana/user/shared"
+)
+
+// TopicSend is the PubSub topic notifications are queued on.
+const TopicSend = "notification.send"
+
+// ErrQueueFull is returned by Dispatch when the notification could not be queued.
+var ErrQueueFull = errors.New("notification queue is full")
+
+// Templates shipped with the service.
+const (
+	TemplateVerifyEmail        = "verify-email"
+	TemplateResetPassword      = "reset-password"
+	TemplateMagicLink          = "magic-link"
+	TemplateAccountLocked      = "account-locked"
+	TemplateAccountDeactivated = "account-deactivated"
+)
+
+// Notification asks for a templated message to be sent to a recipient.
+type Notification struct {
+	Template string                 `json:"template"`
+	Version  int                    `json:"version,omitempty"`
+	Locale   string                 `json:"locale,omitempty"`
+	To       string                 `json:"to"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+// Dispatcher queues notifications on the PubSub worker pool, then renders and
+// sends them in the background so callers do not wait on the Notifier.
+type Dispatcher struct {
+	pubsub      shared.PubSub
+	templates   *Templates
+	notifier    Notifier
+	sendTimeout time.Duration
+}
+
+// ProvideDispatcher is the provider for Dispatcher. It subscribes to TopicSend,
+// so it must be created before the PubSub is started.
+func ProvideDispatcher(config *configs.Config, pubsub shared.PubSub, templates *Templates, notifier Notifier) *Dispatcher {
+	d := &Dispatcher{
+		pubsub:      pubsub,
+		templates:   templates,
+		notifier:    notifier,
+		sendTimeout: config.Notifier.SendTimeout,
+	}
+	pubsub.SubscriberRegistry(TopicSend, d.process,
+		shared.SetMaxRetry(config.Notifier.MaxRetry),
+		shared.SetMaxDelayRetry(config.Notifier.RetryDelay),
+	)
+	return d
+}
+
+// Dispatch queues a notification. It never waits on the Notifier: when the queue
+// is full because sending is stalled, the notification is dropped and
+// ErrQueueFull returned. Rendering and delivery errors are only logged, since
+// they happen after Dispatch returned.
+func (d *Dispatcher) Dispatch(ctx context.Context, notification Notification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	if !d.pubsub.TryPublish(TopicSend, payload) {
+		log.Warn().Str("template", notification.Template).Msg("[Dispatcher] dropped notification, queue is full")
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// Send renders and sends a notification right away. It is meant for code that
+// already runs on the worker pool, such as event subscribers.
+func (d *Dispatcher) Send(ctx context.Context, notification Notification) error {
+	message, err := d.templates.Render(notification)
+	if err != nil {
+		return err
+	}
+	return d.send(ctx, message)
+}
+
+func (d *Dispatcher) send(ctx context.Context, message Message) error {
+	if d.sendTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.sendTimeout)
+		defer cancel()
+	}
+	return d.notifier.Send(ctx, message)
+}
+
+func (d *Dispatcher) process(payload []byte) error {
+	var notification Notification
+	if err := json.Unmarshal(payload, &notification); err != nil {
+		log.Error().Err(err).Msg("[Dispatcher] failed decode notification")
+		return nil
+	}
+	message, err := d.templates.Render(notification)
+	if err != nil {
+		// retrying does not fix a broken template
+		log.Error().Err(err).Str("template", notification.Template).Msg("[Dispatcher] failed render notification")
+		return nil
+	}
+	if err = d.send(context.Background(), message); err != nil {
+		log.Error().Err(err).Str("template", notification.Template).Msg("[Dispatcher] failed send notification")
+		return err
+	}
+	return nil
+}
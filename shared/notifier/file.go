@@ -0,0 +1,38 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileNotifier writes every message as an .eml file into a directory instead of
+// sending it. It is meant for development, where the files can be opened with any
+// mail client.
+type FileNotifier struct {
+	dir  string
+	from string
+}
+
+// NewFileNotifier returns a FileNotifier writing into dir.
+func NewFileNotifier(dir, from string) *FileNotifier {
+	return &FileNotifier{dir: dir, from: from}
+}
+
+func (n *FileNotifier) Send(ctx context.Context, message Message) error {
+	now := time.Now()
+	body, err := buildMIME(n.from, message, now)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(n.dir, 0o755); err != nil {
+		return err
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(message.To)
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), recipient)
+	return os.WriteFile(filepath.Join(n.dir, name), body, 0o644)
+}
@@ -0,0 +1,51 @@
+package notifier
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryNotifier keeps sent messages in memory instead of sending them, so tests
+// can inspect them.
+type MemoryNotifier struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryNotifier returns an empty MemoryNotifier.
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{}
+}
+
+func (m *MemoryNotifier) Send(ctx context.Context, message Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (m *MemoryNotifier) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// Last returns the message sent most recently to the given address.
+func (m *MemoryNotifier) Last(to string) (Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+// Reset drops the messages sent so far.
+func (m *MemoryNotifier) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
@@ -0,0 +1,72 @@
+package notifier
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"context"
+
+	"github.com/IlhamRobyana/user/configs"
+)
+
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverLog    = "log"
+	DriverMemory = "memory"
+)
+
+// Message is a rendered email message. HTML is optional; when it is set the
+// message is sent with both a plain text and an HTML part. Id and Template
+// identify the message in logs without exposing its content.
+type Message struct {
+	Id       string
+	Template string
+	To       string
+	Subject  string
+	Text     string
+	HTML     string
+}
+
+// Notifier delivers messages.
+type Notifier interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// ProvideNotifier is the provider for the Notifier selected by the configured
+// driver. The driver must be set explicitly: messages carry login and reset
+// links, so a mistyped driver must not silently send them somewhere else.
+func ProvideNotifier(config *configs.Config) Notifier {
+	notifierConfig := config.Notifier
+	switch notifierConfig.Driver {
+	case DriverSMTP:
+		smtpConfig := notifierConfig.SMTP
+		return NewSMTPNotifier(smtpConfig.Host, smtpConfig.Port, smtpConfig.Username, smtpConfig.Password, notifierConfig.From)
+	case DriverFile:
+		return NewFileNotifier(notifierConfig.File.Dir, notifierConfig.From)
+	case DriverMemory:
+		return NewMemoryNotifier()
+	case DriverLog:
+		return NewLogNotifier()
+	}
+	log.Fatal().Str("driver", notifierConfig.Driver).Msg("Unknown notifier driver")
+	return nil
+}
+
+// LogNotifier logs that messages would have been sent instead of sending them.
+// Messages hold secrets such as login links, so only their template, recipient
+// and id are logged, never their content.
+type LogNotifier struct{}
+
+// NewLogNotifier returns a LogNotifier.
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Send(ctx context.Context, message Message) error {
+	log.Info().
+		Str("id", message.Id).
+		Str("template", message.Template).
+		Str("to", message.To).
+		Msg("Email message")
+	return nil
+}
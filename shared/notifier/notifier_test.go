@@ -0,0 +1,232 @@
+package notifier_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/IlhamRobyana/user/configs"
+	"github.com/IlhamRobyana/user/shared"
+	"github.com/IlhamRobyana/user/shared/notifier"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryNotifier(t *testing.T) {
+	ctx := context.Background()
+	n := notifier.NewMemoryNotifier()
+
+	assert.NoError(t, n.Send(ctx, notifier.Message{To: "jane@example.com", Subject: "First"}))
+	assert.NoError(t, n.Send(ctx, notifier.Message{To: "john@example.com", Subject: "Other"}))
+	assert.NoError(t, n.Send(ctx, notifier.Message{To: "jane@example.com", Subject: "Second"}))
+	assert.Len(t, n.Messages(), 3)
+
+	last, ok := n.Last("jane@example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "Second", last.Subject)
+
+	_, ok = n.Last("nobody@example.com")
+	assert.False(t, ok)
+
+	n.Reset()
+	assert.Empty(t, n.Messages())
+}
+
+func TestFileNotifier(t *testing.T) {
+	dir := t.TempDir()
+	n := notifier.NewFileNotifier(dir, "noreply@example.com")
+
+	err := n.Send(context.Background(), notifier.Message{
+		To:      "jane@example.com",
+		Subject: "Hello",
+		Text:    "Plain body",
+		HTML:    "<p>HTML body</p>",
+	})
+	assert.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	if assert.Len(t, files, 1) {
+		content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), "To: jane@example.com\r\n")
+		assert.Contains(t, string(content), "Content-Type: multipart/alternative")
+		assert.Contains(t, string(content), "Plain body")
+		assert.Contains(t, string(content), "<p>HTML body</p>")
+	}
+}
+
+func TestProvideNotifier(t *testing.T) {
+	config := &configs.Config{}
+	config.Notifier.Driver = notifier.DriverLog
+	assert.IsType(t, &notifier.LogNotifier{}, notifier.ProvideNotifier(config))
+
+	config.Notifier.Driver = notifier.DriverMemory
+	assert.IsType(t, &notifier.MemoryNotifier{}, notifier.ProvideNotifier(config))
+
+	config.Notifier.Driver = notifier.DriverFile
+	assert.IsType(t, &notifier.FileNotifier{}, notifier.ProvideNotifier(config))
+
+	config.Notifier.Driver = notifier.DriverSMTP
+	assert.IsType(t, &notifier.SMTPNotifier{}, notifier.ProvideNotifier(config))
+}
+
+func TestLogNotifier(t *testing.T) {
+	var output bytes.Buffer
+	logger := log.Logger
+	log.Logger = zerolog.New(&output)
+	defer func() { log.Logger = logger }()
+
+	err := notifier.NewLogNotifier().Send(context.Background(), notifier.Message{
+		Id:       "message-id",
+		Template: "magic-link/v1/en",
+		To:       "jane@example.com",
+		Subject:  "Your login link",
+		Text:     "http://localhost/magic-link?token=secret",
+		HTML:     "<a href=\"http://localhost/magic-link?token=secret\">Log in</a>",
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, output.String(), `"id":"message-id"`)
+	assert.Contains(t, output.String(), `"template":"magic-link/v1/en"`)
+	assert.Contains(t, output.String(), `"to":"jane@example.com"`)
+	assert.NotContains(t, output.String(), "secret")
+}
+
+func TestTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"welcome/v1/en/subject.txt": {Data: []byte("Welcome {{.Name}}\n")},
+		"welcome/v1/en/body.txt":    {Data: []byte("Hi {{.Name}}")},
+		"welcome/v2/en/subject.txt": {Data: []byte("Welcome aboard {{.Name}}\n")},
+		"welcome/v2/en/body.txt":    {Data: []byte("Hi {{.Name}}")},
+		"welcome/v2/en/body.html":   {Data: []byte("<p>Hi {{.Name}}</p>")},
+		"welcome/v2/id/subject.txt": {Data: []byte("Selamat datang {{.Name}}")},
+		"welcome/v2/id/body.txt":    {Data: []byte("Halo {{.Name}}")},
+	}
+	templates := notifier.NewTemplates(fsys, "en")
+	data := map[string]interface{}{"Name": "<Jane>"}
+
+	t.Run("Latest version", func(t *testing.T) {
+		message, err := templates.Render(notifier.Notification{Template: "welcome", To: "jane@example.com", Data: data})
+		assert.NoError(t, err)
+		assert.Equal(t, "jane@example.com", message.To)
+		assert.Equal(t, "welcome/v2/en", message.Template)
+		assert.NotEmpty(t, message.Id)
+		assert.Equal(t, "Welcome aboard <Jane>", message.Subject)
+		assert.Equal(t, "Hi <Jane>", message.Text)
+		assert.Equal(t, "<p>Hi &lt;Jane&gt;</p>", message.HTML)
+	})
+
+	t.Run("Pinned version", func(t *testing.T) {
+		message, err := templates.Render(notifier.Notification{Template: "welcome", Version: 1, Data: data})
+		assert.NoError(t, err)
+		assert.Equal(t, "Welcome <Jane>", message.Subject)
+		assert.Empty(t, message.HTML)
+	})
+
+	t.Run("Locale fallback", func(t *testing.T) {
+		message, err := templates.Render(notifier.Notification{Template: "welcome", Locale: "id-ID", Data: data})
+		assert.NoError(t, err)
+		assert.Equal(t, "Selamat datang <Jane>", message.Subject)
+
+		message, err = templates.Render(notifier.Notification{Template: "welcome", Locale: "fr", Data: data})
+		assert.NoError(t, err)
+		assert.Equal(t, "Welcome aboard <Jane>", message.Subject)
+	})
+
+	t.Run("Missing template", func(t *testing.T) {
+		_, err := templates.Render(notifier.Notification{Template: "farewell"})
+		assert.ErrorIs(t, err, notifier.ErrTemplateNotFound)
+
+		_, err = templates.Render(notifier.Notification{Template: "welcome", Version: 3})
+		assert.ErrorIs(t, err, notifier.ErrTemplateNotFound)
+	})
+
+	t.Run("Missing data", func(t *testing.T) {
+		_, err := templates.Render(notifier.Notification{Template: "welcome", Data: map[string]interface{}{}})
+		assert.Error(t, err)
+	})
+}
+
+func TestShippedTemplates(t *testing.T) {
+	config := &configs.Config{}
+	config.Notifier.DefaultLocale = "en"
+	templates := notifier.ProvideTemplates(config)
+	linkData := map[string]interface{}{"Name": "Jane", "URL": "http://localhost/link?token=abc", "ExpiresIn": "15m0s"}
+
+	tests := []struct {
+		template string
+		data     map[string]interface{}
+		expected string
+	}{
+		{notifier.TemplateVerifyEmail, linkData, "http://localhost/link?token=abc"},
+		{notifier.TemplateResetPassword, linkData, "http://localhost/link?token=abc"},
+		{notifier.TemplateMagicLink, linkData, "http://localhost/link?token=abc"},
+		{notifier.TemplateAccountLocked, map[string]interface{}{
+			"Name": "Jane", "Reason": "failed_login_attempts", "UnlockAt": "1 Jan 2024 10:02 UTC", "SupportEmail": "support@example.com",
+		}, "1 Jan 2024 10:02 UTC"},
+		{notifier.TemplateAccountDeactivated, map[string]interface{}{
+			"Name": "Jane", "Status": "locked", "Reason": "repeated_lockouts", "SupportEmail": "support@example.com",
+		}, "support@example.com"},
+	}
+	for _, tt := range tests {
+		for _, locale := range []string{"en", "id"} {
+			message, err := templates.Render(notifier.Notification{Template: tt.template, Locale: locale, Data: tt.data})
+			assert.NoError(t, err, "%s/%s", tt.template, locale)
+			assert.NotEmpty(t, message.Subject, "%s/%s", tt.template, locale)
+			assert.Contains(t, message.Text, tt.expected, "%s/%s", tt.template, locale)
+			assert.True(t, strings.Contains(message.HTML, tt.expected), "%s/%s", tt.template, locale)
+		}
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	config := &configs.Config{}
+	config.Notifier.DefaultLocale = "en"
+	pubsub := shared.New(1, shared.SetMessageBuffer(10))
+	memory := notifier.NewMemoryNotifier()
+	dispatcher := notifier.ProvideDispatcher(config, pubsub, notifier.ProvideTemplates(config), memory)
+	pubsub.Start()
+
+	err := dispatcher.Dispatch(context.Background(), notifier.Notification{
+		Template: notifier.TemplateMagicLink,
+		To:       "jane@example.com",
+		Data:     map[string]interface{}{"Name": "Jane", "URL": "http://localhost/magic-link", "ExpiresIn": "15m0s"},
+	})
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		_, ok := memory.Last("jane@example.com")
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	message, _ := memory.Last("jane@example.com")
+	assert.Equal(t, "Your login link", message.Subject)
+}
+
+func TestDispatcherQueueFull(t *testing.T) {
+	config := &configs.Config{}
+	config.Notifier.DefaultLocale = "en"
+	// the pool is never started, so nothing drains the queue
+	pubsub := shared.New(1, shared.SetMessageBuffer(1))
+	dispatcher := notifier.ProvideDispatcher(config, pubsub, notifier.ProvideTemplates(config), notifier.NewMemoryNotifier())
+	notification := notifier.Notification{Template: notifier.TemplateMagicLink, To: "jane@example.com"}
+
+	done := make(chan error, 2)
+	go func() {
+		done <- dispatcher.Dispatch(context.Background(), notification)
+		done <- dispatcher.Dispatch(context.Background(), notification)
+	}()
+	for _, expected := range []error{nil, notifier.ErrQueueFull} {
+		select {
+		case err := <-done:
+			assert.Equal(t, expected, err)
+		case <-time.After(time.Second):
+			t.Fatal("Dispatch blocked on a full queue")
+		}
+	}
+}
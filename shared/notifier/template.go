@@ -0,0 +1,188 @@
+package notifier
+
+import (
+	"github.com/google/uuid"
+
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+
+	"github.com/IlhamRobyana/user/configs"
+)
+
+// Templates are laid out as <name>/v<version>/<locale>/ with a subject.txt, a
+// body.txt and an optional body.html each.
+//
+//go:embed templates
+var templateFS embed.FS
+
+const (
+	subjectFile  = "subject.txt"
+	textBodyFile = "body.txt"
+	htmlBodyFile = "body.html"
+)
+
+// ErrTemplateNotFound is returned when no template matches a notification, not
+// even in the default locale.
+var ErrTemplateNotFound = errors.New("notifier: template not found")
+
+// Templates renders notifications into messages. Parsed templates are cached.
+type Templates struct {
+	fsys          fs.FS
+	defaultLocale string
+	mu            sync.RWMutex
+	cache         map[string]*messageTemplate
+}
+
+type messageTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// NewTemplates returns Templates reading from fsys. Notifications in a locale
+// without a template fall back to defaultLocale.
+func NewTemplates(fsys fs.FS, defaultLocale string) *Templates {
+	return &Templates{
+		fsys:          fsys,
+		defaultLocale: defaultLocale,
+		cache:         make(map[string]*messageTemplate),
+	}
+}
+
+// ProvideTemplates is the provider for the Templates shipped with the service.
+func ProvideTemplates(config *configs.Config) *Templates {
+	fsys, err := fs.Sub(templateFS, "templates")
+	if err != nil {
+		panic(err)
+	}
+	return NewTemplates(fsys, config.Notifier.DefaultLocale)
+}
+
+// Render renders a notification. A zero version selects the latest version of
+// the template. The locale falls back from a regional variant such as "id-ID" to
+// its language and then to the default locale.
+func (t *Templates) Render(notification Notification) (Message, error) {
+	version := notification.Version
+	if version == 0 {
+		latest, err := t.latestVersion(notification.Template)
+		if err != nil {
+			return Message{}, err
+		}
+		version = latest
+	}
+
+	var dir string
+	for _, locale := range t.localeCandidates(notification.Locale) {
+		candidate := path.Join(notification.Template, fmt.Sprintf("v%d", version), locale)
+		if _, err := fs.Stat(t.fsys, path.Join(candidate, subjectFile)); err == nil {
+			dir = candidate
+			break
+		}
+	}
+	if dir == "" {
+		return Message{}, fmt.Errorf("%w: %s v%d", ErrTemplateNotFound, notification.Template, version)
+	}
+
+	tmpl, err := t.load(dir)
+	if err != nil {
+		return Message{}, err
+	}
+
+	message := Message{Id: uuid.NewString(), Template: dir, To: notification.To}
+	var buf strings.Builder
+	if err = tmpl.subject.Execute(&buf, notification.Data); err != nil {
+		return Message{}, err
+	}
+	message.Subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err = tmpl.text.Execute(&buf, notification.Data); err != nil {
+		return Message{}, err
+	}
+	message.Text = buf.String()
+
+	if tmpl.html != nil {
+		buf.Reset()
+		if err = tmpl.html.Execute(&buf, notification.Data); err != nil {
+			return Message{}, err
+		}
+		message.HTML = buf.String()
+	}
+	return message, nil
+}
+
+func (t *Templates) latestVersion(name string) (int, error) {
+	entries, err := fs.ReadDir(t.fsys, name)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	latest := 0
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "v") {
+			continue
+		}
+		version, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "v"))
+		if err == nil && version > latest {
+			latest = version
+		}
+	}
+	if latest == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return latest, nil
+}
+
+func (t *Templates) localeCandidates(locale string) []string {
+	var candidates []string
+	if locale != "" {
+		candidates = append(candidates, locale)
+		if language, _, found := strings.Cut(locale, "-"); found {
+			candidates = append(candidates, language)
+		}
+	}
+	return append(candidates, t.defaultLocale)
+}
+
+func (t *Templates) load(dir string) (*messageTemplate, error) {
+	t.mu.RLock()
+	tmpl, ok := t.cache[dir]
+	t.mu.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	tmpl = new(messageTemplate)
+	var err error
+	tmpl.subject, err = t.parseText(dir, subjectFile)
+	if err != nil {
+		return nil, err
+	}
+	tmpl.text, err = t.parseText(dir, textBodyFile)
+	if err != nil {
+		return nil, err
+	}
+	htmlPath := path.Join(dir, htmlBodyFile)
+	if _, err = fs.Stat(t.fsys, htmlPath); err == nil {
+		tmpl.html, err = htmltemplate.New(htmlBodyFile).Option("missingkey=error").ParseFS(t.fsys, htmlPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	t.mu.Lock()
+	t.cache[dir] = tmpl
+	t.mu.Unlock()
+	return tmpl, nil
+}
+
+func (t *Templates) parseText(dir, file string) (*texttemplate.Template, error) {
+	return texttemplate.New(file).Option("missingkey=error").ParseFS(t.fsys, path.Join(dir, file))
+}
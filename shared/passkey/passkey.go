@@ -0,0 +1,204 @@
+package passkey
+
+import (
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidResponse = errors.New("invalid webauthn response")
+	// ErrCloned is returned when the signature counter of an authenticator did not
+	// increase, which hints at a cloned credential.
+	ErrCloned = errors.New("authenticator signature counter did not increase")
+)
+
+// Config describes the relying party, i.e. the site credentials are scoped to.
+type Config struct {
+	RPID          string
+	RPDisplayName string
+	RPOrigins     []string
+	// Timeout bounds how long a ceremony may take from begin to finish.
+	Timeout time.Duration
+}
+
+// User is an account credentials are registered for. ID is the user handle
+// stored on the authenticator.
+type User struct {
+	ID          []byte
+	Name        string
+	DisplayName string
+	Credentials []Credential
+}
+
+func (u User) WebAuthnID() []byte {
+	return u.ID
+}
+
+func (u User) WebAuthnName() string {
+	return u.Name
+}
+
+func (u User) WebAuthnDisplayName() string {
+	return u.DisplayName
+}
+
+func (u User) WebAuthnIcon() string {
+	return ""
+}
+
+func (u User) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.Credentials))
+	for _, credential := range u.Credentials {
+		credentials = append(credentials, credential.webAuthnCredential())
+	}
+	return credentials
+}
+
+// Credential is the public part of a key pair held by an authenticator.
+type Credential struct {
+	ID              []byte
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	SignCount       uint32
+	Transports      []string
+}
+
+func (c Credential) webAuthnCredential() webauthn.Credential {
+	transports := make([]protocol.AuthenticatorTransport, 0, len(c.Transports))
+	for _, transport := range c.Transports {
+		transports = append(transports, protocol.AuthenticatorTransport(transport))
+	}
+	return webauthn.Credential{
+		ID:              c.ID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transport:       transports,
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    c.AAGUID,
+			SignCount: c.SignCount,
+		},
+	}
+}
+
+func newCredential(credential webauthn.Credential) Credential {
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+	return Credential{
+		ID:              credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      transports,
+	}
+}
+
+// Session is the state of a ceremony kept by the relying party between its begin
+// and finish steps. It can be marshalled to JSON.
+type Session = webauthn.SessionData
+
+// RelyingParty runs the registration and login ceremonies.
+type RelyingParty struct {
+	webAuthn *webauthn.WebAuthn
+}
+
+// NewRelyingParty returns a relying party for the given configuration.
+func NewRelyingParty(config Config) (*RelyingParty, error) {
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    config.Timeout > 0,
+		Timeout:    config.Timeout,
+		TimeoutUVD: config.Timeout,
+	}
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          config.RPID,
+		RPDisplayName: config.RPDisplayName,
+		RPOrigins:     config.RPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &RelyingParty{webAuthn: webAuthn}, nil
+}
+
+// BeginRegistration returns the options to create a discoverable credential for
+// user with, excluding the authenticators already registered.
+func (rp *RelyingParty) BeginRegistration(user User) (*protocol.CredentialCreation, Session, error) {
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.Credentials))
+	for _, credential := range user.Credentials {
+		exclusions = append(exclusions, credential.webAuthnCredential().Descriptor())
+	}
+	creation, session, err := rp.webAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return nil, Session{}, err
+	}
+	return creation, *session, nil
+}
+
+// FinishRegistration verifies the JSON encoded response of the authenticator and
+// returns the new credential.
+func (rp *RelyingParty) FinishRegistration(user User, session Session, response []byte) (Credential, error) {
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(response))
+	if err != nil {
+		return Credential{}, invalidResponse(err)
+	}
+	credential, err := rp.webAuthn.CreateCredential(user, session, parsed)
+	if err != nil {
+		return Credential{}, invalidResponse(err)
+	}
+	return newCredential(*credential), nil
+}
+
+// BeginLogin returns the options to sign in with any discoverable credential.
+func (rp *RelyingParty) BeginLogin() (*protocol.CredentialAssertion, Session, error) {
+	assertion, session, err := rp.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, Session{}, err
+	}
+	return assertion, *session, nil
+}
+
+// FinishLogin verifies the JSON encoded assertion of the authenticator. The user
+// is looked up by resolve from the user handle the authenticator returned. The
+// credential is returned with its updated signature counter.
+func (rp *RelyingParty) FinishLogin(session Session, response []byte, resolve func(userID []byte) (User, error)) (User, Credential, error) {
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(response))
+	if err != nil {
+		return User{}, Credential{}, invalidResponse(err)
+	}
+
+	var user User
+	credential, err := rp.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		user, err = resolve(userHandle)
+		return user, err
+	}, session, parsed)
+	if err != nil {
+		return User{}, Credential{}, invalidResponse(err)
+	}
+	if credential.Authenticator.CloneWarning {
+		return User{}, Credential{}, ErrCloned
+	}
+	return user, newCredential(*credential), nil
+}
+
+func invalidResponse(err error) error {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) && protocolErr.DevInfo != "" {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidResponse, protocolErr.Details, protocolErr.DevInfo)
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+}
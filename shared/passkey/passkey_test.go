@@ -0,0 +1,218 @@
+package passkey_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IlhamRobyana/user/shared/passkey"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	flagUserPresent        = 0x01
+	flagUserVerified       = 0x04
+	flagAttestedCredential = 0x40
+)
+
+// softAuthenticator is a software authenticator holding a single discoverable
+// ES256 credential, answering ceremonies the way a browser and an authenticator
+// would together.
+type softAuthenticator struct {
+	origin       string
+	credentialID []byte
+	key          *ecdsa.PrivateKey
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T, origin string) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+	return &softAuthenticator{origin: origin, credentialID: credentialID, key: key}
+}
+
+func (a *softAuthenticator) create(t *testing.T, creation *protocol.CredentialCreation) []byte {
+	options := creation.Response
+	a.userHandle = []byte(options.User.ID.(protocol.URLEncodedBase64))
+
+	publicKey, err := cbor.Marshal(map[int]interface{}{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+
+	authData := a.authenticatorData(options.RelyingParty.ID, flagUserPresent|flagUserVerified|flagAttestedCredential)
+	authData = append(authData, make([]byte, 16)...) // aaguid
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, publicKey...)
+
+	attestationObject, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	require.NoError(t, err)
+
+	return a.marshal(t, map[string]string{
+		"clientDataJSON":    encode(a.clientData(t, "webauthn.create", options.Challenge)),
+		"attestationObject": encode(attestationObject),
+	})
+}
+
+func (a *softAuthenticator) get(t *testing.T, assertion *protocol.CredentialAssertion) []byte {
+	options := assertion.Response
+	a.signCount++
+	authData := a.authenticatorData(options.RelyingPartyID, flagUserPresent|flagUserVerified)
+	clientData := a.clientData(t, "webauthn.get", options.Challenge)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	return a.marshal(t, map[string]string{
+		"clientDataJSON":    encode(clientData),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) authenticatorData(rpID string, flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	authData := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(authData, a.signCount)
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony string, challenge protocol.URLEncodedBase64) []byte {
+	clientData, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge.String(),
+		"origin":    a.origin,
+	})
+	require.NoError(t, err)
+	return clientData
+}
+
+func (a *softAuthenticator) marshal(t *testing.T, response map[string]string) []byte {
+	credential, err := json.Marshal(map[string]interface{}{
+		"id":       encode(a.credentialID),
+		"rawId":    encode(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	require.NoError(t, err)
+	return credential
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestRelyingParty(t *testing.T) {
+	rp, err := passkey.NewRelyingParty(passkey.Config{
+		RPID:          "example.com",
+		RPDisplayName: "Example",
+		RPOrigins:     []string{"https://example.com"},
+		Timeout:       time.Minute,
+	})
+	require.NoError(t, err)
+
+	register := func(t *testing.T, authenticator *softAuthenticator, user passkey.User) passkey.Credential {
+		creation, session, err := rp.BeginRegistration(user)
+		require.NoError(t, err)
+		credential, err := rp.FinishRegistration(user, session, authenticator.create(t, creation))
+		require.NoError(t, err)
+		return credential
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		authenticator := newSoftAuthenticator(t, "https://example.com")
+		user := passkey.User{ID: []byte("user-1"), Name: "jane@example.com", DisplayName: "Jane Doe"}
+
+		credential := register(t, authenticator, user)
+		assert.Equal(t, authenticator.credentialID, credential.ID)
+		assert.Equal(t, "none", credential.AttestationType)
+		user.Credentials = []passkey.Credential{credential}
+
+		for i := 1; i <= 2; i++ {
+			assertion, session, err := rp.BeginLogin()
+			require.NoError(t, err)
+
+			loggedIn, used, err := rp.FinishLogin(session, authenticator.get(t, assertion), func(userID []byte) (passkey.User, error) {
+				assert.Equal(t, user.ID, userID)
+				return user, nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, loggedIn.ID)
+			assert.Equal(t, uint32(i), used.SignCount)
+			user.Credentials = []passkey.Credential{used}
+		}
+	})
+
+	t.Run("Excludes Registered Credentials", func(t *testing.T) {
+		user := passkey.User{ID: []byte("user-2"), Name: "john@example.com", DisplayName: "John Doe"}
+		user.Credentials = []passkey.Credential{register(t, newSoftAuthenticator(t, "https://example.com"), user)}
+
+		creation, _, err := rp.BeginRegistration(user)
+		require.NoError(t, err)
+		require.Len(t, creation.Response.CredentialExcludeList, 1)
+		assert.Equal(t, user.Credentials[0].ID, []byte(creation.Response.CredentialExcludeList[0].CredentialID))
+	})
+
+	t.Run("Wrong Origin", func(t *testing.T) {
+		authenticator := newSoftAuthenticator(t, "https://evil.example.org")
+		user := passkey.User{ID: []byte("user-3"), Name: "jane@example.com", DisplayName: "Jane Doe"}
+
+		creation, session, err := rp.BeginRegistration(user)
+		require.NoError(t, err)
+		_, err = rp.FinishRegistration(user, session, authenticator.create(t, creation))
+		assert.ErrorIs(t, err, passkey.ErrInvalidResponse)
+	})
+
+	t.Run("Unknown User", func(t *testing.T) {
+		authenticator := newSoftAuthenticator(t, "https://example.com")
+		user := passkey.User{ID: []byte("user-4"), Name: "jane@example.com", DisplayName: "Jane Doe"}
+		register(t, authenticator, user)
+
+		assertion, session, err := rp.BeginLogin()
+		require.NoError(t, err)
+		_, _, err = rp.FinishLogin(session, authenticator.get(t, assertion), func(userID []byte) (passkey.User, error) {
+			return passkey.User{}, errors.New("not found")
+		})
+		assert.ErrorIs(t, err, passkey.ErrInvalidResponse)
+	})
+
+	t.Run("Cloned Authenticator", func(t *testing.T) {
+		authenticator := newSoftAuthenticator(t, "https://example.com")
+		user := passkey.User{ID: []byte("user-5"), Name: "jane@example.com", DisplayName: "Jane Doe"}
+		credential := register(t, authenticator, user)
+		credential.SignCount = 5
+		user.Credentials = []passkey.Credential{credential}
+
+		assertion, session, err := rp.BeginLogin()
+		require.NoError(t, err)
+		_, _, err = rp.FinishLogin(session, authenticator.get(t, assertion), func(userID []byte) (passkey.User, error) {
+			return user, nil
+		})
+		assert.ErrorIs(t, err, passkey.ErrCloned)
+	})
+}
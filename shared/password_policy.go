@@ -0,0 +1,200 @@
+package shared
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+
+	"bufio"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/IlhamRobyana/user/configs"
+	"github.com/IlhamRobyana/user/shared/crypt"
+)
+
+// PasswordTag is the validation tag checking a field against the password policy.
+const PasswordTag = "password"
+
+// PasswordHashTag is the validation tag checking a field holds a password hash of
+// a known algorithm.
+const PasswordHashTag = "password_hash"
+
+// bcryptMaxLength is the number of bytes bcrypt takes into account, longer
+// passwords would be silently truncated.
+const bcryptMaxLength = 72
+
+// PasswordPolicy describes the passwords accepted by the password validation tag.
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	DenyList      map[string]struct{}
+}
+
+// DefaultPasswordPolicy returns the policy used until InitPasswordPolicy is called.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:    8,
+		MaxLength:    bcryptMaxLength,
+		RequireUpper: true,
+		RequireLower: true,
+		RequireDigit: true,
+	}
+}
+
+// Violations returns the reasons why password breaks the policy. Personal values
+// such as the email or the full name of the user must not appear in the password.
+func (p PasswordPolicy) Violations(password string, personal ...string) []string {
+	var reasons []string
+	if len([]rune(password)) < p.MinLength {
+		reasons = append(reasons, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	maxLength := p.MaxLength
+	if maxLength <= 0 || maxLength > bcryptMaxLength {
+		maxLength = bcryptMaxLength
+	}
+	if len(password) > maxLength {
+		reasons = append(reasons, fmt.Sprintf("must be at most %d bytes long", maxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		reasons = append(reasons, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		reasons = append(reasons, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		reasons = append(reasons, "must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		reasons = append(reasons, "must contain a symbol")
+	}
+
+	if _, denied := p.DenyList[strings.ToLower(password)]; denied {
+		reasons = append(reasons, "is too common")
+	}
+	if containsPersonalValue(password, personal...) {
+		reasons = append(reasons, "must not contain your email or name")
+	}
+	return reasons
+}
+
+// containsPersonalValue reports whether password contains the email, the local
+// part of the email or any word of the name given in personal.
+func containsPersonalValue(password string, personal ...string) bool {
+	password = strings.ToLower(password)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		parts := strings.Fields(value)
+		if at := strings.Index(value, "@"); at > 0 {
+			parts = []string{value, value[:at]}
+		}
+		for _, part := range parts {
+			if len([]rune(part)) >= 3 && strings.Contains(password, part) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	passwordPolicyMu sync.RWMutex
+	passwordPolicy   = DefaultPasswordPolicy()
+)
+
+// GetPasswordPolicy returns the password policy in use.
+func GetPasswordPolicy() PasswordPolicy {
+	passwordPolicyMu.RLock()
+	defer passwordPolicyMu.RUnlock()
+	return passwordPolicy
+}
+
+// SetPasswordPolicy sets the password policy used by the password validation tag.
+func SetPasswordPolicy(policy PasswordPolicy) {
+	passwordPolicyMu.Lock()
+	defer passwordPolicyMu.Unlock()
+	passwordPolicy = policy
+}
+
+// InitPasswordPolicy sets the password policy from the configuration.
+func InitPasswordPolicy(config *configs.Config) {
+	policyConfig := config.Internal.PasswordPolicy
+	policy := PasswordPolicy{
+		MinLength:     policyConfig.MinLength,
+		MaxLength:     policyConfig.MaxLength,
+		RequireUpper:  policyConfig.RequireUpper,
+		RequireLower:  policyConfig.RequireLower,
+		RequireDigit:  policyConfig.RequireDigit,
+		RequireSymbol: policyConfig.RequireSymbol,
+	}
+	if policyConfig.DenyListFile != "" {
+		denyList, err := LoadPasswordDenyList(policyConfig.DenyListFile)
+		if err != nil {
+			log.Fatal().Err(err).Str("file", policyConfig.DenyListFile).Msg("Failed loading password deny-list")
+		}
+		policy.DenyList = denyList
+	}
+	log.Info().Int("denyList", len(policy.DenyList)).Msg("Password policy initialized.")
+	SetPasswordPolicy(policy)
+}
+
+// LoadPasswordDenyList reads one password per line from the file. Empty lines
+// and lines starting with # are ignored.
+func LoadPasswordDenyList(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	denyList := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		denyList[strings.ToLower(line)] = struct{}{}
+	}
+	return denyList, scanner.Err()
+}
+
+// validatePassword checks the field against the password policy. The Email and
+// Fullname fields of the same struct, when present, must not appear in it.
+func validatePassword(fl validator.FieldLevel) bool {
+	var personal []string
+	parent := reflect.Indirect(fl.Parent())
+	if parent.Kind() == reflect.Struct {
+		for _, name := range []string{"Email", "Fullname"} {
+			if field := parent.FieldByName(name); field.IsValid() && field.Kind() == reflect.String {
+				personal = append(personal, field.String())
+			}
+		}
+	}
+	return len(GetPasswordPolicy().Violations(fl.Field().String(), personal...)) == 0
+}
+
+func validatePasswordHash(fl validator.FieldLevel) bool {
+	return crypt.IsPasswordHash(fl.Field().String())
+}
@@ -0,0 +1,93 @@
+package shared_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IlhamRobyana/user/shared"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := shared.PasswordPolicy{
+		MinLength:     10,
+		MaxLength:     100,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		DenyList:      map[string]struct{}{"password123!a": {}},
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		assert.Empty(t, policy.Violations("Tr0ub4dor&3x", "jane@example.com", "Jane Doe"))
+	})
+
+	t.Run("Character Classes", func(t *testing.T) {
+		reasons := policy.Violations("abcdefghijkl")
+		assert.Contains(t, reasons, "must contain an uppercase letter")
+		assert.Contains(t, reasons, "must contain a digit")
+		assert.Contains(t, reasons, "must contain a symbol")
+		assert.NotContains(t, reasons, "must contain a lowercase letter")
+	})
+
+	t.Run("Length", func(t *testing.T) {
+		assert.Contains(t, policy.Violations("Ab1!"), "must be at least 10 characters long")
+		// the configured maximum is capped by what bcrypt can hash
+		long := "Ab1!" + string(make([]byte, 80))
+		assert.Contains(t, policy.Violations(long), "must be at most 72 bytes long")
+	})
+
+	t.Run("Deny List", func(t *testing.T) {
+		assert.Contains(t, policy.Violations("Password123!A"), "is too common")
+	})
+
+	t.Run("Personal Values", func(t *testing.T) {
+		assert.Contains(t, policy.Violations("Jane.Doe#2024", "jane@example.com"), "must not contain your email or name")
+		assert.Contains(t, policy.Violations("xX!Doe2024Xx", "jd@example.com", "Jane Doe"), "must not contain your email or name")
+	})
+
+	t.Run("Load Deny List", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "passwords.txt")
+		assert.NoError(t, os.WriteFile(path, []byte("# comment\nQwerty123\n\nletmein\n"), 0o600))
+
+		denyList, err := shared.LoadPasswordDenyList(path)
+		assert.NoError(t, err)
+		assert.Len(t, denyList, 2)
+		assert.Contains(t, denyList, "qwerty123")
+	})
+}
+
+func TestPasswordValidation(t *testing.T) {
+	type request struct {
+		Email    string `json:"email" validate:"required"`
+		Password string `json:"password" validate:"required,password"`
+	}
+	shared.SetPasswordPolicy(shared.DefaultPasswordPolicy())
+
+	t.Run("Success", func(t *testing.T) {
+		err := shared.GetValidator().Struct(request{Email: "jane@example.com", Password: "Tr0ub4dor&3x"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Field Reasons", func(t *testing.T) {
+		err := shared.ValidationError(shared.GetValidator().Struct(request{Email: "jane@example.com", Password: "jane"}))
+		assert.Equal(t, http.StatusBadRequest, failure.GetCode(err))
+
+		f, ok := err.(*failure.Failure)
+		assert.True(t, ok)
+		assert.Contains(t, f.Fields["password"], "must be at least 8 characters long")
+		assert.Contains(t, f.Fields["password"], "must contain an uppercase letter")
+	})
+
+	t.Run("Personal Values", func(t *testing.T) {
+		err := shared.ValidationError(shared.GetValidator().Struct(request{Email: "jane@example.com", Password: "Jane12345678"}))
+
+		f, ok := err.(*failure.Failure)
+		assert.True(t, ok)
+		assert.Equal(t, []string{"must not contain your email or name"}, f.Fields["password"])
+	})
+}
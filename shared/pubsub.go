@@ -4,6 +4,8 @@ package shared
 
 import (
 	"time"
+
+	"github.com/IlhamRobyana/user/configs"
 )
 
 type message struct {
@@ -157,6 +159,18 @@ func (p PubSub) Publish(topic string, payload []byte) {
 	}
 }
 
+// TryPublish queues a message without waiting for room in the buffer. It reports
+// false and drops the message when the buffer is full, so callers serving
+// requests are not held up by slow subscribers.
+func (p PubSub) TryPublish(topic string, payload []byte) bool {
+	select {
+	case p.message <- message{topic: topic, payload: payload}:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p PubSub) SubscriberRegistry(topicListener string, pr Process, opts ...func(*consumerConfig)) {
 	cfg := defaultConsumerConfig()
 
@@ -191,3 +205,10 @@ func (p PubSub) dispatch() {
 		response <- msg
 	}
 }
+
+// ProvidePubSub is the provider for the PubSub worker pool shared by the service.
+// Subscribers register while the service is wired up; the pool is started by the
+// HTTP server.
+func ProvidePubSub(config *configs.Config) PubSub {
+	return New(config.PubSub.Workers, SetMessageBuffer(config.PubSub.MessageBuffer))
+}
@@ -83,3 +83,13 @@ func TestPubSub(t *testing.T) {
 		assert.Equal(t, 1000, counter)
 	})
 }
+
+func TestPubSubTryPublish(t *testing.T) {
+	pubsub := shared.New(1, shared.SetMessageBuffer(2))
+	pubsub.SubscriberRegistry("test", func(message []byte) error { return nil })
+
+	// nothing consumes the messages before Start, so the buffer fills up
+	assert.True(t, pubsub.TryPublish("test", []byte("first")))
+	assert.True(t, pubsub.TryPublish("test", []byte("second")))
+	assert.False(t, pubsub.TryPublish("test", []byte("third")))
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	"context"
+	"os"
+	"time"
+)
+
+// acquireScript takes the lock when it is free and renews it when it is already
+// held by the same owner.
+var acquireScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
+// releaseScript drops the lock only when it is held by the owner.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisLocker is a Locker keeping its locks in Redis, shared by every instance of
+// the service.
+type RedisLocker struct {
+	client *redis.Client
+	prefix string
+	owner  string
+}
+
+// NewRedisLocker returns a Locker storing its locks in client under keys starting
+// with prefix. Every locker is a distinct owner.
+func NewRedisLocker(client *redis.Client, prefix string) *RedisLocker {
+	hostname, _ := os.Hostname()
+	return &RedisLocker{
+		client: client,
+		prefix: prefix,
+		owner:  hostname + "/" + uuid.NewString(),
+	}
+}
+
+// Acquire takes or renews the lock of key for ttl.
+func (l *RedisLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	held, err := acquireScript.Run(ctx, l.client, []string{l.prefix + key}, l.owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return held == 1, nil
+}
+
+// Release drops the lock of key when this locker holds it.
+func (l *RedisLocker) Release(ctx context.Context, key string) error {
+	return releaseScript.Run(ctx, l.client, []string{l.prefix + key}, l.owner).Err()
+}
@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IlhamRobyana/user/configs"
+	"github.com/IlhamRobyana/user/infras"
+)
+
+// Job is a task run periodically by the scheduler.
+type Job struct {
+	// Name identifies the job and its lock, it must be unique per scheduler.
+	Name string
+	// Interval is the time between two runs of the job.
+	Interval time.Duration
+	// Run does the work of a single run. Its context is cancelled when the run
+	// takes longer than the interval or the scheduler stops.
+	Run func(ctx context.Context) error
+}
+
+// Locker elects the instance that runs a job when the service runs on several
+// instances.
+type Locker interface {
+	// Acquire takes or renews the lock of key for ttl and reports whether this
+	// instance holds it.
+	Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Release gives up the lock of key when this instance holds it.
+	Release(ctx context.Context, key string) error
+}
+
+// Scheduler runs jobs periodically. On every tick a job only runs on the
+// instance that holds its lock, which it keeps renewing for as long as it runs,
+// so a job runs once per interval however many instances there are.
+type Scheduler struct {
+	locker Locker
+	jobs   []Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New returns a scheduler electing the instance that runs a job with locker.
+func New(locker Locker) *Scheduler {
+	return &Scheduler{locker: locker}
+}
+
+// ProvideScheduler is the provider for the scheduler shared by the service. Jobs
+// register while the service is wired up; the scheduler is started by the HTTP
+// server.
+func ProvideScheduler(config *configs.Config) *Scheduler {
+	return New(NewRedisLocker(infras.RedisNewClient(*config), config.Scheduler.LockPrefix))
+}
+
+// Register adds a job to the scheduler. Jobs must be registered before the
+// scheduler starts.
+func (s *Scheduler) Register(job Job) {
+	if job.Interval <= 0 {
+		panic(fmt.Sprintf("scheduler: job %q has no interval", job.Name))
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// Start runs every registered job once and then on every interval, until the
+// scheduler stops.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+}
+
+// Stop cancels the running jobs, waits for them to return and gives up their
+// locks so another instance takes over.
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.run(ctx, job)
+		select {
+		case <-ctx.Done():
+			if err := s.locker.Release(context.Background(), job.Name); err != nil {
+				log.Warn().Err(err).Str("job", job.Name).Msg("[Scheduler] failed release lock")
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run runs a job once when this instance holds its lock.
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	// the lock outlives the interval so the instance holding it renews it on
+	// its next tick, before any other instance may take it
+	leader, err := s.locker.Acquire(ctx, job.Name, job.Interval+job.Interval/2)
+	if err != nil {
+		log.Warn().Err(err).Str("job", job.Name).Msg("[Scheduler] failed acquire lock")
+		return
+	}
+	if !leader {
+		return
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, job.Interval)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Str("job", job.Name).Interface("panic", r).Msg("[Scheduler] job panicked")
+		}
+	}()
+	if err = job.Run(runCtx); err != nil {
+		log.Error().Err(err).Str("job", job.Name).Msg("[Scheduler] job failed")
+	}
+}
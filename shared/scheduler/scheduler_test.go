@@ -0,0 +1,156 @@
+package scheduler_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IlhamRobyana/user/shared/scheduler"
+	"github.com/stretchr/testify/assert"
+)
+
+// sharedLocker hands out locks shared by several schedulers, as Redis does for
+// several instances.
+type sharedLocker struct {
+	mu     sync.Mutex
+	owners map[string]*ownerLocker
+}
+
+type ownerLocker struct {
+	shared   *sharedLocker
+	released int32
+}
+
+func (l *sharedLocker) owner() *ownerLocker {
+	return &ownerLocker{shared: l}
+}
+
+func (o *ownerLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	o.shared.mu.Lock()
+	defer o.shared.mu.Unlock()
+	if holder, ok := o.shared.owners[key]; ok && holder != o {
+		return false, nil
+	}
+	o.shared.owners[key] = o
+	return true, nil
+}
+
+func (o *ownerLocker) Release(ctx context.Context, key string) error {
+	o.shared.mu.Lock()
+	defer o.shared.mu.Unlock()
+	if o.shared.owners[key] == o {
+		delete(o.shared.owners, key)
+		atomic.AddInt32(&o.released, 1)
+	}
+	return nil
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("Runs job on every interval", func(t *testing.T) {
+		locker := &sharedLocker{owners: map[string]*ownerLocker{}}
+		var runs int32
+		s := scheduler.New(locker.owner())
+		s.Register(scheduler.Job{
+			Name:     "count",
+			Interval: 20 * time.Millisecond,
+			Run: func(ctx context.Context) error {
+				atomic.AddInt32(&runs, 1)
+				return nil
+			},
+		})
+
+		s.Start()
+		time.Sleep(110 * time.Millisecond)
+		s.Stop()
+
+		assert.GreaterOrEqual(t, atomic.LoadInt32(&runs), int32(3))
+	})
+
+	t.Run("Only the leader runs the job", func(t *testing.T) {
+		locker := &sharedLocker{owners: map[string]*ownerLocker{}}
+		leaderLock, followerLock := locker.owner(), locker.owner()
+		var leaderRuns, followerRuns int32
+		job := func(runs *int32) scheduler.Job {
+			return scheduler.Job{
+				Name:     "purge",
+				Interval: 20 * time.Millisecond,
+				Run: func(ctx context.Context) error {
+					atomic.AddInt32(runs, 1)
+					return nil
+				},
+			}
+		}
+		leader := scheduler.New(leaderLock)
+		leader.Register(job(&leaderRuns))
+		follower := scheduler.New(followerLock)
+		follower.Register(job(&followerRuns))
+
+		leader.Start()
+		time.Sleep(10 * time.Millisecond)
+		follower.Start()
+		time.Sleep(60 * time.Millisecond)
+		leader.Stop()
+		assert.Positive(t, atomic.LoadInt32(&leaderRuns))
+		assert.Zero(t, atomic.LoadInt32(&followerRuns))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&leaderLock.released))
+
+		// the follower takes over once the leader is gone
+		time.Sleep(60 * time.Millisecond)
+		follower.Stop()
+		assert.Positive(t, atomic.LoadInt32(&followerRuns))
+	})
+
+	t.Run("Cancels runs on stop", func(t *testing.T) {
+		locker := &sharedLocker{owners: map[string]*ownerLocker{}}
+		cancelled := make(chan struct{})
+		s := scheduler.New(locker.owner())
+		s.Register(scheduler.Job{
+			Name:     "slow",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) error {
+				<-ctx.Done()
+				close(cancelled)
+				return ctx.Err()
+			},
+		})
+
+		s.Start()
+		time.Sleep(10 * time.Millisecond)
+		s.Stop()
+
+		select {
+		case <-cancelled:
+		default:
+			t.Fatal("run was not cancelled")
+		}
+	})
+
+	t.Run("Survives a panicking job", func(t *testing.T) {
+		locker := &sharedLocker{owners: map[string]*ownerLocker{}}
+		var runs int32
+		s := scheduler.New(locker.owner())
+		s.Register(scheduler.Job{
+			Name:     "panic",
+			Interval: 20 * time.Millisecond,
+			Run: func(ctx context.Context) error {
+				atomic.AddInt32(&runs, 1)
+				panic("boom")
+			},
+		})
+
+		s.Start()
+		time.Sleep(50 * time.Millisecond)
+		s.Stop()
+
+		assert.GreaterOrEqual(t, atomic.LoadInt32(&runs), int32(2))
+	})
+
+	t.Run("Rejects jobs without interval", func(t *testing.T) {
+		s := scheduler.New(&ownerLocker{})
+		assert.Panics(t, func() {
+			s.Register(scheduler.Job{Name: "never"})
+		})
+	})
+}
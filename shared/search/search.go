@@ -0,0 +1,131 @@
+// Package search ranks and highlights text matching a search query in pure Go,
+// for results that do not come from a full-text index.
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// MaxTerms is the number of terms of a query that are searched for, the rest is
+// ignored.
+const MaxTerms = 8
+
+// Scores of a term matching a token of a field.
+const (
+	scoreExact  = 2
+	scorePrefix = 1
+)
+
+// Terms splits a query into distinct lower-cased terms the same way fields are
+// split into tokens, on anything but letters and digits.
+func Terms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, term := range tokens(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+		if len(terms) == MaxTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// Score ranks fields against the terms of a query. Every term has to start a
+// token of one of the fields, terms matching a whole token rank higher. Fields
+// missing a term score zero.
+func Score(terms []string, fields ...string) float64 {
+	var fieldTokens [][]string
+	for _, field := range fields {
+		fieldTokens = append(fieldTokens, tokens(field))
+	}
+
+	var score float64
+	for _, term := range terms {
+		best := 0
+		for _, tokens := range fieldTokens {
+			for _, token := range tokens {
+				switch {
+				case token == term:
+					best = scoreExact
+				case strings.HasPrefix(token, term) && best < scorePrefix:
+					best = scorePrefix
+				}
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		score += float64(best)
+	}
+	return score
+}
+
+// Highlight returns text, HTML escaped, with the fragments matching the terms
+// wrapped in <mark> elements. Like Score, terms only match the start of a token.
+// It returns false when no term matches.
+func Highlight(text string, terms []string) (string, bool) {
+	runes := []rune(text)
+	marked := make([]bool, len(runes))
+	found := false
+	for i := range runes {
+		if i > 0 && isTokenRune(runes[i-1]) || !isTokenRune(runes[i]) {
+			continue
+		}
+		for _, term := range terms {
+			if n := matchPrefix(runes[i:], []rune(term)); n > 0 {
+				for j := i; j < i+n; j++ {
+					marked[j] = true
+				}
+				found = true
+			}
+		}
+	}
+	if !found {
+		return html.EscapeString(text), false
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && marked[j] == marked[i] {
+			j++
+		}
+		fragment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			fragment = "<mark>" + fragment + "</mark>"
+		}
+		b.WriteString(fragment)
+		i = j
+	}
+	return b.String(), true
+}
+
+// matchPrefix returns the length of term when text starts with it, ignoring case,
+// and zero otherwise.
+func matchPrefix(text, term []rune) int {
+	if len(term) == 0 || len(term) > len(text) {
+		return 0
+	}
+	for i, r := range term {
+		if unicode.ToLower(text[i]) != r {
+			return 0
+		}
+	}
+	return len(term)
+}
+
+func tokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !isTokenRune(r)
+	})
+}
+
+func isTokenRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,61 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/IlhamRobyana/user/shared/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"jane", "doe", "example", "com"}, search.Terms("  Jane DOE jane@Example.com "))
+	assert.Empty(t, search.Terms(" +-*@ "))
+	assert.Len(t, search.Terms("a b c d e f g h i j"), search.MaxTerms)
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		fields []string
+		score  float64
+	}{
+		{"Exact token", "jane", []string{"jane@example.com", "Jane Doe"}, 2},
+		{"Prefix of token", "jan", []string{"janet@example.com", "Janet Roe"}, 1},
+		{"Case differences", "JANE@EXAMPLE.COM", []string{"jane@example.com", "Jane Doe"}, 6},
+		{"Terms across fields", "doe example", []string{"jane@example.com", "Jane Doe"}, 4},
+		{"Missing term", "jane smith", []string{"jane@example.com", "Jane Doe"}, 0},
+		{"Middle of token", "ane", []string{"jane@example.com", "Jane Doe"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.score, search.Score(search.Terms(tt.query), tt.fields...))
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	t.Run("Marks token prefixes", func(t *testing.T) {
+		highlighted, ok := search.Highlight("Jane Doe-Janeway", search.Terms("jane"))
+		assert.True(t, ok)
+		assert.Equal(t, "<mark>Jane</mark> Doe-<mark>Jane</mark>way", highlighted)
+	})
+
+	t.Run("Merges adjacent matches", func(t *testing.T) {
+		highlighted, ok := search.Highlight("jane@example.com", search.Terms("jane example"))
+		assert.True(t, ok)
+		assert.Equal(t, "<mark>jane</mark>@<mark>example</mark>.com", highlighted)
+	})
+
+	t.Run("Escapes HTML", func(t *testing.T) {
+		highlighted, ok := search.Highlight("<b>Jane</b> & co", search.Terms("jane"))
+		assert.True(t, ok)
+		assert.Equal(t, "&lt;b&gt;<mark>Jane</mark>&lt;/b&gt; &amp; co", highlighted)
+	})
+
+	t.Run("No match", func(t *testing.T) {
+		highlighted, ok := search.Highlight("Jane Doe", search.Terms("smith"))
+		assert.False(t, ok)
+		assert.Equal(t, "Jane Doe", highlighted)
+	})
+}
@@ -0,0 +1,64 @@
+package token
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"errors"
+	"time"
+)
+
+// Audiences of the tokens sent to users by email. A token is only accepted for
+// the purpose it was issued for.
+const (
+	AudienceEmailVerification = "email-verification"
+	AudienceMagicLink         = "magic-link"
+)
+
+// EmailClaims is the set of claims carried by tokens sent to users by email.
+// Email binds the token to the address it was sent to.
+type EmailClaims struct {
+	UserID uuid.UUID `json:"userId"`
+	Email  string    `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// NewEmailClaims returns claims for audience sent to email of the given user which
+// expire after ttl.
+func NewEmailClaims(audience, issuer string, userID uuid.UUID, email string, ttl time.Duration) EmailClaims {
+	now := time.Now()
+	return EmailClaims{
+		UserID: userID,
+		Email:  email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    issuer,
+			Subject:   userID.String(),
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+}
+
+// SignEmail signs the claims with HMAC-SHA256 using secret.
+func SignEmail(claims EmailClaims, secret string) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// ParseEmail verifies the signature, the audience and the time based claims of
+// tokenStr and returns its claims.
+func ParseEmail(tokenStr, secret, audience string) (EmailClaims, error) {
+	var claims EmailClaims
+	_, err := jwt.ParseWithClaims(tokenStr, &claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(audience))
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return EmailClaims{}, ErrExpired
+		}
+		return EmailClaims{}, ErrInvalid
+	}
+	return claims, nil
+}
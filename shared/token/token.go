@@ -0,0 +1,77 @@
+package token
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrExpired = errors.New("token is expired")
+	ErrInvalid = errors.New("token is invalid")
+)
+
+// Claims is the set of claims carried by the access tokens issued by this service.
+// SessionID identifies the login the token was issued for.
+type Claims struct {
+	UserID    uuid.UUID `json:"userId"`
+	Status    string    `json:"status"`
+	SessionID uuid.UUID `json:"sessionId"`
+	jwt.RegisteredClaims
+}
+
+type contextKey string
+
+const claimsContextKey contextKey = "claims"
+
+// NewClaims returns claims for the given user session which expire after ttl.
+func NewClaims(issuer string, userID uuid.UUID, status string, sessionID uuid.UUID, ttl time.Duration) Claims {
+	now := time.Now()
+	return Claims{
+		UserID:    userID,
+		Status:    status,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    issuer,
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+}
+
+// Sign signs the claims with HMAC-SHA256 using secret.
+func Sign(claims Claims, secret string) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// Parse verifies the signature and the time based claims of tokenStr and returns its claims.
+func Parse(tokenStr, secret string) (Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(tokenStr, &claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return Claims{}, ErrExpired
+		}
+		return Claims{}, ErrInvalid
+	}
+	return claims, nil
+}
+
+// NewContext returns a copy of ctx carrying the verified claims.
+func NewContext(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey, claims)
+}
+
+// FromContext returns the verified claims carried by ctx, if any.
+func FromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(Claims)
+	return claims, ok
+}
@@ -0,0 +1,85 @@
+package token_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IlhamRobyana/user/shared/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken(t *testing.T) {
+	userID := uuid.New()
+	sessionID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		claims := token.NewClaims("evm/user", userID, "active", sessionID, time.Minute)
+		signed, err := token.Sign(claims, "secret")
+		assert.NoError(t, err)
+
+		parsed, err := token.Parse(signed, "secret")
+		assert.NoError(t, err)
+		assert.Equal(t, userID, parsed.UserID)
+		assert.Equal(t, "active", parsed.Status)
+		assert.Equal(t, sessionID, parsed.SessionID)
+		assert.Equal(t, claims.ID, parsed.ID)
+	})
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		signed, err := token.Sign(token.NewClaims("evm/user", userID, "active", sessionID, time.Minute), "secret")
+		assert.NoError(t, err)
+
+		_, err = token.Parse(signed, "other-secret")
+		assert.ErrorIs(t, err, token.ErrInvalid)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		signed, err := token.Sign(token.NewClaims("evm/user", userID, "active", sessionID, -time.Minute), "secret")
+		assert.NoError(t, err)
+
+		_, err = token.Parse(signed, "secret")
+		assert.ErrorIs(t, err, token.ErrExpired)
+	})
+}
+
+func TestEmailToken(t *testing.T) {
+	userID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		claims := token.NewEmailClaims(token.AudienceEmailVerification, "evm/user", userID, "jane@example.com", time.Hour)
+		signed, err := token.SignEmail(claims, "secret")
+		assert.NoError(t, err)
+
+		parsed, err := token.ParseEmail(signed, "secret", token.AudienceEmailVerification)
+		assert.NoError(t, err)
+		assert.Equal(t, userID, parsed.UserID)
+		assert.Equal(t, "jane@example.com", parsed.Email)
+	})
+
+	t.Run("Other Audience", func(t *testing.T) {
+		claims := token.NewEmailClaims(token.AudienceEmailVerification, "evm/user", userID, "jane@example.com", time.Hour)
+		signed, err := token.SignEmail(claims, "secret")
+		assert.NoError(t, err)
+
+		_, err = token.ParseEmail(signed, "secret", token.AudienceMagicLink)
+		assert.ErrorIs(t, err, token.ErrInvalid)
+	})
+
+	t.Run("Access Token", func(t *testing.T) {
+		signed, err := token.Sign(token.NewClaims("evm/user", userID, "active", uuid.New(), time.Minute), "secret")
+		assert.NoError(t, err)
+
+		_, err = token.ParseEmail(signed, "secret", token.AudienceMagicLink)
+		assert.ErrorIs(t, err, token.ErrInvalid)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		claims := token.NewEmailClaims(token.AudienceMagicLink, "evm/user", userID, "jane@example.com", -time.Minute)
+		signed, err := token.SignEmail(claims, "secret")
+		assert.NoError(t, err)
+
+		_, err = token.ParseEmail(signed, "secret", token.AudienceMagicLink)
+		assert.ErrorIs(t, err, token.ErrExpired)
+	})
+}
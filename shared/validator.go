@@ -3,10 +3,16 @@
 package shared
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"sync"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/rs/zerolog/log"
+
+	"github.com/IlhamRobyana/user/shared/failure"
 )
 
 var once sync.Once
@@ -17,7 +23,64 @@ func GetValidator() *validator.Validate {
 	once.Do(func() {
 		log.Info().Msg("Validator initialized.")
 		v = validator.New()
+		// report fields by their JSON names, which are the names clients know
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+		if err := v.RegisterValidation(PasswordTag, validatePassword); err != nil {
+			log.Fatal().Err(err).Msg("Failed registering password validation")
+		}
+		if err := v.RegisterValidation(PasswordHashTag, validatePasswordHash); err != nil {
+			log.Fatal().Err(err).Msg("Failed registering password hash validation")
+		}
 	})
 
 	return v
 }
+
+// ValidationError turns the error returned by the validator into a bad request
+// failure listing the reasons per field.
+func ValidationError(err error) error {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return failure.BadRequest(err)
+	}
+
+	fields := make(map[string][]string)
+	for _, fieldError := range validationErrors {
+		field := fieldError.Field()
+		fields[field] = append(fields[field], validationReasons(fieldError)...)
+	}
+	return failure.ValidationFailed(fields)
+}
+
+func validationReasons(fieldError validator.FieldError) []string {
+	switch fieldError.Tag() {
+	case "required":
+		return []string{"is required"}
+	case "email":
+		return []string{"must be a valid email"}
+	case "nefield":
+		return []string{fmt.Sprintf("must differ from %s", fieldError.Param())}
+	case PasswordTag:
+		password, _ := fieldError.Value().(string)
+		reasons := GetPasswordPolicy().Violations(password)
+		if len(reasons) == 0 {
+			// only the personal values of the struct were found in the password
+			reasons = []string{"must not contain your email or name"}
+		}
+		return reasons
+	case PasswordHashTag:
+		return []string{"must be a bcrypt or argon2id hash"}
+	case "max":
+		return []string{fmt.Sprintf("must be at most %s characters", fieldError.Param())}
+	case "oneof":
+		return []string{fmt.Sprintf("must be one of %s", strings.ReplaceAll(fieldError.Param(), " ", ", "))}
+	default:
+		return []string{fmt.Sprintf("failed on the '%s' validation", fieldError.Tag())}
+	}
+}
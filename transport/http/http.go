@@ -20,7 +20,9 @@ import (
 	"github.com/IlhamRobyana/user/configs"
 	"github.com/IlhamRobyana/user/docs"
 	"github.com/IlhamRobyana/user/infras"
+	"github.com/IlhamRobyana/user/shared"
 	"github.com/IlhamRobyana/user/shared/logger"
+	"github.com/IlhamRobyana/user/shared/scheduler"
 	"github.com/IlhamRobyana/user/transport/http/response"
 	"github.com/IlhamRobyana/user/transport/http/router"
 )
@@ -42,19 +44,23 @@ const (
 
 // HTTP is the HTTP server.
 type HTTP struct {
-	Config *configs.Config
-	DB     *infras.MySQLConn
-	Router router.Router
-	State  ServerState
-	mux    *chi.Mux
+	Config    *configs.Config
+	DB        *infras.MySQLConn
+	PubSub    shared.PubSub
+	Scheduler *scheduler.Scheduler
+	Router    router.Router
+	State     ServerState
+	mux       *chi.Mux
 }
 
 // ProvideHTTP is the provider for HTTP.
-func ProvideHTTP(db *infras.MySQLConn, config *configs.Config, router router.Router) *HTTP {
+func ProvideHTTP(db *infras.MySQLConn, config *configs.Config, pubsub shared.PubSub, jobs *scheduler.Scheduler, router router.Router) *HTTP {
 	return &HTTP{
-		DB:     db,
-		Config: config,
-		Router: router,
+		DB:        db,
+		Config:    config,
+		PubSub:    pubsub,
+		Scheduler: jobs,
+		Router:    router,
 	}
 }
 
@@ -65,6 +71,11 @@ func (h *HTTP) SetupAndServe() {
 	h.setupSwaggerDocs()
 	h.setupRoutes()
 	h.setupGracefulShutdown()
+	h.PubSub.Start()
+	if h.Config.Scheduler.Enable {
+		h.Scheduler.Start()
+		log.Info().Msg("Scheduler started.")
+	}
 	h.State = ServerStateReady
 
 	h.logServerInfo()
@@ -112,6 +123,7 @@ func (h *HTTP) respondToSigterm(done chan os.Signal) {
 
 	log.Info().Int64("seconds", shutdownConfig.CleanupPeriodSeconds).Msg("Entering cleanup period.")
 	h.State = ServerStateInCleanupPeriod
+	h.Scheduler.Stop()
 	time.Sleep(time.Duration(shutdownConfig.CleanupPeriodSeconds) * time.Second)
 
 	log.Info().Msg("Cleaning up completed. Shutting down now.")
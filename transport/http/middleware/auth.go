@@ -2,19 +2,96 @@
 package middleware
 
 import (
-	"github.com/IlhamRobyana/user/infras"
+	"github.com/rs/zerolog/log"
+
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/IlhamRobyana/user/configs"
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/internal/domain/user/repository"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/shared/token"
+	"github.com/IlhamRobyana/user/transport/http/response"
 )
 
+// TokenRevocationChecker reports whether an access token was revoked before it
+// expired.
+type TokenRevocationChecker interface {
+	IsAccessTokenRevoked(ctx context.Context, claims token.Claims) (bool, error)
+}
+
 type Authentication struct {
-	db *infras.MySQLConn
+	cfg                    *configs.Config
+	userRepository         repository.UserRepository
+	tokenRevocationChecker TokenRevocationChecker
 }
 
 const (
 	HeaderAuthorization = "Authorization"
+	bearerPrefix        = "Bearer "
 )
 
-func ProvideAuthentication(db *infras.MySQLConn) *Authentication {
+func ProvideAuthentication(cfg *configs.Config, userRepository repository.UserRepository, tokenRevocationChecker TokenRevocationChecker) *Authentication {
 	return &Authentication{
-		db: db,
+		cfg:                    cfg,
+		userRepository:         userRepository,
+		tokenRevocationChecker: tokenRevocationChecker,
 	}
 }
+
+// VerifyBearerToken verifies the bearer access token in the Authorization header
+// and stores the caller's principal and the token claims in the request context.
+func (a *Authentication) VerifyBearerToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(HeaderAuthorization)
+		if !strings.HasPrefix(header, bearerPrefix) {
+			response.WithError(w, failure.Unauthorized("Missing bearer token"))
+			return
+		}
+
+		claims, err := token.Parse(strings.TrimPrefix(header, bearerPrefix), a.cfg.Auth.AccessToken.Secret)
+		if err != nil {
+			if errors.Is(err, token.ErrExpired) {
+				response.WithError(w, failure.Unauthorized("Token has expired"))
+				return
+			}
+			response.WithError(w, failure.Unauthorized("Invalid token"))
+			return
+		}
+		revoked, err := a.tokenRevocationChecker.IsAccessTokenRevoked(r.Context(), claims)
+		if err != nil {
+			response.WithError(w, err)
+			return
+		}
+		if revoked {
+			response.WithError(w, failure.Unauthorized("Token has been revoked"))
+			return
+		}
+
+		user, err := a.userRepository.ResolveUserByID(r.Context(), claims.UserID)
+		if err != nil {
+			if failure.GetCode(err) == http.StatusNotFound {
+				response.WithError(w, failure.Unauthorized("Token has been revoked"))
+				return
+			}
+			log.Error().Err(err).Msg("[VerifyBearerToken] failed get user")
+			response.WithError(w, err)
+			return
+		}
+		if !user.Status.CanLogin() {
+			response.WithError(w, failure.Unauthorized("Token has been revoked"))
+			return
+		}
+
+		ctx := model.NewPrincipalContext(r.Context(), model.Principal{
+			UserID:    user.Id,
+			Role:      user.Role,
+			SessionID: claims.SessionID,
+		})
+		ctx = token.NewContext(ctx, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
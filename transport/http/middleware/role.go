@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/IlhamRobyana/user/internal/domain/user/model"
+	"github.com/IlhamRobyana/user/shared/failure"
+	"github.com/IlhamRobyana/user/transport/http/response"
+)
+
+// RequireRole only lets through callers with one of the given roles. It must run
+// after VerifyBearerToken.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			caller, ok := model.PrincipalFromContext(r.Context())
+			if !ok {
+				response.WithError(w, failure.Unauthorized("Missing authenticated user"))
+				return
+			}
+			for _, role := range roles {
+				if caller.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			response.WithError(w, failure.Forbidden("Not allowed to access this resource"))
+		})
+	}
+}
@@ -11,11 +11,13 @@ import (
 
 // Base is the base object of all responses
 type Base struct {
-	Data             *interface{} `json:"data,omitempty"`
-	Metadata         *interface{} `json:"metadata,omitempty"`
-	Error            *string      `json:"error,omitempty"`
-	ErrorDescription *string      `json:"errorDescription,omitempty"`
-	Message          *string      `json:"message,omitempty"`
+	Data             *interface{}        `json:"data,omitempty"`
+	Metadata         *interface{}        `json:"metadata,omitempty"`
+	Error            *string             `json:"error,omitempty"`
+	ErrorCode        *string             `json:"errorCode,omitempty"`
+	ErrorDescription *string             `json:"errorDescription,omitempty"`
+	ErrorFields      map[string][]string `json:"errorFields,omitempty"`
+	Message          *string             `json:"message,omitempty"`
 }
 
 // NoContent sends a response without any content
@@ -42,7 +44,14 @@ func WithMetadata(w http.ResponseWriter, code int, jsonPayload interface{}, meta
 func WithError(w http.ResponseWriter, err error) {
 	code := failure.GetCode(err)
 	errMsg := err.Error()
-	respond(w, code, Base{Error: &errMsg})
+	base := Base{Error: &errMsg}
+	if f, ok := err.(*failure.Failure); ok {
+		base.ErrorFields = f.Fields
+		if f.ErrorCode != "" {
+			base.ErrorCode = &f.ErrorCode
+		}
+	}
+	respond(w, code, base)
 }
 
 // WithPreparingShutdown sends a default response for when the server is preparing to shut down
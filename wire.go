@@ -12,7 +12,11 @@ import (
 	userRepository "github.com/IlhamRobyana/user/internal/domain/user/repository"
 	userService "github.com/IlhamRobyana/user/internal/domain/user/service"
 	userHandler "github.com/IlhamRobyana/user/internal/handlers/user"
+	"github.com/IlhamRobyana/user/shared"
+	"github.com/IlhamRobyana/user/shared/notifier"
+	"github.com/IlhamRobyana/user/shared/scheduler"
 	"github.com/IlhamRobyana/user/transport/http"
+	"github.com/IlhamRobyana/user/transport/http/middleware"
 	"github.com/IlhamRobyana/user/transport/http/router"
 )
 
@@ -27,6 +31,19 @@ var persistencesServiceGen = wire.NewSet(
 	infras.ProvideMySQLConn,
 )
 
+// Wiring for background workers.
+var workersServiceGen = wire.NewSet(
+	shared.ProvidePubSub,
+	scheduler.ProvideScheduler,
+)
+
+// Wiring for outbound notifications.
+var notifiersServiceGen = wire.NewSet(
+	notifier.ProvideNotifier,
+	notifier.ProvideTemplates,
+	notifier.ProvideDispatcher,
+)
+
 // Wiring for domain user.
 var domainUserServiceGen = wire.NewSet(
 	// UserService interface and implementation
@@ -35,6 +52,15 @@ var domainUserServiceGen = wire.NewSet(
 	// UserRepository interface and implementation
 	userRepository.ProvideUserRepositoryMySQL,
 	wire.Bind(new(userRepository.UserRepository), new(*userRepository.UserRepositoryMySQL)),
+	// SessionRepository interface and implementation
+	userRepository.ProvideSessionRepositoryMySQL,
+	wire.Bind(new(userRepository.SessionRepository), new(*userRepository.SessionRepositoryMySQL)),
+	// MFARepository interface and implementation
+	userRepository.ProvideMFARepositoryMySQL,
+	wire.Bind(new(userRepository.MFARepository), new(*userRepository.MFARepositoryMySQL)),
+	// WebAuthnRepository interface and implementation
+	userRepository.ProvideWebAuthnRepositoryMySQL,
+	wire.Bind(new(userRepository.WebAuthnRepository), new(*userRepository.WebAuthnRepositoryMySQL)),
 )
 
 // Wiring for all domains.
@@ -46,6 +72,8 @@ var domainsServiceGen = wire.NewSet(
 var routingServiceGen = wire.NewSet(
 	wire.Struct(new(router.DomainHandlers), "*"),
 
+	middleware.ProvideAuthentication,
+	wire.Bind(new(middleware.TokenRevocationChecker), new(*userService.UserServiceImpl)),
 	userHandler.ProvideUserHandler,
 	router.ProvideRouter,
 )
@@ -57,6 +85,10 @@ func InitializeServiceServiceGen() *http.HTTP {
 		configurationsServiceGen,
 		// persistences
 		persistencesServiceGen,
+		// workers
+		workersServiceGen,
+		// notifiers
+		notifiersServiceGen,
 
 		// domains
 		domainsServiceGen,